@@ -0,0 +1,58 @@
+package cdb
+
+import (
+	"io"
+	"os"
+)
+
+// AccessPattern is a hint on how a reader accesses the database, see CDB.SetAccessPattern
+type AccessPattern int
+
+const (
+	// AccessNormal is the default readahead of the kernel
+	AccessNormal AccessPattern = iota
+	// AccessRandom disables readahead, it suits point lookups in databases larger than the page cache
+	AccessRandom
+	// AccessSequential doubles readahead and drops pages soon after they are read, it suits scans
+	AccessSequential
+	// AccessWillNeed starts reading the whole database into the page cache in background
+	AccessWillNeed
+)
+
+// SetAccessPattern tells readers to advise the kernel of the given access pattern of the database
+// by fadvise for files and by madvise for memory mappings. Hints are applied on Linux only and
+// are ignored for other backends. Like SetHash, it affects only new instances of Reader.
+func (cdb *CDB) SetAccessPattern(pattern AccessPattern) {
+	cdb.opts.access = pattern
+}
+
+// SetHugePages tells memory-mapped readers to ask the kernel to back the mapping by transparent
+// huge pages, which cut TLB misses of random lookups in multi-gigabyte databases. It is Linux-specific
+// and takes effect only if the kernel supports huge pages for read-only file mappings,
+// otherwise it is ignored. Like SetHash, it affects only new instances of Reader.
+func (cdb *CDB) SetHugePages(enabled bool) {
+	cdb.opts.hugePages = enabled
+}
+
+// adviseFile advises the given access pattern for the file of the given reader
+func adviseFile(reader io.ReaderAt, pattern AccessPattern) {
+	if pattern == AccessNormal {
+		return
+	}
+
+	if f, ok := osFile(reader); ok {
+		fadvise(f, 0, 0, pattern)
+	}
+}
+
+// osFile returns the file, which the given reader reads, looking through reader wrappers
+func osFile(reader io.ReaderAt) (*os.File, bool) {
+	switch reader := reader.(type) {
+	case interface{ unwrap() io.ReaderAt }:
+		return osFile(reader.unwrap())
+	case *os.File:
+		return reader, true
+	}
+
+	return nil, false
+}
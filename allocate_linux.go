@@ -0,0 +1,21 @@
+//go:build linux
+// +build linux
+
+package cdb
+
+import (
+	"io"
+	"os"
+	"syscall"
+)
+
+// Mode of fallocate, which allocates the space without changing the file size
+const fallocKeepSize = 0x01
+
+// allocate allocates the space of the given region of the file up front, if the writer is a file.
+// It is a hint, failures are ignored.
+func allocate(writer io.WriteSeeker, offset, length int64) {
+	if f, ok := writer.(*os.File); ok {
+		_ = syscall.Fallocate(int(f.Fd()), fallocKeepSize, offset, length)
+	}
+}
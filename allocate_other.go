@@ -0,0 +1,9 @@
+//go:build !linux
+// +build !linux
+
+package cdb
+
+import "io"
+
+// allocate does nothing, there is no fallocate on the platform
+func allocate(writer io.WriteSeeker, offset, length int64) {}
@@ -0,0 +1,199 @@
+package cdb
+
+import (
+	"errors"
+	"sort"
+)
+
+// Maximum gap between values of a batch, which are read at once
+const batchReadGap = 4096
+
+// ErrBatchMismatch tells that the keys and the values of a batch differ in number
+var ErrBatchMismatch = errors.New("cdb batch must have a value for every key")
+
+// batchValue is a value section of a batch lookup
+type batchValue struct {
+	section sectionReaderFactory
+	// i is the number of the key in the batch
+	i int
+}
+
+// MultiGet returns the first values associated with the given keys, see Reader.MultiGet
+func (r *readerImpl) MultiGet(keys [][]byte) ([][]byte, []error) {
+	if r.opts.tracer != nil {
+		return r.tracedMultiGet(keys)
+	}
+
+	return r.multiGet(keys)
+}
+
+// multiGet implements MultiGet
+func (r *readerImpl) multiGet(keys [][]byte) ([][]byte, []error) {
+	values := make([][]byte, len(keys))
+	errs := make([]error, len(keys))
+	batch := make([]batchValue, 0, len(keys))
+
+	for i, key := range keys {
+		found := false
+
+		errs[i] = r.forEachEntry(key, false, func(section sectionReaderFactory) bool {
+			batch = append(batch, batchValue{section, i})
+			found = true
+			return false
+		})
+
+		if errs[i] == nil && !found {
+			errs[i] = ErrEntryNotFound
+		}
+	}
+
+	// Values are read in the file order, so neighbours are read at once
+	sort.Slice(batch, func(i, j int) bool {
+		return batch[i].section.position < batch[j].section.position
+	})
+
+	for len(batch) > 0 {
+		n := r.batchRun(batch)
+		r.readBatchRun(batch[:n], values, errs)
+		batch = batch[n:]
+	}
+
+	return values, errs
+}
+
+// MultiHas tells which of the given keys exist, see Reader.MultiHas
+func (r *readerImpl) MultiHas(keys [][]byte) ([]bool, error) {
+	exists := make([]bool, len(keys))
+
+	for i, key := range keys {
+		var err error
+
+		if exists[i], err = r.Has(key); err != nil {
+			return nil, err
+		}
+	}
+
+	return exists, nil
+}
+
+// batchRun returns the number of leading values of the given sorted batch, which are read at once
+func (r *readerImpl) batchRun(batch []batchValue) int {
+	if r.mem != nil {
+		return len(batch)
+	}
+
+	end := batch[0].section.position + batch[0].section.size
+	n := 1
+
+	for ; n < len(batch); n++ {
+		section := batch[n].section
+
+		if section.position > end+batchReadGap {
+			break
+		}
+
+		if section.position+section.size > end {
+			end = section.position + section.size
+		}
+	}
+
+	return n
+}
+
+// readBatchRun reads the values of the given sorted batch run by a single read
+func (r *readerImpl) readBatchRun(run []batchValue, values [][]byte, errs []error) {
+	if r.mem != nil || len(run) == 1 {
+		for _, v := range run {
+			values[v.i], errs[v.i] = r.readValue(v.section)
+		}
+
+		return
+	}
+
+	begin, end := run[0].section.position, uint32(0)
+
+	for _, v := range run {
+		if v.section.position+v.section.size > end {
+			end = v.section.position + v.section.size
+		}
+	}
+
+	buf := make([]byte, end-begin)
+
+	if _, err := r.reader.ReadAt(buf, int64(begin)); err != nil {
+		for _, v := range run {
+			errs[v.i] = err
+		}
+
+		return
+	}
+
+	for _, v := range run {
+		from := v.section.position - begin
+		to := from + v.section.size
+		values[v.i] = buf[from:to:to]
+	}
+}
+
+// PutMany saves the given pairs <keys[i], values[i]>, see Writer.PutMany
+func (w *writerImpl) PutMany(keys, values [][]byte) error {
+	return w.putMany(keys, values, recordMeta{})
+}
+
+// PutMany saves the given pairs <keys[i], values[i]> into the bucket, see Writer.PutMany
+func (w *bucketWriter) PutMany(keys, values [][]byte) error {
+	if w.id == 0 {
+		return ErrBucketsDisabled
+	}
+
+	return w.putMany(keys, values, recordMeta{bucket: w.id})
+}
+
+// putMany saves the given pairs with the given meta fields, collections of the writer are grown once per batch
+func (w *writerImpl) putMany(keys, values [][]byte, meta recordMeta) error {
+	if len(keys) != len(values) {
+		return ErrBatchMismatch
+	}
+
+	if w.index != nil && cap(w.entries)-len(w.entries) < len(keys) {
+		entries := make([]indexEntry, len(w.entries), len(w.entries)+len(keys))
+		copy(entries, w.entries)
+		w.entries = entries
+	}
+
+	for i, key := range keys {
+		if err := w.put(key, values[i], meta); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// PutMany saves the given pairs <keys[i], values[i]>, every part gets its pairs in one batch, see Writer.PutMany
+func (w *shardedWriter) PutMany(keys, values [][]byte) error {
+	if len(keys) != len(values) {
+		return ErrBatchMismatch
+	}
+
+	partKeys := make([][][]byte, len(w.parts))
+	partValues := make([][][]byte, len(w.parts))
+
+	for i, key := range keys {
+		j := shardOf(w.hasher, key, len(w.parts))
+		partKeys[j] = append(partKeys[j], key)
+		partValues[j] = append(partValues[j], values[i])
+	}
+
+	for j, part := range w.parts {
+		if len(partKeys[j]) == 0 {
+			continue
+		}
+
+		if err := part.PutMany(partKeys[j], partValues[j]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
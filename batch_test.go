@@ -0,0 +1,94 @@
+package cdb
+
+func (suite *CDBTestSuite) TestMultiGet() {
+	suite.fillTestCDB()
+
+	keys := [][]byte{[]byte("missing key")}
+	for i := len(suite.testRecords) - 1; i >= 0; i-- {
+		keys = append(keys, suite.testRecords[i].key)
+	}
+
+	mmapReader, err := suite.cdbHandle.GetReaderMmap(suite.cdbFile)
+	suite.Require().Nil(err)
+	defer mmapReader.Close()
+
+	for _, reader := range []Reader{suite.getCDBReader(), mmapReader} {
+		values, errs := reader.MultiGet(keys)
+		suite.Require().Len(values, len(keys))
+		suite.Require().Len(errs, len(keys))
+
+		suite.Nil(values[0])
+		suite.Equal(ErrEntryNotFound, errs[0])
+
+		for i, key := range keys[1:] {
+			expected, err := reader.Get(key)
+			suite.Require().Nil(err)
+			suite.Nil(errs[i+1])
+			suite.Equal(expected, values[i+1])
+			suite.Equal(len(values[i+1]), cap(values[i+1]), "Value must not expose the rest of the batch")
+		}
+	}
+}
+
+func (suite *CDBTestSuite) TestMultiHas() {
+	suite.fillTestCDB()
+
+	reader := suite.getCDBReader()
+	keys := [][]byte{suite.testRecords[0].key, []byte("missing key"), suite.testRecords[1].key}
+
+	exists, err := reader.MultiHas(keys)
+	suite.Nil(err)
+	suite.Equal([]bool{true, false, true}, exists)
+}
+
+func (suite *CDBTestSuite) TestPutMany() {
+	keys := make([][]byte, len(suite.testRecords))
+	values := make([][]byte, len(suite.testRecords))
+
+	for i, rec := range suite.testRecords {
+		keys[i], values[i] = rec.key, rec.val
+	}
+
+	writer := suite.getCDBWriter()
+	suite.Equal(ErrBatchMismatch, writer.PutMany(keys, values[1:]))
+	suite.Require().Nil(writer.PutMany(keys, values))
+	suite.Require().Nil(writer.Close())
+
+	for _, rec := range suite.testRecords {
+		value, err := suite.getCDBReader().Get(rec.key)
+		suite.Nil(err)
+		suite.Equal(rec.val, value)
+	}
+
+	files := suite.createShardFiles(2)
+	defer suite.removeShardFiles(files)
+
+	var err error
+	parts := make([]Writer, len(files))
+
+	for i, f := range files {
+		parts[i], err = suite.cdbHandle.GetWriter(f)
+		suite.Require().Nil(err)
+	}
+
+	sharded, err := suite.cdbHandle.NewShardedWriter(parts)
+	suite.Require().Nil(err)
+	suite.Require().Nil(sharded.PutMany(keys, values))
+	suite.Require().Nil(sharded.Close())
+
+	readers := make([]Reader, len(files))
+
+	for i, f := range files {
+		readers[i], err = suite.cdbHandle.GetReader(f)
+		suite.Require().Nil(err)
+	}
+
+	reader, err := suite.cdbHandle.NewShardedReader(readers)
+	suite.Require().Nil(err)
+
+	for _, rec := range suite.testRecords {
+		value, err := reader.Get(rec.key)
+		suite.Nil(err)
+		suite.Equal(rec.val, value)
+	}
+}
@@ -0,0 +1,151 @@
+package cdb
+
+import "io"
+
+// BatchIterator reads records of an iterator in batches into reusable buffers, so exports
+// don't pay for a call and an allocation per record. Do not share object between multiple goroutines.
+type BatchIterator struct {
+	iterator Iterator
+	// done tells that the last record of the iterator is read
+	done bool
+	// buf holds keys and values of the batch, sizes are their sizes
+	buf    []byte
+	sizes  []int
+	keys   [][]byte
+	values [][]byte
+}
+
+// NewBatchIterator returns a new BatchIterator, which reads records starting from the current record
+// of the given iterator
+func NewBatchIterator(iterator Iterator) *BatchIterator {
+	return &BatchIterator{iterator: iterator}
+}
+
+// NextBatch returns the keys and the values of the next at most n records. The batch is empty
+// after the last record. Keys and values are valid until the next call of NextBatch.
+func (b *BatchIterator) NextBatch(n int) (keys, values [][]byte, err error) {
+	if n <= 0 {
+		return nil, nil, ErrInvalidLimit
+	}
+
+	b.buf, b.sizes = b.buf[:0], b.sizes[:0]
+
+	for len(b.sizes) < 2*n && !b.done {
+		var keySize int
+
+		start := len(b.buf)
+
+		if b.buf, keySize, err = appendRecord(b.iterator, b.buf); err != nil {
+			return nil, nil, err
+		}
+
+		b.sizes = append(b.sizes, keySize, len(b.buf)-start-keySize)
+
+		ok, err := b.iterator.Next()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		b.done = !ok
+	}
+
+	// The buffer is sliced once it is filled, appends may move it
+	b.keys, b.values = b.keys[:0], b.values[:0]
+
+	for j, pos := 0, 0; j < len(b.sizes); j += 2 {
+		keyEnd := pos + b.sizes[j]
+		valueEnd := keyEnd + b.sizes[j+1]
+
+		b.keys = append(b.keys, b.buf[pos:keyEnd:keyEnd])
+		b.values = append(b.values, b.buf[keyEnd:valueEnd:valueEnd])
+		pos = valueEnd
+	}
+
+	return b.keys, b.values, nil
+}
+
+// recordAppender is implemented by iterators, which read records into a given buffer
+type recordAppender interface {
+	// appendRecord appends the key and the value of the current record to dst, returns the size of the key
+	appendRecord(dst []byte) ([]byte, int, error)
+}
+
+// appendRecord appends the key and the value of the current record of the iterator to dst,
+// returns the size of the key
+func appendRecord(iterator Iterator, dst []byte) ([]byte, int, error) {
+	if appender, ok := iterator.(recordAppender); ok {
+		return appender.appendRecord(dst)
+	}
+
+	key, err := iterator.Key()
+	if err != nil {
+		return dst, 0, err
+	}
+
+	value, err := iterator.Value()
+	if err != nil {
+		return dst, 0, err
+	}
+
+	return append(append(dst, key...), value...), len(key), nil
+}
+
+// appendRecord appends the key and the value of the current record to dst, see recordAppender
+func (i *iterator) appendRecord(dst []byte) ([]byte, int, error) {
+	keyFactory, valueFactory := i.record.keySectionFactory, i.record.valueSectionFactory
+	start := len(dst)
+
+	dst = extend(dst, int(keyFactory.size)+int(valueFactory.size))
+	key, value := dst[start:start+int(keyFactory.size)], dst[start+int(keyFactory.size):]
+
+	if err := readSectionInto(keyFactory, key); err != nil {
+		return dst[:start], 0, err
+	}
+
+	if err := readSectionInto(valueFactory, value); err != nil {
+		return dst[:start], 0, err
+	}
+
+	return dst, len(key), nil
+}
+
+// readSectionInto reads the section of the given factory into buf of the section size
+func readSectionInto(f *sectionReaderFactory, buf []byte) error {
+	if n, err := f.reader.ReadAt(buf, int64(f.position)); err != nil && !(err == io.EOF && n == len(buf)) {
+		return err
+	}
+
+	return nil
+}
+
+// extend returns dst extended by size bytes, it is reallocated only if it is too small
+func extend(dst []byte, size int) []byte {
+	if n := len(dst) + size; n <= cap(dst) {
+		return dst[:n]
+	}
+
+	grown := make([]byte, len(dst)+size, 2*cap(dst)+size)
+	copy(grown, dst)
+
+	return grown
+}
+
+// appendRecord appends the current record of the current iterator to dst, see recordAppender
+func (i *concatIterator) appendRecord(dst []byte) ([]byte, int, error) {
+	return appendRecord(i.iterators[0], dst)
+}
+
+// appendRecord appends the current record of the current iterator to dst, see recordAppender
+func (i *mergeIterator) appendRecord(dst []byte) ([]byte, int, error) {
+	return appendRecord(i.iterators[i.current], dst)
+}
+
+// appendRecord appends the current record to dst, see recordAppender
+func (i *stackIterator) appendRecord(dst []byte) ([]byte, int, error) {
+	return appendRecord(i.Iterator, dst)
+}
+
+// appendRecord appends the current record to dst, see recordAppender
+func (i *filterIterator) appendRecord(dst []byte) ([]byte, int, error) {
+	return appendRecord(i.Iterator, dst)
+}
@@ -0,0 +1,38 @@
+package cdb
+
+func (suite *CDBTestSuite) TestBatchIterator() {
+	suite.fillTestCDB()
+
+	for _, open := range []func() (Iterator, error){suite.getCDBReader().Iterator, suite.getCDBReader().KeyIterator} {
+		iterator, err := open()
+		suite.Require().Nil(err)
+
+		batches := NewBatchIterator(iterator)
+		_, _, err = batches.NextBatch(0)
+		suite.Equal(ErrInvalidLimit, err)
+
+		// 10 records are read by batches of 4, 4 and 2
+		var sizes []int
+		i := 0
+
+		for {
+			keys, values, err := batches.NextBatch(4)
+			suite.Require().Nil(err)
+
+			if len(keys) == 0 {
+				break
+			}
+
+			sizes = append(sizes, len(keys))
+
+			for j := range keys {
+				suite.Equal(suite.testRecords[i].key, keys[j])
+				suite.Equal(suite.testRecords[i].val, values[j])
+				i++
+			}
+		}
+
+		suite.Equal([]int{4, 4, 2}, sizes)
+		suite.Equal(len(suite.testRecords), i)
+	}
+}
@@ -0,0 +1,180 @@
+package cdb
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// The bloom filter is a trailer placed after the hash tables, the header keeps its position:
+//
+//	+---------+--------+------+
+//	| hashNum | bitNum | bits |
+//	+---------+--------+------+
+//	   u32       u32    (bitNum+7)/8 bytes
+//
+// Bits are derived from the key hash stored in slots by the double hashing, so neither
+// the writer nor the reader has to hash keys twice.
+const (
+	// Size of the bloom filter header
+	bloomHeaderSize = 8
+	// Maximum number of bits per key
+	maxBloomBitsPerKey = 64
+	// Maximum number of hash functions
+	maxBloomHashNum = 30
+)
+
+// ErrInvalidBloomBitsPerKey tells that the requested number of bloom filter bits per key is out of the supported range
+var ErrInvalidBloomBitsPerKey = errors.New("cdb bloom filter bits per key must be in range [0, 64]")
+
+// bloomFilter is a bloom filter over key hashes
+type bloomFilter struct {
+	hashNum, bitNum uint32
+	bits            []byte
+}
+
+// newBloomFilter returns an empty bloom filter sized for n keys
+func newBloomFilter(n int, bitsPerKey uint32) *bloomFilter {
+	// hashNum = bitsPerKey * ln(2) is the optimal number of hash functions
+	hashNum := bitsPerKey * 69 / 100
+	if hashNum < 1 {
+		hashNum = 1
+	}
+
+	if hashNum > maxBloomHashNum {
+		hashNum = maxBloomHashNum
+	}
+
+	bitNum := bloomBitNum(n, bitsPerKey)
+
+	return &bloomFilter{
+		hashNum: hashNum,
+		bitNum:  uint32(bitNum),
+		bits:    make([]byte, (bitNum+7)/8),
+	}
+}
+
+// bitsPerKey returns the number of bits per key of the filter of n keys, see CDB.SetBloomFilter.
+// The bits per key of a filter of a few keys, which is padded to the minimal size, are guessed
+// by the number of hash functions.
+func (b *bloomFilter) bitsPerKey(n int) uint32 {
+	bitsPerKey := (b.hashNum*100 + 68) / 69
+
+	if n > 0 && uint64(b.bitNum) > 64 {
+		bitsPerKey = uint32(uint64(b.bitNum) / uint64(n))
+	}
+
+	if bitsPerKey < 1 {
+		return 1
+	}
+
+	if bitsPerKey > maxBloomBitsPerKey {
+		return maxBloomBitsPerKey
+	}
+
+	return bitsPerKey
+}
+
+// bloomBitNum returns the number of bits of the filter of n keys
+func bloomBitNum(n int, bitsPerKey uint32) uint64 {
+	bitNum := uint64(n) * uint64(bitsPerKey)
+	if bitNum < 64 {
+		bitNum = 64
+	}
+
+	if bitNum > maxUint {
+		bitNum = maxUint
+	}
+
+	return bitNum
+}
+
+// bloomSize returns the size of the written filter of n keys
+func bloomSize(n int, bitsPerKey uint32) int64 {
+	return bloomHeaderSize + int64(bloomBitNum(n, bitsPerKey)+7)/8
+}
+
+// add adds the given key hash to the filter
+func (b *bloomFilter) add(h uint32) {
+	delta := bloomDelta(h)
+
+	for i := uint32(0); i < b.hashNum; i++ {
+		bit := h % b.bitNum
+		b.bits[bit/8] |= 1 << (bit % 8)
+		h += delta
+	}
+}
+
+// mayContain returns false if the key with the given hash definitely isn't in the set
+func (b *bloomFilter) mayContain(h uint32) bool {
+	delta := bloomDelta(h)
+
+	for i := uint32(0); i < b.hashNum; i++ {
+		bit := h % b.bitNum
+		if b.bits[bit/8]&(1<<(bit%8)) == 0 {
+			return false
+		}
+
+		h += delta
+	}
+
+	return true
+}
+
+// size returns the size of the serialized filter
+func (b *bloomFilter) size() int {
+	return bloomHeaderSize + len(b.bits)
+}
+
+// write writes the filter to the given writer
+func (b *bloomFilter) write(writer io.Writer) error {
+	if err := writePair(writer, b.hashNum, b.bitNum); err != nil {
+		return err
+	}
+
+	_, err := writer.Write(b.bits)
+
+	return err
+}
+
+// readBloomFilter reads a filter located at the given position
+func readBloomFilter(reader io.ReaderAt, pos uint32) (*bloomFilter, error) {
+	buf := make([]byte, bloomHeaderSize)
+
+	if _, err := reader.ReadAt(buf, int64(pos)); err != nil {
+		return nil, ErrInvalidHeader
+	}
+
+	b := &bloomFilter{
+		hashNum: binary.LittleEndian.Uint32(buf),
+		bitNum:  binary.LittleEndian.Uint32(buf[4:]),
+	}
+
+	if b.hashNum == 0 || b.hashNum > maxBloomHashNum || b.bitNum == 0 {
+		return nil, ErrInvalidHeader
+	}
+
+	// The size comes from the file, it is checked before the bits are allocated
+	size := (uint64(b.bitNum) + 7) / 8
+	end := uint64(pos) + bloomHeaderSize + size
+	total, sized := readerSize(reader)
+
+	if end > maxUint || (sized && end > uint64(total)) {
+		return nil, corrupted(nil, int64(pos), -1,
+			fmt.Sprintf("bloom filter of %d bits at position %d is out of the database", b.bitNum, pos))
+	}
+
+	b.bits = make([]byte, size)
+
+	if _, err := reader.ReadAt(b.bits, int64(pos+bloomHeaderSize)); err != nil {
+		return nil, corrupted(err, int64(pos), -1, "bloom filter is truncated")
+	}
+
+	return b, nil
+}
+
+// bloomDelta returns the step of the double hashing
+func bloomDelta(h uint32) uint32 {
+	return mix32(h) | 1
+}
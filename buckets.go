@@ -0,0 +1,195 @@
+package cdb
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"time"
+)
+
+// Buckets are logical keyspaces of a database. Every record of a database with buckets
+// has the bucket field, that is the id of its bucket (see recordLayout), 0 is the id of
+// the root bucket, which is used by plain Writer and Reader objects. The key hash of a record
+// is calculated over the bucket name followed by the key. The bucket directory is a trailer
+// placed after the hash tables, the header keeps its position:
+//
+//	+-------+---------+-----+---------+
+//	| count | entry 1 | ... | entry N |
+//	+-------+---------+-----+---------+
+//	   u32
+//
+// where an entry describes a named bucket:
+//
+//	+----+------+---------+------+
+//	| id | size | nameLen | name |
+//	+----+------+---------+------+
+//	 u32   u32     u32
+//
+// The size of the root bucket is the number of records not listed in the directory.
+const (
+	// Size of the bucket field
+	bucketFieldSize = 4
+	// Size of the fixed part of a bucket directory entry
+	bucketEntrySize = 12
+)
+
+// ErrBucketsDisabled tells that it was an attempt to put a record into a named bucket without the buckets support
+var ErrBucketsDisabled = errors.New("cdb buckets support is disabled, see CDB.SetBuckets")
+
+// bucketInfo describes a named bucket of a database
+type bucketInfo struct {
+	id   uint32
+	size int
+}
+
+// bucketWriter implements Writer interface, puts records into a bucket of the parent writer
+type bucketWriter struct {
+	*writerImpl
+	id uint32
+}
+
+// Bucket returns a Writer, which puts records into the bucket with the given name.
+// The empty name stands for the root bucket. All bucket writers share the database,
+// Close of any of them commits it.
+func (w *writerImpl) Bucket(name string) Writer {
+	if name == "" {
+		return w
+	}
+
+	if w.header.flags&flagBuckets == 0 {
+		return &bucketWriter{w, 0}
+	}
+
+	id, ok := w.bucketIDs[name]
+
+	if !ok {
+		id = uint32(len(w.bucketNames))
+		w.bucketIDs[name] = id
+		w.bucketNames = append(w.bucketNames, []byte(name))
+		w.bucketSizes = append(w.bucketSizes, 0)
+	}
+
+	return &bucketWriter{w, id}
+}
+
+// Put saves a new associated pair <key, value> into the bucket.
+func (w *bucketWriter) Put(key, value []byte) error {
+	if w.id == 0 {
+		return ErrBucketsDisabled
+	}
+
+	return w.put(key, value, recordMeta{bucket: w.id})
+}
+
+// PutWithExpiry saves a new associated pair <key, value>, which expires at the given time, into the bucket.
+func (w *bucketWriter) PutWithExpiry(key, value []byte, expiresAt time.Time) error {
+	if w.id == 0 {
+		return ErrBucketsDisabled
+	}
+
+	if w.header.flags&flagExpiry == 0 {
+		return ErrExpiryDisabled
+	}
+
+	return w.put(key, value, expiryMeta(expiresAt, w.id))
+}
+
+// writeBucketDirectory writes the bucket directory trailer, if there are named buckets
+func (w *writerImpl) writeBucketDirectory() error {
+	if len(w.bucketNames) <= 1 {
+		return nil
+	}
+
+	pos, err := w.writer.Seek(0, io.SeekCurrent)
+
+	if err != nil {
+		return err
+	}
+
+	// Positions are relative to the database start
+	pos -= w.begin
+
+	if pos >= maxUint {
+		return ErrTooBig
+	}
+
+	buf := make([]byte, 4)
+	binary.LittleEndian.PutUint32(buf, uint32(len(w.bucketNames)-1))
+
+	for id := 1; id < len(w.bucketNames); id++ {
+		name := w.bucketNames[id]
+		entry := make([]byte, bucketEntrySize, bucketEntrySize+len(name))
+
+		binary.LittleEndian.PutUint32(entry, uint32(id))
+		binary.LittleEndian.PutUint32(entry[4:], uint32(w.bucketSizes[id]))
+		binary.LittleEndian.PutUint32(entry[8:], uint32(len(name)))
+		buf = append(buf, append(entry, name...)...)
+	}
+
+	w.header.buckets = uint32(pos)
+
+	_, err = w.writer.Write(buf)
+
+	return err
+}
+
+// readBucketDirectory reads the bucket directory located at the given position
+func readBucketDirectory(reader io.ReaderAt, pos uint32) (map[string]bucketInfo, error) {
+	buf := make([]byte, bucketEntrySize)
+
+	if _, err := reader.ReadAt(buf[:4], int64(pos)); err != nil {
+		return nil, ErrInvalidHeader
+	}
+
+	count := binary.LittleEndian.Uint32(buf)
+	buckets := make(map[string]bucketInfo)
+	pos += 4
+
+	for i := uint32(0); i < count; i++ {
+		if _, err := reader.ReadAt(buf, int64(pos)); err != nil {
+			return nil, ErrInvalidHeader
+		}
+
+		info := bucketInfo{
+			id:   binary.LittleEndian.Uint32(buf),
+			size: int(binary.LittleEndian.Uint32(buf[4:])),
+		}
+
+		name := make([]byte, binary.LittleEndian.Uint32(buf[8:]))
+		pos += bucketEntrySize
+
+		if _, err := reader.ReadAt(name, int64(pos)); err != nil {
+			return nil, ErrInvalidHeader
+		}
+
+		pos += uint32(len(name))
+		buckets[string(name)] = info
+	}
+
+	return buckets, nil
+}
+
+// Bucket returns a Reader, which gives access to the records of the bucket with the given name only.
+// The empty name stands for the root bucket. A missing bucket is empty.
+func (r *readerImpl) Bucket(name string) Reader {
+	bucket := *r
+	// Resources belong to the parent reader
+	bucket.closer = nil
+	bucket.bucketName = []byte(name)
+	bucket.bucket, bucket.size = 0, r.rootSize
+
+	if name == "" {
+		return &bucket
+	}
+
+	info, ok := r.buckets[name]
+
+	if !ok {
+		// No record has the maximal id, so nothing is found
+		info.id = maxUint
+	}
+
+	bucket.bucket, bucket.size = info.id, info.size
+
+	return &bucket
+}
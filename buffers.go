@@ -0,0 +1,121 @@
+package cdb
+
+import (
+	"errors"
+	"math/bits"
+	"sync"
+)
+
+const (
+	// Number of size classes of the value pool, the largest class keeps 1 gigabyte buffers
+	valueClasses = 31
+	// Size of the largest pooled value, larger values are always allocated
+	maxPooledValue = 1 << (valueClasses - 1)
+)
+
+// ErrInvalidBufferSize tells that it was an attempt to set a non positive scratch or write buffer size
+// or a pooling limit smaller than it
+var ErrInvalidBufferSize = errors.New("cdb buffer sizes must be positive, the pooling limit must not be less than the scratch size")
+
+// valuePool keeps value buffers in power of two size classes
+type valuePool struct {
+	classes [valueClasses]sync.Pool
+	// max is the size of the largest pooled buffer
+	max int
+}
+
+// SetBufferSizes tells readers to allocate scratch buffers for record headers and keys of the given
+// size and to return to the pool the ones up to maxPooled bytes only, so that a lookup of a rare
+// huge key doesn't pin its buffer. Long keys benefit from a larger scratch size, which saves regrowth.
+// Like SetHash, it affects only new instances of Reader.
+func (cdb *CDB) SetBufferSizes(scratch, maxPooled int) error {
+	if scratch <= 0 || maxPooled < scratch {
+		return ErrInvalidBufferSize
+	}
+
+	cdb.opts.scratchSize, cdb.opts.maxPooledScratch = scratch, maxPooled
+
+	return nil
+}
+
+// SetWriteBufferSize tells writers to buffer records in a buffer of the given size, 4096 bytes by default.
+// A larger buffer saves syscalls of builds with small records, buffered records reach the OS,
+// when the buffer is full or on Writer.Flush. Like SetHash, it affects only new instances of Writer.
+func (cdb *CDB) SetWriteBufferSize(n int) error {
+	if n <= 0 {
+		return ErrInvalidBufferSize
+	}
+
+	cdb.opts.writeBufferSize = n
+
+	return nil
+}
+
+// SetValuePool tells readers to take values of up to maxSize bytes from a pool of buffers shared
+// by the readers of the handle instead of allocating fresh ones. A value, which is no longer
+// used, is handed back by ReleaseValue. It lowers the GC pressure of workloads, which read
+// many mid-sized values and drop them soon. 0 disables the pool. Values of memory-mapped
+// and in-memory readers are never pooled, they aren't allocated at all. maxSize is capped
+// at 1 gigabyte. Like SetHash, it affects only new instances of Reader.
+func (cdb *CDB) SetValuePool(maxSize int) {
+	if maxSize <= 0 {
+		cdb.opts.values = nil
+		return
+	}
+
+	if maxSize > maxPooledValue {
+		maxSize = maxPooledValue
+	}
+
+	cdb.opts.values = &valuePool{max: maxSize}
+}
+
+// ReleaseValue hands the given value back to the pool of the handle, see SetValuePool.
+// The value must be returned by a reader of the handle, which doesn't use memory mapping,
+// and must not be used afterwards. It does nothing without the pool.
+func (cdb *CDB) ReleaseValue(value []byte) {
+	if cdb.opts.values != nil {
+		cdb.opts.values.put(value)
+	}
+}
+
+// valueClass returns the size class of buffers of the given size
+func valueClass(size int) int {
+	return bits.Len(uint(size - 1))
+}
+
+// get returns a buffer of the given size
+func (p *valuePool) get(size int) []byte {
+	if size == 0 || size > p.max {
+		return make([]byte, size)
+	}
+
+	class := valueClass(size)
+
+	if buf, ok := p.classes[class].Get().(*[]byte); ok {
+		return (*buf)[:size]
+	}
+
+	return make([]byte, size, 1<<uint(class))
+}
+
+// put returns the given buffer to the pool, buffers not taken from it are dropped
+func (p *valuePool) put(buf []byte) {
+	c := cap(buf)
+
+	if c == 0 || c > p.max || c&(c-1) != 0 {
+		return
+	}
+
+	buf = buf[:0]
+	p.classes[valueClass(c)].Put(&buf)
+}
+
+// newValue returns a buffer for a value of the given size
+func (r *readerImpl) newValue(size uint32) []byte {
+	if r.opts.values == nil {
+		return make([]byte, size)
+	}
+
+	return r.opts.values.get(int(size))
+}
@@ -0,0 +1,75 @@
+package cdb
+
+import (
+	"math"
+	"testing"
+)
+
+func (suite *CDBTestSuite) TestSetBufferSizes() {
+	suite.Require().Nil(suite.cdbHandle.SetBufferSizes(256, 1024))
+	suite.TestShouldReturnAllValues()
+
+	suite.Equal(ErrInvalidBufferSize, suite.cdbHandle.SetBufferSizes(0, 1024))
+	suite.Equal(ErrInvalidBufferSize, suite.cdbHandle.SetBufferSizes(256, 128))
+}
+
+func (suite *CDBTestSuite) TestSetValuePool() {
+	suite.cdbHandle.SetValuePool(1 << 10)
+	suite.fillTestCDB()
+
+	reader, err := suite.cdbHandle.GetReader(suite.cdbFile)
+	suite.Require().Nil(err)
+
+	for i := 0; i < 2; i++ {
+		for _, rec := range suite.testRecords {
+			value, err := reader.Get(rec.key)
+			suite.Nil(err)
+			suite.Equal(rec.val, value)
+
+			suite.cdbHandle.ReleaseValue(value)
+		}
+	}
+
+	if !raceEnabled {
+		key := suite.testRecords[0].key
+
+		allocs := testing.AllocsPerRun(100, func() {
+			value, _ := reader.Get(key)
+			suite.cdbHandle.ReleaseValue(value)
+		})
+		suite.True(allocs <= 1, "pooled values must not be allocated, got %v allocations", allocs)
+	}
+
+	// Values beyond the largest size class are allocated
+	suite.cdbHandle.SetValuePool(math.MaxInt32)
+	suite.Equal(maxPooledValue, suite.cdbHandle.opts.values.max)
+	suite.Equal(valueClasses-1, valueClass(suite.cdbHandle.opts.values.max))
+}
+
+func (suite *CDBTestSuite) TestWriteBufferAndFlush() {
+	suite.Equal(ErrInvalidBufferSize, suite.cdbHandle.SetWriteBufferSize(0))
+	suite.Require().Nil(suite.cdbHandle.SetWriteBufferSize(1 << 16))
+
+	writer := suite.getCDBWriter()
+	suite.Require().Nil(writer.Put([]byte("key"), []byte("value")))
+
+	info, err := suite.cdbFile.Stat()
+	suite.Require().Nil(err)
+	suite.Zero(info.Size(), "the record is buffered")
+
+	suite.Require().Nil(writer.Flush())
+
+	info, err = suite.cdbFile.Stat()
+	suite.Require().Nil(err)
+	suite.NotZero(info.Size(), "the record is flushed")
+
+	suite.Require().Nil(writer.Close())
+
+	value, err := suite.getCDBReader().Get([]byte("key"))
+	suite.Nil(err)
+	suite.Equal([]byte("value"), value)
+
+	writer = suite.getCDBWriter()
+	suite.Require().Nil(writer.Abort())
+	suite.Equal(ErrWriterAborted, writer.Flush())
+}
@@ -0,0 +1,192 @@
+package cdb
+
+import (
+	"container/list"
+	"sync"
+)
+
+// CachedReader implements Reader interface, keeps recently read values in memory,
+// so that repeated Gets of hot keys don't touch the database. Only Get, GetString and,
+// with the negative cache, Has are cached, other methods go to the underlying reader.
+// Cached values are shared between callers and must not be modified.
+type CachedReader struct {
+	Reader
+
+	mu     sync.Mutex
+	values lruCache
+	// misses keeps recently missed keys, see SetNegativeCache
+	misses lruCache
+}
+
+// lruCache keeps pairs <key, value> up to the limits, evicting the least recently used ones
+type lruCache struct {
+	// lru keeps *cacheEntry objects from the most to the least recently used one
+	lru     *list.List
+	entries map[string]*list.Element
+	// maxEntries and maxBytes limit the cache, 0 means no limit
+	maxEntries, maxBytes int
+	bytes                int
+}
+
+// cacheEntry is a cached pair <key, value>
+type cacheEntry struct {
+	key   string
+	value []byte
+}
+
+// NewCachedReader returns a CachedReader over the given reader, which keeps at most maxEntries
+// values of at most maxBytes bytes in total, keys included. A zero limit means no limit.
+func NewCachedReader(reader Reader, maxEntries, maxBytes int) *CachedReader {
+	return &CachedReader{
+		Reader: reader,
+		values: newLRUCache(maxEntries, maxBytes),
+		misses: newLRUCache(0, 0),
+	}
+}
+
+// SetNegativeCache makes the reader remember at most n recently missed keys, so that repeated
+// lookups of absent keys don't probe the database. 0 disables the negative cache, it's the default.
+func (c *CachedReader) SetNegativeCache(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.misses = newLRUCache(n, 0)
+}
+
+// Get returns the first value associated with the given key, from the cache if possible
+func (c *CachedReader) Get(key []byte) ([]byte, error) {
+	c.mu.Lock()
+	value, ok := c.values.lookup(key)
+	_, missed := c.misses.lookup(key)
+	c.mu.Unlock()
+
+	if ok {
+		return value, nil
+	}
+
+	if missed {
+		return nil, ErrEntryNotFound
+	}
+
+	value, err := c.Reader.Get(key)
+
+	if err == ErrEntryNotFound {
+		c.miss(key)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.values.add(key, value)
+	c.mu.Unlock()
+
+	return value, nil
+}
+
+// GetString returns the first value associated with the given string key, from the cache if possible
+func (c *CachedReader) GetString(key string) ([]byte, error) {
+	return c.Get(stringBytes(key))
+}
+
+// Has returns true if the given key exists, otherwise returns false. Consults the negative cache.
+func (c *CachedReader) Has(key []byte) (bool, error) {
+	c.mu.Lock()
+	_, missed := c.misses.lookup(key)
+	c.mu.Unlock()
+
+	if missed {
+		return false, nil
+	}
+
+	exists, err := c.Reader.Has(key)
+
+	if err == nil && !exists {
+		c.miss(key)
+	}
+
+	return exists, err
+}
+
+// HasString returns true if the given string key exists, otherwise returns false. Consults the negative cache.
+func (c *CachedReader) HasString(key string) (bool, error) {
+	return c.Has(stringBytes(key))
+}
+
+// Clone returns the reader itself, so that the cache stays shared by goroutines
+func (c *CachedReader) Clone() Reader {
+	return c
+}
+
+// Purge drops all cached values and missed keys
+func (c *CachedReader) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.values = newLRUCache(c.values.maxEntries, c.values.maxBytes)
+	c.misses = newLRUCache(c.misses.maxEntries, 0)
+}
+
+// miss remembers the given missed key, if the negative cache is enabled
+func (c *CachedReader) miss(key []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.misses.maxEntries > 0 {
+		c.misses.add(key, nil)
+	}
+}
+
+// newLRUCache returns an empty cache with the given limits
+func newLRUCache(maxEntries, maxBytes int) lruCache {
+	return lruCache{
+		lru:        list.New(),
+		entries:    make(map[string]*list.Element),
+		maxEntries: maxEntries,
+		maxBytes:   maxBytes,
+	}
+}
+
+// lookup returns the cached value of the given key
+func (c *lruCache) lookup(key []byte) ([]byte, bool) {
+	element, ok := c.entries[string(key)]
+	if !ok {
+		return nil, false
+	}
+
+	c.lru.MoveToFront(element)
+
+	return element.Value.(*cacheEntry).value, true
+}
+
+// add caches the given pair <key, value>, evicting the least recently used ones over the limits
+func (c *lruCache) add(key, value []byte) {
+	size := len(key) + len(value)
+
+	if c.maxBytes > 0 && size > c.maxBytes {
+		return
+	}
+
+	if _, ok := c.entries[string(key)]; ok {
+		return
+	}
+
+	entry := &cacheEntry{string(key), value}
+	c.entries[entry.key] = c.lru.PushFront(entry)
+	c.bytes += size
+
+	for (c.maxEntries > 0 && c.lru.Len() > c.maxEntries) || (c.maxBytes > 0 && c.bytes > c.maxBytes) {
+		c.evict()
+	}
+}
+
+// evict drops the least recently used value
+func (c *lruCache) evict() {
+	element := c.lru.Back()
+	entry := element.Value.(*cacheEntry)
+
+	c.lru.Remove(element)
+	delete(c.entries, entry.key)
+	c.bytes -= len(entry.key) + len(entry.value)
+}
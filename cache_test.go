@@ -0,0 +1,79 @@
+package cdb
+
+func (suite *CDBTestSuite) TestCachedReader() {
+	suite.fillTestCDB()
+
+	reader := NewCachedReader(suite.getCDBReader(), 3, 0)
+
+	for _, rec := range suite.testRecords {
+		value, err := reader.Get(rec.key)
+		suite.Nil(err)
+		suite.Equal(rec.val, value)
+	}
+
+	suite.Equal(3, reader.values.lru.Len(), "Cache must be limited by entries")
+
+	rec := suite.testRecords[len(suite.testRecords)-1]
+	cached, ok := reader.values.lookup(rec.key)
+	suite.True(ok)
+	suite.Equal(rec.val, cached)
+
+	_, ok = reader.values.lookup(suite.testRecords[0].key)
+	suite.False(ok, "Least recently used value must be evicted")
+
+	_, err := reader.Get([]byte("missing key"))
+	suite.Equal(ErrEntryNotFound, err)
+
+	size := len(rec.key) + len(rec.val)
+	reader = NewCachedReader(suite.getCDBReader(), 0, 2*size)
+
+	for _, rec := range suite.testRecords {
+		_, err := reader.GetString(string(rec.key))
+		suite.Nil(err)
+	}
+
+	suite.Equal(2, reader.values.lru.Len(), "Cache must be limited by bytes")
+	suite.True(reader.values.bytes <= 2*size)
+
+	reader.Purge()
+	suite.Equal(0, reader.values.lru.Len())
+	suite.Equal(0, reader.values.bytes)
+}
+
+func (suite *CDBTestSuite) TestNegativeCache() {
+	suite.fillTestCDB()
+
+	reader := NewCachedReader(suite.getCDBReader(), 0, 0)
+	missing := []byte("missing key")
+
+	_, err := reader.Get(missing)
+	suite.Equal(ErrEntryNotFound, err)
+	suite.Equal(0, reader.misses.lru.Len(), "Negative cache must be disabled by default")
+
+	reader.SetNegativeCache(2)
+
+	for i := 0; i < 3; i++ {
+		_, err = reader.Get(missing)
+		suite.Equal(ErrEntryNotFound, err)
+
+		exists, err := reader.HasString(string(missing) + "2")
+		suite.Nil(err)
+		suite.False(exists)
+
+		exists, err = reader.Has(suite.testRecords[0].key)
+		suite.Nil(err)
+		suite.True(exists)
+	}
+
+	suite.Equal(2, reader.misses.lru.Len())
+
+	// A remembered miss must be answered without probing the database
+	reader.misses.add(suite.testRecords[1].key, nil)
+
+	exists, err := reader.Has(suite.testRecords[1].key)
+	suite.Nil(err)
+	suite.False(exists)
+
+	reader.Purge()
+	suite.Equal(0, reader.misses.lru.Len())
+}
@@ -3,63 +3,353 @@
 package cdb
 
 import (
+	"context"
 	"errors"
 	"hash"
 	"io"
+	"time"
 )
 
 const (
 	// Number of hash tables
 	tableNum = 256
+	// Maximum number of hash tables in the v2 format
+	maxTableNum = 1 << 16
+	// Maximum alignment of record starts in the v2 format
+	maxAlign = 1 << 16
 	// Maximum value of uint32
 	maxUint = 0xffffffff
 	// Size of 256 tables refs
 	tablesRefsSize = tableNum * 8
 	// Size of hash table slot
 	slotSize = 8
+	// Size of the upper half of a 64-bit hash, which extends slots with the 64-bit hash
+	hashHiSize = 4
 )
 
 // ErrOutOfMemory tells that it was an attempt to create a cdb database up to 4 gigabytes
 var ErrOutOfMemory = errors.New("OutOfMemory. CDB can handle any database up to 4 gigabytes")
 
+// ErrTooBig tells that a write would place data beyond the 4 gigabytes, which 32-bit offsets address.
+// Writers check offsets before writing, so nothing wraps around. It is the same error as ErrOutOfMemory,
+// so err == ErrOutOfMemory checks keep working, and a failed offset check can't be told from other
+// failures of the size limit. Writers never switch to a format with 64-bit offsets, a larger dataset
+// has to be split, e.g. by CDB.NewShardedWriter or CDB.CreatePartitioned.
+var ErrTooBig = ErrOutOfMemory
+
+// ErrInvalidTableNum tells that the requested number of hash tables is out of the supported range
+var ErrInvalidTableNum = errors.New("cdb table number must be in range [1, 65536]")
+
+// ErrInvalidAlignment tells that the requested record alignment is not a power of two up to 65536
+var ErrInvalidAlignment = errors.New("cdb record alignment must be a power of two up to 65536")
+
+// ErrInvalidValueSize tells that a value doesn't have the fixed value size of the database
+var ErrInvalidValueSize = errors.New("cdb value size differs from the fixed value size")
+
+// ErrExpiryDisabled tells that it was an attempt to put an expiring record without the expiry support
+var ErrExpiryDisabled = errors.New("cdb expiry support is disabled, see CDB.SetExpiry")
+
+// ErrWriterAborted tells that it was an attempt to use a writer after Abort
+var ErrWriterAborted = errors.New("cdb writer is aborted")
+
 // Hasher is a callback for creating a new instance of hash.Hash32.
 type Hasher func() hash.Hash32
 
+// Hasher64 is a constructor of 64-bit hash functions, see CDB.SetHash64
+type Hasher64 func() hash.Hash64
+
+// hash64Adapter implements hash.Hash32 over a hash.Hash64, Sum32 is the lower half of Sum64
+type hash64Adapter struct {
+	hash.Hash64
+}
+
+// Sum32 returns the lower half of the 64-bit hash
+func (h hash64Adapter) Sum32() uint32 {
+	return uint32(h.Sum64())
+}
+
 // CDB is an associative array: it maps strings (``keys'') to strings (``data'').
 type CDB struct {
 	Hasher
+	opts options
+}
+
+// options holds the format settings of a handle, which are copied into every new Writer.
+type options struct {
+	tableNum          uint32
+	align             uint32
+	prefixCompression bool
+	expiry            bool
+	recordFlags       bool
+	bloomBitsPerKey   uint32
+	fixedValueSize    bool
+	valueSize         uint32
+	versions          int
+	duplicates        DuplicatePolicy
+	buckets           bool
+	seededHash        bool
+	hash64            bool
+	// spillDir and spillSlots are used by writers only, see SetSpill
+	spillDir   string
+	spillSlots int
+	// expectedRecords and expectedBytes are size hints of writers, see SetExpectedRecords
+	expectedRecords int
+	expectedBytes   int64
+	hashWorkers     int
+	writeBufferSize int
+	sync            bool
+	progress        func(BuildProgress)
+	progressEvery   int
+	deterministic   bool
+	delta           bool
+	// scratchSize, maxPooledScratch and values are used by readers only, see SetBufferSizes
+	scratchSize      int
+	maxPooledScratch int
+	values           *valuePool
+	access           AccessPattern
+	hugePages        bool
+	// skipExpired, now and retry are used by readers only
+	skipExpired bool
+	now         func() time.Time
+	retry       retryPolicy
+	metrics     Metrics
+	tracer      Tracer
+	logger      eventLogger
+	slowRead    time.Duration
+}
+
+// flags returns the v2 header flags matching the options.
+func (o options) flags() uint32 {
+	var flags uint32
+
+	if o.prefixCompression {
+		flags |= flagPrefixCompression
+	}
+
+	if o.expiry {
+		flags |= flagExpiry
+	}
+
+	// Dropped versions and replaced duplicates are marked as superseded, deltas mark tombstones
+	if o.recordFlags || o.delta || o.versions > 0 || (o.versions == 0 && o.duplicates == DuplicatesKeepLast) {
+		flags |= flagRecordFlags
+	}
+
+	if o.versions != 0 {
+		flags |= flagVersioned
+	}
+
+	if o.buckets {
+		flags |= flagBuckets
+	}
+
+	if o.fixedValueSize {
+		flags |= flagFixedValueSize
+	}
+
+	if o.seededHash {
+		flags |= flagSeededHash
+	}
+
+	if o.hash64 {
+		flags |= flagHash64
+	}
+
+	return flags
+}
+
+// v2 tells if the options require the v2 format.
+func (o options) v2() bool {
+	return o.tableNum != tableNum || o.align > 1 || o.flags() != 0 || o.bloomBitsPerKey != 0
 }
 
 // Writer provides API for creating database.
 type Writer interface {
 	// Put saves a new associated pair <key, value> into databases. Returns an error on failure.
+	// Records are appended to the data section in the Put order, the n-th put record of a database
+	// gets the sequence number n starting from 0, see Iterator.Sequence.
 	Put(key []byte, value []byte) error
+	// PutMany saves the pairs <keys[i], values[i]> in the order of keys, the overhead of a Put
+	// is paid once per batch. Returns ErrBatchMismatch if the number of values differs from
+	// the number of keys. On an error the pairs before the failed one are saved, a sharded writer
+	// saves the pairs part by part.
+	PutMany(keys, values [][]byte) error
+	// PutReader saves a new associated pair <key, value>, the value of the given size is copied from r
+	// without buffering it in memory. A negative size means that the size is unknown: the value is read
+	// until io.EOF and its size is patched into the record afterwards. A short reader fails with
+	// io.ErrUnexpectedEOF. The record may be partially written on an error of r, Abort the writer then.
+	PutReader(key []byte, r io.Reader, size int64) error
+	// PutString saves a new associated pair <key, value> with a string key, the key is not copied.
+	PutString(key string, value []byte) error
+	// PutWithExpiry saves a new associated pair <key, value>, which expires at the given time.
+	// Requires the expiry support, see CDB.SetExpiry. A zero time means that the record never expires.
+	PutWithExpiry(key []byte, value []byte, expiresAt time.Time) error
+	// Bucket returns a Writer, which puts records into the bucket with the given name.
+	// Requires the buckets support, see CDB.SetBuckets. The empty name stands for the root bucket.
+	Bucket(name string) Writer
+	// Delete writes a tombstone of the key, which hides the key of older layers of Stack, see CDB.SetDelta.
+	// Returns ErrDeleteDisabled without the record flags support. Lookups of the database itself
+	// find the tombstone as a record with the empty value and the RecordTombstone flag.
+	Delete(key []byte) error
+	// Stats returns the statistics of the records put so far, see WriterStats. The statistics of
+	// a bucket writer cover the whole database.
+	Stats() WriterStats
+	// EstimateFinalSize returns the size of the database, if it is closed now without further puts,
+	// so build pipelines can enforce size budgets. The sorted index is not counted.
+	EstimateFinalSize() int64
+	// Flush pushes the buffered records to the underlying writer, e.g. between batches of a long build.
+	// The database is readable only after Close anyway.
+	Flush() error
 	// Close commits database, makes it possible for reading.
 	Close() error
+	// Abort discards the database instead of Close, e.g. when a build fails. Buffered records are dropped,
+	// a writer returned by CDB.Create closes and removes its file, otherwise the output, which is already
+	// written, is left to the caller. The writer can't be used after Abort.
+	Abort() error
 }
 
 // Reader provides API for retrieving values, iterating through dataset. All methods are thread safe.
 type Reader interface {
-	// Get returns the first value associated with the given key
+	// Get returns the first value associated with the given key, ErrEntryNotFound if there is no such key.
 	Get(key []byte) ([]byte, error)
+	// MultiGet returns the first values associated with the given keys and the errors of their lookups,
+	// ErrEntryNotFound for missing keys. Values are read in the file order, neighbouring ones at once.
+	MultiGet(keys [][]byte) ([][]byte, []error)
+	// GetSize returns the length of the first value associated with the given key without reading the value.
+	// Returns ErrEntryNotFound if there is no such key.
+	GetSize(key []byte) (int, error)
+	// GetRange returns at most length bytes of the first value associated with the given key starting
+	// from the given offset. The range is cut at the end of the value, an offset beyond it is ErrInvalidRange.
+	GetRange(key []byte, offset, length int) ([]byte, error)
+	// GetStream returns a stream of the first value associated with the given key, so that big values
+	// aren't read into memory. The stream is an *io.SectionReader inside, it also implements io.Seeker and io.ReaderAt.
+	GetStream(key []byte) (io.ReadCloser, error)
+	// GetInto copies the first value associated with the given key into dst, which is grown only
+	// if it is too small, and returns the buffer with the value and the length of the value.
+	GetInto(key, dst []byte) ([]byte, int, error)
+	// GetVersion returns the n-th value associated with the given key, starting from 0.
+	// Versions of a versioned database go from the newest to the oldest one,
+	// otherwise values go in the insertion order. See CDB.SetVersions.
+	GetVersion(key []byte, n int) ([]byte, error)
+	// Versions returns all values associated with the given key in the order of GetVersion.
+	Versions(key []byte) ([][]byte, error)
+	// CountKey returns the number of values associated with the given key, 0 if there is no such key.
+	// Values aren't read, GetVersion(key, i) for i in [0, CountKey(key)) enumerates them.
+	CountKey(key []byte) (int, error)
+	// GetString returns the first value associated with the given string key, the key is not copied.
+	GetString(key string) ([]byte, error)
 	// Has returns true if the given key exists, otherwise returns false.
 	Has(key []byte) (bool, error)
-	// Iterator returns a new Iterator object that points on the first record.
+	// MultiHas tells which of the given keys exist. Only keys are compared, values aren't read.
+	MultiHas(keys [][]byte) ([]bool, error)
+	// HasString returns true if the given string key exists, the key is not copied.
+	HasString(key string) (bool, error)
+	// Iterator returns a new Iterator object that points on the first record. The iterator walks
+	// records in the Put order, parts of a sharded database one by one.
 	Iterator() (Iterator, error)
+	// KeyIterator is like Iterator, but the iterator is tuned for scans of keys: a key is read
+	// together with its record header and values are never touched, unless Value is called.
+	KeyIterator() (Iterator, error)
+	// GetContext, HasContext and IteratorContext are like Get, Has and Iterator, but fail with the context
+	// error once the given context is done. The context is checked before every read of the database.
+	GetContext(ctx context.Context, key []byte) ([]byte, error)
+	HasContext(ctx context.Context, key []byte) (bool, error)
+	IteratorContext(ctx context.Context) (Iterator, error)
 	// IteratorAt returns a new Iterator object that points on the first record associated with the given key.
+	// Returns ErrEntryNotFound if there is no such key.
 	IteratorAt(key []byte) (Iterator, error)
+	// Range returns a new Iterator object, which walks records with keys in range [start, end) in the key order.
+	// A nil bound means that the range is not bounded from that side. Requires the sorted index.
+	// Returns ErrEntryNotFound if no record is in the range, the returned iterator is never nil otherwise.
+	Range(start, end []byte) (Iterator, error)
+	// Iterate is like Range, but the given bounds tell if records with the start and the end keys
+	// belong to the range, e.g. Iterate(start, end, IncludeBoth) walks the closed range [start, end].
+	// Range(start, end) is Iterate(start, end, IncludeStart). Requires the sorted index.
+	// Returns ErrEntryNotFound if no record is in the range.
+	Iterate(start, end []byte, bounds Bounds) (Iterator, error)
+	// IteratePrefix returns a new Iterator object, which walks records with keys starting with the given prefix.
+	// Records are walked in the key order with the sorted index, otherwise the whole database is scanned
+	// in the file order. Returns nil if there is no such record.
+	IteratePrefix(prefix []byte) (Iterator, error)
+	// WalkSlots calls fn for every slot of every hash table, including empty ones, until fn returns an error,
+	// which is returned then, except Stop, which just stops the walk. It is meant for diagnostic tools,
+	// e.g. to visualize collision clusters, see SlotInfo.
+	WalkSlots(fn func(slot SlotInfo) error) error
+	// Sample returns at most n distinct records chosen at random, about uniformly, for spot checks of data.
+	// Fewer records are returned, if the database has fewer ones or they are hard to find, e.g. expired.
+	Sample(n int) ([]Record, error)
+	// Segments splits the records into at most n disjoint segments of about the same size and returns
+	// iterators over them, so that n goroutines can scan the database together. Every iterator points
+	// on the first record of its segment, the segments cover all records in the file order.
+	// A segment of a sharded or a stacked reader may walk several parts.
+	Segments(n int) ([]Iterator, error)
+	// ResumeIterator returns a new Iterator object, which points on the record of the given cursor
+	// and walks the records in the order of the iterator, which exported the cursor, see Iterator.Cursor.
+	// A resumed iterator of a sharded or a stacked reader walks the rest of the part of the cursor
+	// and the following parts. Returns ErrInvalidCursor if the cursor doesn't belong to the database,
+	// e.g. the database was rewritten.
+	ResumeIterator(cursor Cursor) (Iterator, error)
+	// OrderedIterator and ReverseIterator return a new Iterator object, which walks all records
+	// in the key order and in the reverse key order. Records with equal keys are walked in the insertion
+	// order and in the reverse insertion order. Require the sorted index.
+	OrderedIterator() (Iterator, error)
+	ReverseIterator() (Iterator, error)
 	// Size returns the size of the dataset
 	Size() int
+	// Bucket returns a Reader, which gives access to the records of the bucket with the given name only.
+	// The empty name stands for the root bucket. A missing bucket is empty.
+	// A bucket reader shares resources with its parent, its Close does nothing.
+	Bucket(name string) Reader
+	// Check re-reads the header and validates a sample of hash table slots, so that long-running
+	// servers can periodically probe the integrity of the files they serve. Returns a *CorruptionError
+	// on a mismatch. The context bounds the reads.
+	Check(ctx context.Context) error
+	// Clone returns a Reader, which shares the database with this one, but owns its hasher
+	// and scratch buffers. A clone must be used by one goroutine at a time, so that a server
+	// can keep a clone per goroutine without contention. Like a bucket reader, a clone
+	// shares resources with its parent, its Close does nothing.
+	Clone() Reader
+	// Warmup preloads the hash tables into the page cache, so that first lookups don't pay
+	// cold-cache penalties. If data is true, the data section is preloaded too, in background
+	// by fadvise if the database is a file on Linux.
+	Warmup(data bool) error
+	// GetAt returns the key and the value of the record at the given offset, see Iterator.Offset.
+	// It lets external indexes reference records by position. Returns ErrInvalidOffset if there is
+	// no record at the offset and ErrEntryNotFound if the reader treats the record as not existing.
+	GetAt(offset uint32) ([]byte, []byte, error)
+	// Stats returns the statistics of the hash tables of the database, see Stats.
+	Stats() (Stats, error)
+	// Close releases resources of the reader, it is a no-op for readers over plain files.
+	// Close must not be called while other calls are in flight, the reader must not be used after it.
+	// Values returned by a memory-mapped reader become invalid. Repeated calls do nothing.
+	Close() error
 }
 
 // Iterator provides API for iterating through database's records. Do not share object between multiple goroutines.
+// Values are loaded lazily: Next reads the record header only, a value is read when Value
+// is called or the reader of Record().Value is read, so callers filtering on keys don't pay
+// for values they skip.
+// An iterator walks a snapshot: a database file is immutable, so the records, which an iterator
+// yields, don't change however long the scan takes. An iterator of a ReloadingReader walks
+// the version of the file it was obtained from, it must not be used after the next reload,
+// which closes that version.
 type Iterator interface {
 	// Next moves the iterator to the next record. Returns true on success otherwise returns false.
+	// At the end of data Next returns false and a nil error. If a record can't be read, e.g. the database
+	// is truncated or corrupted, Next returns false and the error, the iterator stays stopped then.
 	Next() (bool, error)
+	// Err returns the error, which stopped the iterator, nil if the iterator is not stopped by an error.
+	// It lets callers, which loop on HasNext or ignore the result of Next, tell the end of data from a failure.
+	Err() error
 	// Record returns the current record. This method is lazy. It means, a data is read on require.
 	Record() Record
 	// HasNext tells if the iterator can be moved to the next record.
 	HasNext() bool
+	// Flags returns the raw flags of the current record, 0 if the database has no record flags.
+	Flags() RecordFlags
+	// Offset returns the offset of the current record relative to the database start, see Reader.GetAt.
+	// Records of a sharded database are addressed by offsets inside their parts.
+	Offset() uint32
 	// Key returns key's []byte slice. It is usually easier to use and
 	// faster then iterator.Record().Key().
 	// Because it doesn't requiers allocation for record copy.
@@ -67,9 +357,54 @@ type Iterator interface {
 	// ValueBytes returns values's []byte slice. It is usually easier to use and
 	// faster then iterator.Record().Key().
 	// Because it doesn't requiers allocation for record copy.
+	// The value is read by every call, it isn't kept by the iterator.
 	Value() ([]byte, error)
+	// Cursor returns an opaque cursor of the current record, which survives restarts of the process,
+	// see Reader.ResumeIterator. Returns ErrNoCursor for iterators merging several databases
+	// in the key order and for filtered iterators.
+	Cursor() (Cursor, error)
+	// Seek moves the iterator to the first record associated with the given key, so Next continues
+	// from the record following it: the next one in the file, or the next one in the key order
+	// for iterators returned by Reader.Range. Returns ErrEntryNotFound and leaves the iterator
+	// unchanged if the iterator can't reach the key. Iterators merging several databases
+	// in the key order return ErrMergedSeek.
+	Seek(key []byte) error
+	// Sequence returns the sequence number of the current record, that is the number of records put
+	// before it into its database or its part of a sharded database, see Writer.Put. Skipped records,
+	// e.g. superseded versions, are counted too. The number is counted for free while the iterator
+	// walks from the first record, otherwise the first call walks record headers up to the current record.
+	Sequence() (uint32, error)
+	// Reset moves the iterator back to the record it pointed on when it was created, so a scan is
+	// restarted without a new iterator. The error, which stopped the iterator, is cleared.
+	Reset() error
+	// Skip moves the iterator n records forward like n calls of Next. Returns the number of skipped
+	// records, which is less than n at the end of data. Iterators of the sorted index skip records
+	// without reading them, unless the database has records, which iterators skip.
+	Skip(n int) (int, error)
+	// Position returns the progress of the iterator: the number of records it has pointed on,
+	// the offset of the current record and the done fraction of the walk.
+	Position() Position
 }
 
+// RecordFlags is a bitmask stored with every record of a database with the record flags support,
+// see CDB.SetRecordFlags. It lets features, which change the meaning of a record, compose
+// without new formats.
+type RecordFlags uint8
+
+const (
+	// RecordCompressed tells that the value is compressed
+	RecordCompressed RecordFlags = 1 << iota
+	// RecordEncrypted tells that the value is encrypted
+	RecordEncrypted
+	// RecordTombstone tells that the record marks its key as deleted
+	RecordTombstone
+	// RecordHasTTL tells that the record has an expiration time
+	RecordHasTTL
+	// RecordSuperseded tells that newer versions of the key replaced the record,
+	// it is invisible for lookups and iteration, see CDB.SetVersions
+	RecordSuperseded
+)
+
 // Record provides API for reading record key, value.
 type Record interface {
 	// Key returns io.Reader with given record's key and key size.
@@ -80,7 +415,14 @@ type Record interface {
 
 // New returns a new instance of CDB struct.
 func New() *CDB {
-	return &CDB{NewHash}
+	return &CDB{
+		Hasher: NewHash,
+		opts: options{
+			tableNum:    tableNum,
+			now:         time.Now,
+			scratchSize: scratchSize,
+		},
+	}
 }
 
 // SetHash tells the cdb to use the given hash function for calculations.
@@ -92,14 +434,297 @@ func New() *CDB {
 // h.GetReader(f)  - only new instances will be use fnv.Hash32
 func (cdb *CDB) SetHash(hasher Hasher) {
 	cdb.Hasher = hasher
+	cdb.opts.hash64 = false
+}
+
+// SetHash64 tells the cdb to use the given 64-bit hash function. Slots keep the whole 64-bit
+// key hash, so that probes of databases with hundreds of millions of keys rarely hit slots
+// of other keys and compare keys in vain. The 64-bit hash makes writers produce the v2 format,
+// a reader of such a database requires a handle with a 64-bit hash too.
+// Like SetHash, it affects only new instances of Reader, Writer.
+func (cdb *CDB) SetHash64(hasher Hasher64) {
+	cdb.Hasher = func() hash.Hash32 {
+		return hash64Adapter{hasher()}
+	}
+	cdb.opts.hash64 = true
+}
+
+// SetTableNum tells the cdb to distribute records over n top-level hash tables instead of 256.
+// Small databases benefit from fewer tables (smaller header), huge ones from more tables
+// (shorter hash tables). A table number other than 256 makes writers produce the v2 format,
+// which records the table number in the header, so readers adapt automatically.
+// Like SetHash, it affects only new instances of Writer.
+func (cdb *CDB) SetTableNum(n int) error {
+	if n < 1 || n > maxTableNum {
+		return ErrInvalidTableNum
+	}
+
+	cdb.opts.tableNum = uint32(n)
+
+	return nil
+}
+
+// SetSeededHash tells the cdb to hash keys by SipHash keyed with a random seed instead of
+// the Hasher of the handle, so that attacker-controlled keys can't force worst-case probe chains.
+// Every writer draws its own seed and stores it in the header, readers pick it up automatically.
+// The seeded hash makes writers produce the v2 format. Like SetHash, it affects only new
+// instances of Writer.
+func (cdb *CDB) SetSeededHash(enabled bool) {
+	cdb.opts.seededHash = enabled
+}
+
+// SetAlignment tells the cdb to start every record at a position multiple of n bytes,
+// padding the gaps with zeros. For example, 8 lets mmap'd readers cast fixed-size values
+// of records with equal key sizes directly, 4096 keeps O_DIRECT readers from straddling sectors.
+// n must be a power of two, 1 disables the alignment. An alignment makes writers produce
+// the v2 format. Like SetHash, it affects only new instances of Writer.
+func (cdb *CDB) SetAlignment(n int) error {
+	if n < 1 || n > maxAlign || n&(n-1) != 0 {
+		return ErrInvalidAlignment
+	}
+
+	cdb.opts.align = uint32(n)
+
+	return nil
+}
+
+// SetKeyPrefixCompression tells the cdb to store keys with the front coding: a key sharing
+// a long prefix with a recent key keeps only its distinct suffix and a reference to that key.
+// It saves a lot of space for datasets with long common key prefixes (URLs, file paths),
+// especially when keys are put in the sorted order. Readers restore full keys transparently.
+// Enabled compression makes writers produce the v2 format. Like SetHash, it affects only
+// new instances of Writer.
+func (cdb *CDB) SetKeyPrefixCompression(enabled bool) {
+	cdb.opts.prefixCompression = enabled
+}
+
+// SetExpiry tells the cdb to store an expiration time with every record, so that
+// Writer.PutWithExpiry can be used. The expiry support makes writers produce the v2 format.
+// Like SetHash, it affects only new instances of Writer.
+func (cdb *CDB) SetExpiry(enabled bool) {
+	cdb.opts.expiry = enabled
+}
+
+// SetRecordFlags tells the cdb to reserve a flags byte for every record, see RecordFlags.
+// The record flags make writers produce the v2 format. Like SetHash, it affects only
+// new instances of Writer.
+func (cdb *CDB) SetRecordFlags(enabled bool) {
+	cdb.opts.recordFlags = enabled
+}
+
+// SetBloomFilter tells the cdb to build a bloom filter with the given number of bits per key
+// on Writer.Close. Readers consult the filter before touching hash tables, so most lookups
+// of missing keys cost no disk reads. 10 bits per key give about 1% of false positives,
+// 0 disables the filter. The filter makes writers produce the v2 format.
+// Like SetHash, it affects only new instances of Writer.
+func (cdb *CDB) SetBloomFilter(bitsPerKey int) error {
+	if bitsPerKey < 0 || bitsPerKey > maxBloomBitsPerKey {
+		return ErrInvalidBloomBitsPerKey
+	}
+
+	cdb.opts.bloomBitsPerKey = uint32(bitsPerKey)
+
+	return nil
+}
+
+// SetFixedValueSize tells the cdb that all values have the same size of n bytes
+// (8-byte counters, 16-byte IDs, or even empty values of a set). Records don't store
+// value sizes then, the header keeps the size of all values, so every record is 4 bytes smaller.
+// Values stay next to their keys, there is no separate value region.
+// Writer.Put returns ErrInvalidValueSize for a value of another size.
+// A negative n disables the mode. The fixed value size makes writers produce the v2 format.
+// Like SetHash, it affects only new instances of Writer.
+func (cdb *CDB) SetFixedValueSize(n int) error {
+	if int64(n) > maxUint {
+		return ErrTooBig
+	}
+
+	if n < 0 {
+		cdb.opts.fixedValueSize, cdb.opts.valueSize = false, 0
+	} else {
+		cdb.opts.fixedValueSize, cdb.opts.valueSize = true, uint32(n)
+	}
+
+	return nil
+}
+
+// SetVersions tells the cdb to treat a Put of an already written key as a new version of its value
+// instead of an independent duplicate. Get returns the newest version then, Reader.GetVersion and
+// Reader.Versions give access to older ones. A positive n keeps only the last n versions of a key:
+// older ones become invisible for lookups and iteration (they still occupy space in the file).
+// A negative n keeps all versions, 0 disables versioning. Versioning makes writers produce the v2 format,
+// a limited number of versions requires the record flags and makes the writer keep all keys in memory.
+// Like SetHash, it affects only new instances of Writer.
+func (cdb *CDB) SetVersions(n int) {
+	cdb.opts.versions = n
+}
+
+// SetBuckets tells the cdb to store the bucket of every record, so that multiple logical keyspaces
+// can live in a single database, see Writer.Bucket and Reader.Bucket. The buckets support makes
+// writers produce the v2 format. Like SetHash, it affects only new instances of Writer.
+func (cdb *CDB) SetBuckets(enabled bool) {
+	cdb.opts.buckets = enabled
+}
+
+// SetSkipExpired tells readers to treat expired records as not found: Get returns ErrEntryNotFound,
+// Has returns false and iterators skip them. Databases without the expiry support are not affected.
+// Like SetHash, it affects only new instances of Reader.
+func (cdb *CDB) SetSkipExpired(enabled bool) {
+	cdb.opts.skipExpired = enabled
 }
 
-// GetWriter returns a new Writer object.
+// GetWriter returns a new Writer object. The database starts at the current position of the writer.
 func (cdb *CDB) GetWriter(writer io.WriteSeeker) (Writer, error) {
-	return newWriter(writer, cdb.Hasher)
+	return newWriter(writer, cdb.Hasher, cdb.opts)
 }
 
 // GetReader returns a new Reader object.
 func (cdb *CDB) GetReader(reader io.ReaderAt) (Reader, error) {
-	return newReader(reader, cdb.Hasher)
+	return newReader(reader, cdb.Hasher, cdb.opts)
+}
+
+// GetReaderAt returns a new Reader object for the database, which lives inside a larger file
+// (a tar member, a custom bundle, an executable trailer) at the given offset and has the given length.
+// A database is written inside a larger file by GetWriter of a writer positioned at the offset.
+func (cdb *CDB) GetReaderAt(reader io.ReaderAt, offset, length int64) (Reader, error) {
+	return cdb.GetReader(io.NewSectionReader(reader, offset, length))
+}
+
+// GetWriterWithIndex returns a new Writer object, which also writes the sorted index
+// of the database to the given index writer on Close. The writer keeps all keys
+// in memory until Close to sort them.
+func (cdb *CDB) GetWriterWithIndex(writer io.WriteSeeker, index io.Writer) (Writer, error) {
+	w, err := newWriter(writer, cdb.Hasher, cdb.opts)
+	if err != nil {
+		return nil, err
+	}
+
+	w.index = index
+
+	if cdb.opts.expectedRecords > 0 {
+		w.entries = make([]indexEntry, 0, cdb.opts.expectedRecords)
+	}
+
+	return w, nil
+}
+
+// GetReaderWithIndex returns a new Reader object, which uses the given sorted index
+// for ordered and range access, see Reader.Range.
+func (cdb *CDB) GetReaderWithIndex(reader io.ReaderAt, index io.ReaderAt) (Reader, error) {
+	r, err := newReader(reader, cdb.Hasher, cdb.opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.index, err = readIndex(index); err != nil {
+		return nil, err
+	}
+
+	if r.index.count != r.total {
+		return nil, ErrInvalidIndex
+	}
+
+	return r, nil
+}
+
+// Vacuum copies all records of the database read from src, except the expired ones, into
+// a fresh database written to dst. Expiration times of the remaining records are preserved.
+// The destination keeps the format settings of the source, e.g. the number of tables, versioning,
+// the fixed value size and the bloom filter. Vacuum stops at the first error.
+func (cdb *CDB) Vacuum(dst io.WriteSeeker, src io.ReaderAt) error {
+	readerOpts := cdb.opts
+	readerOpts.skipExpired = true
+
+	reader, err := newReader(src, cdb.Hasher, readerOpts)
+	if err != nil {
+		return err
+	}
+
+	reader.allBuckets = true
+
+	// The destination keeps the format settings of the source
+	writerOpts := cdb.opts
+	writerOpts.tableNum = reader.header.tableNum
+	writerOpts.align = reader.header.align
+	writerOpts.prefixCompression = reader.header.flags&flagPrefixCompression != 0
+	writerOpts.expiry = reader.header.flags&flagExpiry != 0
+	writerOpts.recordFlags = reader.header.flags&flagRecordFlags != 0
+	writerOpts.buckets = reader.header.flags&flagBuckets != 0
+	writerOpts.seededHash = reader.header.flags&flagSeededHash != 0
+	writerOpts.hash64 = reader.header.flags&flagHash64 != 0
+	writerOpts.fixedValueSize = reader.header.flags&flagFixedValueSize != 0
+	writerOpts.valueSize = reader.header.valueSize
+	writerOpts.bloomBitsPerKey = 0
+
+	if reader.bloom != nil {
+		writerOpts.bloomBitsPerKey = reader.bloom.bitsPerKey(reader.total)
+	}
+
+	// Versions, which survived the source build, are all kept
+	if reader.header.flags&flagVersioned == 0 {
+		writerOpts.versions = 0
+	} else if writerOpts.versions == 0 {
+		writerOpts.versions = -1
+	}
+
+	writer, err := newWriter(dst, cdb.Hasher, writerOpts)
+	if err != nil {
+		return err
+	}
+
+	if err := vacuumRecords(reader, writer); err != nil {
+		writer.Abort()
+		return err
+	}
+
+	return writer.Close()
+}
+
+// vacuumRecords copies the records of the reader, which are not expired, to the writer.
+// It stops at the first error.
+func vacuumRecords(reader *readerImpl, writer *writerImpl) error {
+	// Maps bucket ids of the source to ones of the destination
+	bucketIDs := map[uint32]uint32{0: 0}
+
+	// Buckets are created in the order of their ids, so the destination keeps the ids
+	for _, name := range sortedBucketNames(reader.buckets) {
+		bucketIDs[reader.buckets[name].id] = writer.Bucket(name).(*bucketWriter).id
+	}
+
+	if reader.IsEmpty() {
+		return nil
+	}
+
+	iter, err := reader.newIterator(reader.header.dataPosition(), nil, nil)
+	if err != nil {
+		return err
+	}
+
+	for {
+		ok, err := iter.Next()
+		if err != nil {
+			return err
+		}
+
+		if !ok {
+			return nil
+		}
+
+		key, err := iter.Key()
+		if err != nil {
+			return err
+		}
+
+		value, err := iter.Value()
+		if err != nil {
+			return err
+		}
+
+		meta := iter.meta
+		meta.bucket = bucketIDs[meta.bucket]
+
+		if err := writer.put(key, value, meta); err != nil {
+			return err
+		}
+	}
 }
@@ -1,12 +1,17 @@
 package cdb
 
 import (
+	"bytes"
+	"encoding/binary"
+	"hash"
 	"hash/fnv"
+	"io"
 	"io/ioutil"
 	"os"
 	"strconv"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/suite"
 )
@@ -64,6 +69,13 @@ func (suite *CDBTestSuite) TearDownTest() {
 	suite.Nilf(err, "Can't remove cdb file: %#v", err)
 }
 
+func (suite *CDBTestSuite) resetTestCDB() {
+	err := suite.cdbFile.Truncate(0)
+	suite.Require().Nilf(err, "Can't truncate cdb file: %#v", err)
+	_, err = suite.cdbFile.Seek(0, io.SeekStart)
+	suite.Require().Nilf(err, "Can't seek cdb file: %#v", err)
+}
+
 func (suite *CDBTestSuite) fillTestCDB() {
 
 	writer := suite.getCDBWriter()
@@ -150,6 +162,973 @@ func (suite *CDBTestSuite) TestSetHash() {
 	suite.TestShouldReturnAllValues()
 }
 
+func (suite *CDBTestSuite) TestSetSeededHash() {
+	suite.cdbHandle.SetSeededHash(true)
+	suite.TestShouldReturnAllValues()
+	suite.resetTestCDB()
+	suite.TestIterator()
+	suite.resetTestCDB()
+
+	// The seed is taken from the header, the Hasher of the reading handle doesn't matter
+	suite.fillTestCDB()
+	handle := New()
+	handle.SetHash(fnv.New32)
+
+	reader, err := handle.GetReader(suite.cdbFile)
+	suite.Require().Nil(err)
+
+	for _, rec := range suite.testRecords {
+		value, err := reader.Get(rec.key)
+		suite.Nil(err)
+		suite.Equal(rec.val, value)
+	}
+}
+
+func (suite *CDBTestSuite) TestSetHash64() {
+	suite.cdbHandle.SetHash64(fnv.New64a)
+	suite.TestShouldReturnAllValues()
+	suite.resetTestCDB()
+	suite.TestIterator()
+	suite.resetTestCDB()
+
+	suite.fillTestCDB()
+	reader := suite.getCDBReader()
+
+	stats, err := reader.Stats()
+	suite.Nil(err)
+	suite.Equal(len(suite.testRecords), stats.Records)
+
+	_, err = New().GetReader(suite.cdbFile)
+	suite.Equal(ErrNoHash64, err)
+
+	// The seeded hash is 64-bit as well
+	suite.resetTestCDB()
+	suite.cdbHandle.SetSeededHash(true)
+	suite.TestShouldReturnAllValues()
+}
+
+func (suite *CDBTestSuite) TestSipHash() {
+	// The reference vector of SipHash-2-4
+	message := make([]byte, 15)
+	for i := range message {
+		message[i] = byte(i)
+	}
+
+	h := newSipHasher(0x0706050403020100, 0x0f0e0d0c0b0a0908)().(*sipHash)
+	h.Write(message[:3])
+	h.Write(message[3:])
+	suite.Equal(uint64(0xa129ca6149be45e5), h.Sum64())
+}
+
+func (suite *CDBTestSuite) TestSetTableNum() {
+	for _, n := range []int{1, 16, 4096} {
+		suite.Require().Nil(suite.cdbHandle.SetTableNum(n))
+		suite.TestShouldReturnAllValues()
+		suite.resetTestCDB()
+		suite.TestIterator()
+		suite.resetTestCDB()
+	}
+
+	suite.Equal(ErrInvalidTableNum, suite.cdbHandle.SetTableNum(0))
+	suite.Equal(ErrInvalidTableNum, suite.cdbHandle.SetTableNum(maxTableNum+1))
+}
+
+func (suite *CDBTestSuite) TestReaderAdaptsToTableNum() {
+	suite.Require().Nil(suite.cdbHandle.SetTableNum(16))
+	suite.fillTestCDB()
+
+	reader, err := New().GetReader(suite.cdbFile)
+	suite.Require().Nil(err)
+
+	for _, rec := range suite.testRecords {
+		value, err := reader.Get(rec.key)
+		suite.Nil(err)
+		suite.Equal(rec.val, value)
+	}
+}
+
+func (suite *CDBTestSuite) TestSetAlignment() {
+	for _, n := range []int{8, 4096} {
+		suite.Require().Nil(suite.cdbHandle.SetAlignment(n))
+		suite.TestShouldReturnAllValues()
+		suite.resetTestCDB()
+		suite.TestIterator()
+		suite.resetTestCDB()
+		suite.TestIteratorAt()
+		suite.resetTestCDB()
+	}
+
+	suite.Equal(ErrInvalidAlignment, suite.cdbHandle.SetAlignment(0))
+	suite.Equal(ErrInvalidAlignment, suite.cdbHandle.SetAlignment(12))
+	suite.Equal(ErrInvalidAlignment, suite.cdbHandle.SetAlignment(maxAlign*2))
+}
+
+func (suite *CDBTestSuite) TestAlignedRecordStarts() {
+	suite.Require().Nil(suite.cdbHandle.SetAlignment(64))
+	suite.fillTestCDB()
+
+	iterator := suite.mustGetCDBIterator()
+
+	for {
+		record := iterator.Record().(*record)
+		suite.Zero((record.keySectionFactory.position - 8) % 64)
+
+		ok, err := iterator.Next()
+		suite.Require().Nil(err)
+
+		if !ok {
+			break
+		}
+	}
+}
+
+func (suite *CDBTestSuite) TestKeyPrefixCompression() {
+	for i := range suite.testRecords {
+		suite.testRecords[i].key = append([]byte("https://example.com/some/long/path/"), suite.testRecords[i].key...)
+	}
+
+	suite.fillTestCDB()
+	info, err := suite.cdbFile.Stat()
+	suite.Require().Nil(err)
+	plainSize := info.Size()
+
+	suite.resetTestCDB()
+	suite.cdbHandle.SetKeyPrefixCompression(true)
+	suite.TestShouldReturnAllValues()
+
+	info, err = suite.cdbFile.Stat()
+	suite.Require().Nil(err)
+	suite.Less(info.Size(), plainSize)
+
+	suite.resetTestCDB()
+	suite.TestIterator()
+	suite.resetTestCDB()
+	suite.TestIteratorAt()
+}
+
+func (suite *CDBTestSuite) fillExpiringTestCDB(now time.Time) {
+	suite.cdbHandle.SetExpiry(true)
+	writer := suite.getCDBWriter()
+
+	// odd records are expired
+	for i, rec := range suite.testRecords {
+		expiresAt := now.Add(time.Hour)
+		if i%2 == 1 {
+			expiresAt = now.Add(-time.Hour)
+		}
+
+		suite.Require().Nil(writer.PutWithExpiry(rec.key, rec.val, expiresAt))
+	}
+
+	suite.Require().Nil(writer.Close())
+}
+
+func (suite *CDBTestSuite) TestPutWithExpiryDisabled() {
+	writer := suite.getCDBWriter()
+	suite.Equal(ErrExpiryDisabled, writer.PutWithExpiry([]byte("key"), []byte("val"), time.Now()))
+	suite.Nil(writer.Close())
+}
+
+func (suite *CDBTestSuite) TestSkipExpired() {
+	now := time.Now()
+	suite.fillExpiringTestCDB(now)
+
+	// Expired records are visible until the skip expired mode is enabled
+	suite.Equal(len(suite.testRecords), suite.countIteratedRecords(suite.getCDBReader()))
+
+	suite.cdbHandle.SetSkipExpired(true)
+	suite.cdbHandle.opts.now = func() time.Time { return now }
+	reader := suite.getCDBReader()
+
+	for i, rec := range suite.testRecords {
+		value, err := reader.Get(rec.key)
+		exists, _ := reader.Has(rec.key)
+
+		if i%2 == 1 {
+			suite.Equal(ErrEntryNotFound, err)
+			suite.False(exists)
+		} else {
+			suite.Nil(err)
+			suite.Equal(rec.val, value)
+			suite.True(exists)
+		}
+	}
+
+	suite.Equal(len(suite.testRecords)/2, suite.countIteratedRecords(reader))
+}
+
+func (suite *CDBTestSuite) TestVacuum() {
+	now := time.Now()
+	suite.fillExpiringTestCDB(now)
+	suite.cdbHandle.opts.now = func() time.Time { return now }
+
+	f, err := ioutil.TempFile("", "test_vacuum_*.cdb")
+	suite.Require().Nil(err)
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	suite.Require().Nil(suite.cdbHandle.Vacuum(f, suite.cdbFile))
+
+	reader, err := suite.cdbHandle.GetReader(f)
+	suite.Require().Nil(err)
+	suite.Equal(len(suite.testRecords)/2, reader.Size())
+
+	for i, rec := range suite.testRecords {
+		exists, err := reader.Has(rec.key)
+		suite.Nil(err)
+		suite.Equal(i%2 == 0, exists)
+	}
+
+	// Remaining records keep their expiration time
+	suite.cdbHandle.opts.now = func() time.Time { return now.Add(2 * time.Hour) }
+	suite.cdbHandle.SetSkipExpired(true)
+	reader, err = suite.cdbHandle.GetReader(f)
+	suite.Require().Nil(err)
+
+	_, err = reader.Iterator()
+	suite.Equal(ErrEmptyCDB, err)
+}
+
+func (suite *CDBTestSuite) TestVacuumKeepsSettings() {
+	suite.Require().Nil(suite.cdbHandle.SetTableNum(16))
+	suite.Require().Nil(suite.cdbHandle.SetFixedValueSize(4))
+	suite.Require().Nil(suite.cdbHandle.SetBloomFilter(10))
+	suite.cdbHandle.SetVersions(-1)
+
+	writer := suite.getCDBWriter()
+	for _, rec := range suite.testRecords {
+		suite.Require().Nil(writer.Put(rec.key, rec.val))
+	}
+	suite.Require().Nil(writer.Put(suite.testRecords[0].key, []byte("next")))
+	suite.Require().Nil(writer.Close())
+
+	f, err := ioutil.TempFile("", "test_vacuum_*.cdb")
+	suite.Require().Nil(err)
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	// The handle of Vacuum has the default settings
+	suite.Require().Nil(New().Vacuum(f, suite.cdbFile))
+
+	source := suite.getCDBReader().(*readerImpl)
+
+	reader, err := New().GetReader(f)
+	suite.Require().Nil(err)
+
+	vacuumed := reader.(*readerImpl)
+	suite.Equal(uint32(16), vacuumed.header.tableNum)
+	suite.Equal(uint32(4), vacuumed.header.valueSize)
+	suite.Equal(source.header.flags, vacuumed.header.flags)
+	suite.Require().NotNil(vacuumed.bloom)
+	suite.Equal(source.bloom.hashNum, vacuumed.bloom.hashNum)
+	suite.Equal(source.bloom.bitNum, vacuumed.bloom.bitNum)
+	suite.Equal(len(suite.testRecords)+1, reader.Size())
+
+	versions, err := reader.Versions(suite.testRecords[0].key)
+	suite.Nil(err)
+	suite.Equal([][]byte{[]byte("next"), suite.testRecords[0].val}, versions)
+}
+
+func (suite *CDBTestSuite) TestBloomFilter() {
+	suite.Require().Nil(suite.cdbHandle.SetBloomFilter(10))
+	suite.TestShouldReturnAllValues()
+
+	reader := suite.getCDBReader().(*readerImpl)
+	suite.Require().NotNil(reader.bloom)
+
+	falsePositives := 0
+	for i := 0; i < 1000; i++ {
+		key := []byte("missing" + strconv.Itoa(i))
+		if h, _ := reader.calcHash(key); reader.bloom.mayContain(h) {
+			falsePositives++
+		}
+
+		exists, err := reader.Has(key)
+		suite.Nil(err)
+		suite.False(exists)
+	}
+
+	suite.Less(falsePositives, 100)
+
+	suite.resetTestCDB()
+	suite.TestIterator()
+
+	suite.Equal(ErrInvalidBloomBitsPerKey, suite.cdbHandle.SetBloomFilter(-1))
+	suite.Equal(ErrInvalidBloomBitsPerKey, suite.cdbHandle.SetBloomFilter(maxBloomBitsPerKey+1))
+
+	suite.Equal(uint32(10), newBloomFilter(1000, 10).bitsPerKey(1000))
+	suite.Equal(uint32(maxBloomBitsPerKey), newBloomFilter(100, maxBloomBitsPerKey).bitsPerKey(100))
+}
+
+func (suite *CDBTestSuite) TestCorruptedBloomFilter() {
+	suite.Require().Nil(suite.cdbHandle.SetBloomFilter(10))
+	suite.fillTestCDB()
+
+	pos := suite.getCDBReader().(*readerImpl).header.bloom
+
+	// The number of bits points past the end of the file
+	_, err := suite.cdbFile.WriteAt([]byte{0xff, 0xff, 0xff, 0xff}, int64(pos)+4)
+	suite.Require().Nil(err)
+
+	_, err = suite.cdbHandle.GetReader(suite.cdbFile)
+	suite.IsType(&CorruptionError{}, err)
+}
+
+func (suite *CDBTestSuite) TestFixedValueSize() {
+	suite.fillTestCDB()
+	info, err := suite.cdbFile.Stat()
+	suite.Require().Nil(err)
+	plainSize := info.Size()
+
+	suite.resetTestCDB()
+	suite.Require().Nil(suite.cdbHandle.SetFixedValueSize(4))
+	suite.TestShouldReturnAllValues()
+
+	info, err = suite.cdbFile.Stat()
+	suite.Require().Nil(err)
+	suite.Less(info.Size(), plainSize+v2HeaderSize)
+
+	suite.resetTestCDB()
+	suite.TestIterator()
+
+	writer := suite.getCDBWriter()
+	suite.Equal(ErrInvalidValueSize, writer.Put([]byte("key"), []byte("value")))
+	suite.Nil(writer.Close())
+}
+
+func (suite *CDBTestSuite) TestFixedEmptyValueSize() {
+	suite.Require().Nil(suite.cdbHandle.SetFixedValueSize(0))
+
+	for i := range suite.testRecords {
+		suite.testRecords[i].val = []byte{}
+	}
+
+	suite.TestShouldReturnAllValues()
+	suite.Equal(len(suite.testRecords), suite.countIteratedRecords(suite.getCDBReader()))
+}
+
+func (suite *CDBTestSuite) TestEmbeddedAtOffset() {
+	prefix := []byte("container header")
+	_, err := suite.cdbFile.Write(prefix)
+	suite.Require().Nil(err)
+
+	suite.Require().Nil(suite.cdbHandle.SetBloomFilter(10))
+	suite.fillTestCDB()
+
+	end, err := suite.cdbFile.Seek(0, io.SeekCurrent)
+	suite.Require().Nil(err)
+
+	// trailing bytes of the container
+	_, err = suite.cdbFile.Write([]byte("container trailer"))
+	suite.Require().Nil(err)
+
+	offset := int64(len(prefix))
+	reader, err := suite.cdbHandle.GetReaderAt(suite.cdbFile, offset, end-offset)
+	suite.Require().Nil(err)
+
+	for _, rec := range suite.testRecords {
+		value, err := reader.Get(rec.key)
+		suite.Nil(err)
+		suite.Equal(rec.val, value)
+	}
+
+	suite.Equal(len(suite.testRecords), suite.countIteratedRecords(reader))
+}
+
+func (suite *CDBTestSuite) fillVersionedTestCDB() {
+	writer := suite.getCDBWriter()
+
+	for i := 0; i < 4; i++ {
+		for _, rec := range suite.testRecords {
+			suite.Require().Nil(writer.Put(rec.key, []byte(string(rec.val)+strconv.Itoa(i))))
+		}
+	}
+
+	suite.Require().Nil(writer.Close())
+}
+
+func (suite *CDBTestSuite) TestVersions() {
+	suite.cdbHandle.SetVersions(-1)
+	suite.fillVersionedTestCDB()
+
+	reader := suite.getCDBReader()
+	suite.Equal(4*len(suite.testRecords), reader.Size())
+
+	for _, rec := range suite.testRecords {
+		value, err := reader.Get(rec.key)
+		suite.Nil(err)
+		suite.Equal(string(rec.val)+"3", string(value))
+
+		value, err = reader.GetVersion(rec.key, 2)
+		suite.Nil(err)
+		suite.Equal(string(rec.val)+"1", string(value))
+
+		_, err = reader.GetVersion(rec.key, 4)
+		suite.Equal(ErrEntryNotFound, err)
+
+		values, err := reader.Versions(rec.key)
+		suite.Nil(err)
+		suite.Len(values, 4)
+	}
+}
+
+func (suite *CDBTestSuite) TestKeepLastVersions() {
+	suite.cdbHandle.SetVersions(2)
+	suite.fillVersionedTestCDB()
+
+	reader := suite.getCDBReader()
+	suite.Equal(2*len(suite.testRecords), reader.Size())
+	suite.Equal(2*len(suite.testRecords), suite.countIteratedRecords(reader))
+
+	for _, rec := range suite.testRecords {
+		values, err := reader.Versions(rec.key)
+		suite.Nil(err)
+		suite.Equal([][]byte{[]byte(string(rec.val) + "3"), []byte(string(rec.val) + "2")}, values)
+	}
+}
+
+func (suite *CDBTestSuite) TestDuplicatesWithoutVersions() {
+	suite.fillVersionedTestCDB()
+
+	reader := suite.getCDBReader()
+
+	for _, rec := range suite.testRecords {
+		value, err := reader.Get(rec.key)
+		suite.Nil(err)
+		suite.Equal(string(rec.val)+"0", string(value))
+
+		values, err := reader.Versions(rec.key)
+		suite.Nil(err)
+		suite.Len(values, 4)
+		suite.Equal(string(rec.val)+"3", string(values[3]))
+	}
+}
+
+func (suite *CDBTestSuite) TestCountKey() {
+	suite.cdbHandle.SetVersions(2)
+	suite.fillVersionedTestCDB()
+
+	reader := suite.getCDBReader()
+
+	for _, rec := range suite.testRecords {
+		count, err := reader.CountKey(rec.key)
+		suite.Nil(err)
+		suite.Equal(2, count, "Superseded versions are not counted")
+
+		for i := 0; i < count; i++ {
+			value, err := reader.GetVersion(rec.key, i)
+			suite.Nil(err)
+			suite.Equal(string(rec.val)+strconv.Itoa(3-i), string(value))
+		}
+	}
+
+	count, err := reader.CountKey([]byte("missing key"))
+	suite.Nil(err)
+	suite.Equal(0, count)
+}
+
+func (suite *CDBTestSuite) TestBuckets() {
+	suite.cdbHandle.SetBuckets(true)
+
+	writer := suite.getCDBWriter()
+	users, hosts := writer.Bucket("users"), writer.Bucket("hosts")
+
+	for _, rec := range suite.testRecords {
+		suite.Require().Nil(writer.Put(rec.key, []byte("root")))
+		suite.Require().Nil(users.Put(rec.key, []byte("user")))
+	}
+
+	suite.Require().Nil(hosts.Put([]byte("localhost"), []byte("127.0.0.1")))
+	suite.Require().Nil(writer.Close())
+
+	reader := suite.getCDBReader()
+	suite.Equal(len(suite.testRecords), reader.Size())
+	suite.Equal(len(suite.testRecords), reader.Bucket("users").Size())
+	suite.Equal(1, reader.Bucket("hosts").Size())
+	suite.Equal(0, reader.Bucket("missing").Size())
+
+	for _, rec := range suite.testRecords {
+		value, err := reader.Get(rec.key)
+		suite.Nil(err)
+		suite.Equal("root", string(value))
+
+		value, err = reader.Bucket("users").Get(rec.key)
+		suite.Nil(err)
+		suite.Equal("user", string(value))
+
+		_, err = reader.Bucket("hosts").Get(rec.key)
+		suite.Equal(ErrEntryNotFound, err)
+	}
+
+	suite.Equal(len(suite.testRecords), suite.countIteratedRecords(reader))
+	suite.Equal(len(suite.testRecords), suite.countIteratedRecords(reader.Bucket("users")))
+
+	iterator, err := reader.Bucket("hosts").Iterator()
+	suite.Require().Nil(err)
+	suite.EqualKeyValue(iterator, testCDBRecord{[]byte("localhost"), []byte("127.0.0.1")})
+	suite.False(iterator.HasNext() && suite.mustNext(iterator))
+
+	_, err = reader.Bucket("missing").Iterator()
+	suite.Equal(ErrEmptyCDB, err)
+}
+
+func (suite *CDBTestSuite) TestBucketsDisabled() {
+	writer := suite.getCDBWriter()
+	suite.Equal(ErrBucketsDisabled, writer.Bucket("users").Put([]byte("key"), []byte("val")))
+	suite.Nil(writer.Bucket("").Put([]byte("key"), []byte("val")))
+	suite.Nil(writer.Close())
+}
+
+func (suite *CDBTestSuite) mustNext(iterator Iterator) bool {
+	ok, err := iterator.Next()
+	suite.Require().Nil(err)
+	return ok
+}
+
+func (suite *CDBTestSuite) TestGetReaderMmap() {
+	suite.fillTestCDB()
+
+	reader, err := suite.cdbHandle.GetReaderMmap(suite.cdbFile)
+	suite.Require().Nil(err)
+
+	for _, rec := range suite.testRecords {
+		value, err := reader.Get(rec.key)
+		suite.Nil(err)
+		suite.Equal(rec.val, value)
+		suite.Equal(len(value), cap(value), "Value must not expose the rest of the mapping")
+	}
+
+	suite.Equal(len(suite.testRecords), suite.countIteratedRecords(reader))
+
+	value, err := reader.Get(suite.testRecords[0].key)
+	suite.Require().Nil(err)
+	value = CopyValue(value)
+
+	suite.Nil(reader.Bucket("").Close(), "Bucket reader must not release the mapping")
+	_, err = reader.Get(suite.testRecords[0].key)
+	suite.Nil(err)
+
+	suite.Nil(reader.Close())
+	suite.Nil(reader.Close(), "Repeated Close must do nothing")
+	suite.Equal(suite.testRecords[0].val, value)
+}
+
+func (suite *CDBTestSuite) TestGetWriterMmap() {
+	value := bytes.Repeat([]byte{'v'}, 1000)
+
+	build := func(writer Writer, err error) []byte {
+		suite.Require().Nil(err)
+
+		for i := 0; i < 3000; i++ {
+			suite.Require().Nil(writer.Put([]byte(strconv.Itoa(i)), value))
+		}
+
+		suite.Require().Nil(writer.Close())
+
+		data, err := ioutil.ReadFile(suite.cdbFile.Name())
+		suite.Require().Nil(err)
+
+		return data
+	}
+
+	expected := build(suite.cdbHandle.GetWriter(suite.cdbFile))
+
+	for _, sync := range []bool{false, true} {
+		suite.resetTestCDB()
+		suite.cdbHandle.SetSync(sync)
+		suite.Equal(expected, build(suite.cdbHandle.GetWriterMmap(suite.cdbFile)), "The file is cut to the database size")
+
+		offset, err := suite.cdbFile.Seek(0, io.SeekCurrent)
+		suite.Require().Nil(err)
+		suite.Equal(int64(len(expected)), offset, "The file offset is after the database")
+
+		got, err := suite.getCDBReader().Get([]byte("2999"))
+		suite.Nil(err)
+		suite.Equal(value, got)
+	}
+}
+
+func (suite *CDBTestSuite) TestInMemoryReader() {
+	suite.fillTestCDB()
+
+	_, err := suite.cdbFile.Seek(0, io.SeekStart)
+	suite.Require().Nil(err)
+
+	reader, err := suite.cdbHandle.GetReaderInMemory(suite.cdbFile)
+	suite.Require().Nil(err)
+
+	for _, rec := range suite.testRecords {
+		value, err := reader.Get(rec.key)
+		suite.Nil(err)
+		suite.Equal(rec.val, value)
+	}
+
+	suite.Equal(len(suite.testRecords), suite.countIteratedRecords(reader))
+	suite.Nil(reader.Close())
+
+	_, err = suite.cdbHandle.NewReaderFromBytes([]byte{1, 2, 3})
+	suite.NotNil(err)
+}
+
+func (suite *CDBTestSuite) TestWarmup() {
+	suite.fillTestCDB()
+
+	mmapReader, err := suite.cdbHandle.GetReaderMmap(suite.cdbFile)
+	suite.Require().Nil(err)
+	defer mmapReader.Close()
+
+	for _, reader := range []Reader{suite.getCDBReader(), mmapReader} {
+		suite.Nil(reader.Warmup(false))
+		suite.Nil(reader.Warmup(true))
+
+		value, err := reader.Get(suite.testRecords[0].key)
+		suite.Nil(err)
+		suite.Equal(suite.testRecords[0].val, value)
+	}
+}
+
+func (suite *CDBTestSuite) TestCorruptedSlot() {
+	suite.fillTestCDB()
+
+	reader := suite.getCDBReader().(*readerImpl)
+	key := suite.testRecords[0].key
+	h, _ := reader.calcHash(key)
+	ref := reader.refs[h%uint32(len(reader.refs))]
+
+	slot := make([]byte, slotSize)
+	binary.LittleEndian.PutUint32(slot, h)
+	binary.LittleEndian.PutUint32(slot[4:], maxUint)
+
+	for i := uint32(0); i < ref.length; i++ {
+		_, err := suite.cdbFile.WriteAt(slot, int64(ref.position+i*slotSize))
+		suite.Require().Nil(err)
+	}
+
+	_, err := reader.Get(key)
+	suite.Require().IsType(&CorruptionError{}, err)
+	suite.True(err.(*CorruptionError).Is(ErrCorrupted))
+	suite.Equal(int(h%uint32(len(reader.refs))), err.(*CorruptionError).Table)
+}
+
+func (suite *CDBTestSuite) TestTruncatedTables() {
+	suite.fillTestCDB()
+
+	info, err := suite.cdbFile.Stat()
+	suite.Require().Nil(err)
+	suite.Require().Nil(suite.cdbFile.Truncate(info.Size() - slotSize))
+
+	_, err = suite.cdbHandle.GetReader(suite.cdbFile)
+	suite.Require().IsType(&CorruptionError{}, err)
+	suite.True(err.(*CorruptionError).Table >= 0)
+}
+
+func (suite *CDBTestSuite) TestCorruptedRecord() {
+	suite.fillTestCDB()
+
+	reader := suite.getCDBReader().(*readerImpl)
+	size := make([]byte, 4)
+	binary.LittleEndian.PutUint32(size, maxUint)
+
+	// the value size of the first record
+	_, err := suite.cdbFile.WriteAt(size, int64(reader.header.dataPosition()+4))
+	suite.Require().Nil(err)
+
+	_, err = reader.Get(suite.testRecords[0].key)
+	suite.Require().IsType(&CorruptionError{}, err)
+	suite.Equal(int64(reader.header.dataPosition()), err.(*CorruptionError).Offset)
+	suite.Equal(-1, err.(*CorruptionError).Table)
+
+	_, err = reader.Iterator()
+	suite.IsType(&CorruptionError{}, err)
+}
+
+func (suite *CDBTestSuite) TestGetReaderFromSeeker() {
+	suite.fillTestCDB()
+
+	reader, err := suite.cdbHandle.GetReaderFromSeeker(suite.cdbFile)
+	suite.Require().Nil(err)
+
+	var wg sync.WaitGroup
+
+	for _, rec := range suite.testRecords {
+		wg.Add(1)
+
+		go func(rec testCDBRecord) {
+			defer wg.Done()
+
+			value, err := reader.Get(rec.key)
+			suite.Nil(err)
+			suite.Equal(rec.val, value)
+		}(rec)
+	}
+
+	wg.Wait()
+
+	suite.Equal(len(suite.testRecords), suite.countIteratedRecords(reader))
+}
+
+// countingReaderAt counts reads of the underlying reader
+type countingReaderAt struct {
+	io.ReaderAt
+	reads int
+}
+
+func (c *countingReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	c.reads++
+	return c.ReaderAt.ReadAt(p, off)
+}
+
+func (suite *CDBTestSuite) TestGetReadsRecordAtOnce() {
+	writer := suite.getCDBWriter()
+	suite.Require().Nil(writer.Put([]byte("key"), []byte("value")))
+	suite.Require().Nil(writer.Close())
+
+	counter := &countingReaderAt{ReaderAt: suite.cdbFile}
+	reader, err := suite.cdbHandle.GetReader(counter)
+	suite.Require().Nil(err)
+
+	counter.reads = 0
+	value, err := reader.Get([]byte("key"))
+	suite.Nil(err)
+	suite.Equal([]byte("value"), value)
+	suite.Equal(2, counter.reads, "Get must read the slot and the whole record only")
+
+	counter.reads = 0
+	exists, err := reader.Has([]byte("key"))
+	suite.Nil(err)
+	suite.True(exists)
+	suite.Equal(2, counter.reads, "Has must read the slot and the record header with the key only")
+}
+
+// bytesCountingReaderAt counts bytes read from the underlying reader
+type bytesCountingReaderAt struct {
+	io.ReaderAt
+	bytes int
+}
+
+func (c *bytesCountingReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	c.bytes += len(p)
+	return c.ReaderAt.ReadAt(p, off)
+}
+
+func (suite *CDBTestSuite) TestHasDoesNotReadValue() {
+	value := make([]byte, 1<<20)
+
+	writer := suite.getCDBWriter()
+	suite.Require().Nil(writer.Put([]byte("key"), value))
+	suite.Require().Nil(writer.Close())
+
+	counter := &bytesCountingReaderAt{ReaderAt: suite.cdbFile}
+	reader, err := suite.cdbHandle.GetReader(counter)
+	suite.Require().Nil(err)
+
+	counter.bytes = 0
+	exists, err := reader.Has([]byte("key"))
+	suite.Nil(err)
+	suite.True(exists)
+	suite.True(counter.bytes < 1024, "Has must not read the value, read %d bytes", counter.bytes)
+
+	counter.bytes = 0
+	size, err := reader.GetSize([]byte("key"))
+	suite.Nil(err)
+	suite.Equal(len(value), size)
+	suite.True(counter.bytes < 1024, "GetSize must not read the value, read %d bytes", counter.bytes)
+}
+
+// collidingHash implements hash.Hash32, all keys have the same hash
+type collidingHash struct{ hashImpl }
+
+func (h *collidingHash) Sum32() uint32 {
+	return 42
+}
+
+func (suite *CDBTestSuite) TestGetComparesKeyBeforeValue() {
+	suite.cdbHandle.SetHash(func() hash.Hash32 { return &collidingHash{} })
+
+	value := make([]byte, 1<<20)
+
+	writer := suite.getCDBWriter()
+	suite.Require().Nil(writer.Put([]byte("a"), value))
+	suite.Require().Nil(writer.Put([]byte("b"), value))
+	suite.Require().Nil(writer.Close())
+
+	counter := &bytesCountingReaderAt{ReaderAt: suite.cdbFile}
+	reader, err := suite.cdbHandle.GetReader(counter)
+	suite.Require().Nil(err)
+
+	counter.bytes = 0
+	got, err := reader.Get([]byte("b"))
+	suite.Nil(err)
+	suite.Equal(len(value), len(got))
+	suite.True(counter.bytes < len(value)+4096, "Get must not read the value of a colliding key, read %d bytes", counter.bytes)
+}
+
+func (suite *CDBTestSuite) TestGetSize() {
+	suite.fillTestCDB()
+
+	reader := suite.getCDBReader()
+
+	for _, rec := range suite.testRecords {
+		size, err := reader.GetSize(rec.key)
+		suite.Nil(err)
+		suite.Equal(len(rec.val), size)
+	}
+
+	_, err := reader.GetSize([]byte("missing key"))
+	suite.Equal(ErrEntryNotFound, err)
+}
+
+func (suite *CDBTestSuite) TestGetRange() {
+	suite.fillTestCDB()
+
+	reader := suite.getCDBReader()
+	rec := suite.testRecords[3]
+
+	value, err := reader.GetRange(rec.key, 1, 2)
+	suite.Nil(err)
+	suite.Equal(rec.val[1:3], value)
+
+	value, err = reader.GetRange(rec.key, 2, 100)
+	suite.Nil(err)
+	suite.Equal(rec.val[2:], value)
+
+	value, err = reader.GetRange(rec.key, len(rec.val), 1)
+	suite.Nil(err)
+	suite.Empty(value)
+
+	_, err = reader.GetRange(rec.key, len(rec.val)+1, 1)
+	suite.Equal(ErrInvalidRange, err)
+
+	_, err = reader.GetRange(rec.key, -1, 1)
+	suite.Equal(ErrInvalidRange, err)
+
+	_, err = reader.GetRange([]byte("missing key"), 0, 1)
+	suite.Equal(ErrEntryNotFound, err)
+}
+
+func (suite *CDBTestSuite) TestGetStream() {
+	suite.fillTestCDB()
+
+	reader := suite.getCDBReader()
+
+	for _, rec := range suite.testRecords {
+		stream, err := reader.GetStream(rec.key)
+		suite.Require().Nil(err)
+
+		value, err := ioutil.ReadAll(stream)
+		suite.Nil(err)
+		suite.Equal(rec.val, value)
+		suite.Nil(stream.Close())
+	}
+
+	_, err := reader.GetStream([]byte("missing key"))
+	suite.Equal(ErrEntryNotFound, err)
+}
+
+func (suite *CDBTestSuite) TestGetInto() {
+	suite.fillTestCDB()
+
+	reader, err := suite.cdbHandle.GetReader(suite.cdbFile)
+	suite.Require().Nil(err)
+
+	buf := make([]byte, 0, 4)
+
+	for _, rec := range suite.testRecords {
+		var n int
+
+		buf, n, err = reader.GetInto(rec.key, buf)
+		suite.Nil(err)
+		suite.Equal(len(rec.val), n)
+		suite.Equal(rec.val, buf[:n])
+	}
+
+	_, n, err := reader.GetInto([]byte("missing key"), buf)
+	suite.Equal(ErrEntryNotFound, err)
+	suite.Equal(0, n)
+
+	buf = make([]byte, 64)
+	allocs := testing.AllocsPerRun(100, func() {
+		reader.GetInto(suite.testRecords[0].key, buf)
+	})
+
+	if !raceEnabled {
+		suite.Equal(float64(0), allocs)
+	}
+}
+
+func (suite *CDBTestSuite) TestStringKeys() {
+	writer, err := suite.cdbHandle.GetWriter(suite.cdbFile)
+	suite.Require().Nil(err)
+
+	for _, rec := range suite.testRecords {
+		suite.Require().Nil(writer.PutString(string(rec.key), rec.val))
+	}
+
+	suite.Require().Nil(writer.Close())
+
+	reader, err := suite.cdbHandle.GetReaderMmap(suite.cdbFile)
+	suite.Require().Nil(err)
+	defer reader.Close()
+
+	for _, rec := range suite.testRecords {
+		value, err := reader.GetString(string(rec.key))
+		suite.Nil(err)
+		suite.Equal(rec.val, value)
+
+		exists, err := reader.HasString(string(rec.key))
+		suite.Nil(err)
+		suite.True(exists)
+	}
+
+	key := string(suite.testRecords[0].key)
+	allocs := testing.AllocsPerRun(100, func() {
+		reader.GetString(key)
+		reader.HasString(key)
+	})
+
+	if !raceEnabled {
+		suite.Equal(float64(0), allocs)
+	}
+}
+
+func (suite *CDBTestSuite) TestGetDoesNotAllocate() {
+	suite.fillTestCDB()
+
+	reader, err := suite.cdbHandle.GetReaderMmap(suite.cdbFile)
+	suite.Require().Nil(err)
+	defer reader.Close()
+
+	key := suite.testRecords[len(suite.testRecords)/2].key
+	missing := []byte("missing key")
+
+	allocs := testing.AllocsPerRun(100, func() {
+		reader.Get(key)
+		reader.Has(key)
+		reader.Has(missing)
+	})
+
+	if !raceEnabled {
+		suite.Equal(float64(0), allocs)
+	}
+}
+
+func (suite *CDBTestSuite) countIteratedRecords(reader Reader) int {
+	iterator, err := reader.Iterator()
+	suite.Require().Nil(err)
+
+	n := 1
+	for {
+		ok, err := iterator.Next()
+		suite.Require().Nil(err)
+
+		if !ok {
+			return n
+		}
+
+		n++
+	}
+}
+
 func BenchmarkGetReader(b *testing.B) {
 
 	n := 1000
@@ -217,3 +1196,53 @@ func BenchmarkWriterPut(b *testing.B) {
 
 	writer.Close()
 }
+
+func (suite *CDBTestSuite) TestClone() {
+	suite.fillTestCDB()
+	reader := suite.getCDBReader()
+
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		clone := reader.Clone()
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for _, rec := range suite.testRecords {
+				value, err := clone.Get(rec.key)
+				suite.Nil(err)
+				suite.Equal(rec.val, value)
+			}
+		}()
+	}
+	wg.Wait()
+
+	clone := reader.Clone()
+	suite.Nil(clone.Close())
+
+	exists, err := reader.Has(suite.testRecords[0].key)
+	suite.Nil(err)
+	suite.True(exists)
+
+	if !raceEnabled {
+		allocs := testing.AllocsPerRun(100, func() {
+			clone.Has(suite.testRecords[0].key)
+		})
+		suite.Equal(0.0, allocs)
+	}
+}
+
+func (suite *CDBTestSuite) TestErrTooBig() {
+	writer := suite.getCDBWriter()
+	impl := writer.(*writerImpl)
+	impl.current = maxUint - 20
+
+	err := writer.Put([]byte("key"), make([]byte, 20))
+	suite.Equal(ErrTooBig, err)
+	suite.True(err == ErrOutOfMemory, "ErrTooBig must be the former error")
+	suite.Zero(impl.buffer.Buffered(), "Nothing must be written")
+
+	suite.Nil(writer.Put([]byte("key"), nil))
+	suite.Equal(ErrTooBig, writer.Close(), "Hash tables don't fit")
+}
@@ -0,0 +1,155 @@
+package cdb
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+)
+
+// Number of slots of a hash table, which Check validates
+const checkSlotsPerTable = 64
+
+// Check re-reads the header and the table refs and validates a sample of slots of every hash table:
+// a slot must point at a record inside the data section, which key has the hash of the slot.
+// Returns a *CorruptionError on a mismatch, the error of the context if it is done.
+func (r *readerImpl) Check(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	bounded := r.withContext(ctx)
+
+	if err := bounded.checkHeader(); err != nil {
+		return err
+	}
+
+	names := map[uint32][]byte{0: nil}
+	for name, info := range r.buckets {
+		names[info.id] = []byte(name)
+	}
+
+	for table, ref := range r.refs {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if err := bounded.checkTable(table, ref, names); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkHeader tells if the header and the table refs of the database are the ones read on open
+func (r *readerImpl) checkHeader() error {
+	h, err := readHeader(r.reader)
+	if err != nil {
+		return corrupted(err, 0, -1, "header can't be read")
+	}
+
+	if h != r.header {
+		return corrupted(nil, 0, -1, "header is changed")
+	}
+
+	buf := make([]byte, len(r.refs)*8)
+	if _, err := r.reader.ReadAt(buf, int64(h.refsPosition())); err != nil {
+		return corrupted(err, int64(h.refsPosition()), -1, "table refs can't be read")
+	}
+
+	for i, ref := range r.refs {
+		if binary.LittleEndian.Uint32(buf[i*8:]) != ref.position || binary.LittleEndian.Uint32(buf[i*8+4:]) != ref.length {
+			return corrupted(nil, int64(h.refsPosition())+int64(i)*8, i, "table ref is changed")
+		}
+	}
+
+	return nil
+}
+
+// checkTable validates a sample of slots of the given hash table, names maps bucket ids to names
+func (r *readerImpl) checkTable(table int, ref hashTableRef, names map[uint32][]byte) error {
+	step := ref.length / checkSlotsPerTable
+	if step == 0 {
+		step = 1
+	}
+
+	size := r.header.slotSize()
+
+	for k := uint32(0); k < ref.length; k += step {
+		var entry slot
+		pos := ref.position + k*size
+
+		if err := r.readSlot(pos, &entry); err != nil {
+			return corrupted(err, int64(pos), table, "slot is out of the database")
+		}
+
+		if entry.position == 0 {
+			continue
+		}
+
+		if entry.position < r.header.dataPosition() || entry.position >= r.endPos {
+			return corrupted(nil, int64(pos), table, fmt.Sprintf("slot points at %d out of the data section", entry.position))
+		}
+
+		layout, err := r.readRecord(entry.position)
+		if err != nil {
+			return err
+		}
+
+		key, err := r.readKey(layout)
+		if err != nil {
+			return corrupted(err, int64(layout.keyPosition), table, "key is out of the database")
+		}
+
+		hashFunc := r.hasher()
+		hashFunc.Write(names[layout.bucket])
+		hashFunc.Write(key)
+
+		if h, hi := r.header.keyHash(hashFunc); h != entry.hash || hi != entry.hi || h%uint32(len(r.refs)) != uint32(table) {
+			return corrupted(nil, int64(pos), table, fmt.Sprintf("slot hash doesn't match the key %q", truncateKey(key)))
+		}
+	}
+
+	return nil
+}
+
+// truncateKey returns the beginning of the given key for error messages
+func truncateKey(key []byte) []byte {
+	const maxKeyInError = 64
+
+	if len(key) > maxKeyInError {
+		return key[:maxKeyInError]
+	}
+
+	return key
+}
+
+// Check checks all parts, see Reader.Check
+func (r *shardedReader) Check(ctx context.Context) error {
+	for _, part := range r.parts {
+		if err := part.Check(ctx); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Check checks all layers, see Reader.Check
+func (r *stackedReader) Check(ctx context.Context) error {
+	for _, layer := range r.layers {
+		if err := layer.Check(ctx); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Check checks the current version, see Reader.Check
+func (r *ReloadingReader) Check(ctx context.Context) error {
+	g := r.acquire()
+	defer g.release()
+
+	return g.reader.Check(ctx)
+}
@@ -0,0 +1,52 @@
+package cdb
+
+import (
+	"context"
+	"encoding/binary"
+)
+
+func (suite *CDBTestSuite) TestCheck() {
+	suite.fillTestCDB()
+	reader := suite.getCDBReader().(*readerImpl)
+
+	suite.Nil(reader.Check(context.Background()))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	suite.Equal(context.Canceled, reader.Check(ctx))
+
+	// A slot of another key
+	h, _ := reader.calcHash(suite.testRecords[0].key)
+	table := h % uint32(len(reader.refs))
+	ref := reader.refs[table]
+
+	for k := uint32(0); k < ref.length; k++ {
+		pos := int64(ref.position + k*slotSize)
+		slot := make([]byte, slotSize)
+
+		_, err := suite.cdbFile.ReadAt(slot, pos)
+		suite.Require().Nil(err)
+
+		if binary.LittleEndian.Uint32(slot[4:]) == 0 {
+			continue
+		}
+
+		binary.LittleEndian.PutUint32(slot, binary.LittleEndian.Uint32(slot)+uint32(len(reader.refs)))
+		_, err = suite.cdbFile.WriteAt(slot, pos)
+		suite.Require().Nil(err)
+
+		break
+	}
+
+	err := reader.Check(context.Background())
+
+	corruption, ok := err.(*CorruptionError)
+	suite.Require().True(ok, "unexpected error %v", err)
+	suite.Equal(int(table), corruption.Table)
+	suite.True(corruption.Is(ErrCorrupted))
+
+	// Table refs
+	_, err = suite.cdbFile.WriteAt([]byte{1, 2, 3, 4}, 0)
+	suite.Require().Nil(err)
+	suite.IsType(&CorruptionError{}, reader.Check(context.Background()))
+}
@@ -0,0 +1,94 @@
+package cdb
+
+import (
+	"context"
+	"io"
+)
+
+// contextReaderAt implements io.ReaderAt, fails reads once the context is done
+type contextReaderAt struct {
+	ctx    context.Context
+	reader io.ReaderAt
+}
+
+// ReadAt reads from the underlying reader unless the context is done
+func (r *contextReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	return readAtContext(r.ctx, r.reader, p, off)
+}
+
+// unwrap returns the underlying reader
+func (r *contextReaderAt) unwrap() io.ReaderAt {
+	return r.reader
+}
+
+// readerAtContext is implemented by wrappers of io.ReaderAt, which reads observe a context
+type readerAtContext interface {
+	readAtContext(ctx context.Context, p []byte, off int64) (int, error)
+}
+
+// readAtContext reads len(p) bytes at the given offset, passes the context to the reader if it observes one
+func readAtContext(ctx context.Context, reader io.ReaderAt, p []byte, off int64) (int, error) {
+	if reader, ok := reader.(readerAtContext); ok {
+		return reader.readAtContext(ctx, p, off)
+	}
+
+	return reader.ReadAt(p, off)
+}
+
+// withContext returns a copy of the reader, which reads are bounded by the given context.
+// A read already started by the underlying reader is not interrupted.
+func (r *readerImpl) withContext(ctx context.Context) *readerImpl {
+	bounded := *r
+	bounded.reader = &contextReaderAt{ctx, r.reader}
+	bounded.ctx = ctx
+
+	return &bounded
+}
+
+// GetContext returns the first value associated with the given key, see Reader.GetContext
+func (r *readerImpl) GetContext(ctx context.Context, key []byte) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return r.withContext(ctx).Get(key)
+}
+
+// HasContext returns true if the given key exists, see Reader.HasContext
+func (r *readerImpl) HasContext(ctx context.Context, key []byte) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	return r.withContext(ctx).Has(key)
+}
+
+// IteratorContext returns new Iterator object that points on first record, see Reader.IteratorContext
+func (r *readerImpl) IteratorContext(ctx context.Context) (Iterator, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return r.withContext(ctx).Iterator()
+}
+
+// GetContext returns the first value associated with the given key
+func (r *shardedReader) GetContext(ctx context.Context, key []byte) ([]byte, error) {
+	return r.part(key).GetContext(ctx, key)
+}
+
+// HasContext returns true if the given key exists
+func (r *shardedReader) HasContext(ctx context.Context, key []byte) (bool, error) {
+	return r.part(key).HasContext(ctx, key)
+}
+
+// IteratorContext returns a new Iterator object, which walks all parts one by one
+func (r *shardedReader) IteratorContext(ctx context.Context) (Iterator, error) {
+	return r.concat(func(part Reader) (Iterator, error) {
+		return part.IteratorContext(ctx)
+	})
+}
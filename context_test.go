@@ -0,0 +1,42 @@
+package cdb
+
+import "context"
+
+func (suite *CDBTestSuite) TestContextLookups() {
+	suite.fillTestCDB()
+
+	reader := suite.getCDBReader()
+	rec := suite.testRecords[0]
+
+	value, err := reader.GetContext(context.Background(), rec.key)
+	suite.Nil(err)
+	suite.Equal(rec.val, value)
+
+	exists, err := reader.HasContext(context.Background(), rec.key)
+	suite.Nil(err)
+	suite.True(exists)
+
+	iterator, err := reader.IteratorContext(context.Background())
+	suite.Require().Nil(err)
+	suite.EqualKeyValue(iterator, rec)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err = reader.GetContext(ctx, rec.key)
+	suite.Equal(context.Canceled, err)
+
+	_, err = reader.HasContext(ctx, rec.key)
+	suite.Equal(context.Canceled, err)
+
+	_, err = reader.IteratorContext(ctx)
+	suite.Equal(context.Canceled, err)
+
+	ctx, cancel = context.WithCancel(context.Background())
+	iterator, err = reader.IteratorContext(ctx)
+	suite.Require().Nil(err)
+	cancel()
+
+	_, err = iterator.Next()
+	suite.Equal(context.Canceled, err)
+}
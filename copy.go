@@ -0,0 +1,59 @@
+package cdb
+
+// CopyOption configures Copy
+type CopyOption func(*copyOptions)
+
+// copyOptions are the steps of Copy, which are applied to every record in order
+type copyOptions struct {
+	steps []copyStep
+}
+
+// copyStep returns the record to be put instead of the given one, keep is false if the record is skipped
+type copyStep func(key, value []byte) (newKey, newValue []byte, keep bool, err error)
+
+// CopyFilter makes Copy skip records, which are not accepted by keep
+func CopyFilter(keep func(key, value []byte) bool) CopyOption {
+	return func(o *copyOptions) {
+		o.steps = append(o.steps, func(key, value []byte) ([]byte, []byte, bool, error) {
+			return key, value, keep(key, value), nil
+		})
+	}
+}
+
+// CopyTransform makes Copy put the key and the value returned by transform instead of the ones
+// of a record, e.g. a recompressed value. An error of transform stops Copy, see ForEach.
+func CopyTransform(transform func(key, value []byte) ([]byte, []byte, error)) CopyOption {
+	return func(o *copyOptions) {
+		o.steps = append(o.steps, func(key, value []byte) ([]byte, []byte, bool, error) {
+			key, value, err := transform(key, value)
+			return key, value, err == nil, err
+		})
+	}
+}
+
+// Copy puts all records of src into dst in the file order. Filters and transforms given by options
+// are applied to every record in the order of options. Record flags and expiration times aren't
+// copied, see CDB.Vacuum for a copy of a database file, which keeps them. dst isn't closed,
+// so records of several databases can be copied into it.
+func Copy(dst Writer, src Reader, opts ...CopyOption) error {
+	var o copyOptions
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return ForEach(src, func(key, value []byte) error {
+		for _, step := range o.steps {
+			var (
+				keep bool
+				err  error
+			)
+
+			if key, value, keep, err = step(key, value); err != nil || !keep {
+				return err
+			}
+		}
+
+		return dst.Put(key, value)
+	})
+}
@@ -0,0 +1,73 @@
+package cdb
+
+import (
+	"bytes"
+	"errors"
+)
+
+func (suite *CDBTestSuite) TestCopy() {
+	suite.fillTestCDB()
+	reader := suite.getCDBReader()
+
+	files := suite.createShardFiles(1)
+	defer suite.removeShardFiles(files)
+
+	writer, err := suite.cdbHandle.GetWriter(files[0])
+	suite.Require().Nil(err)
+
+	err = Copy(writer, reader,
+		CopyFilter(func(key, _ []byte) bool {
+			return !bytes.Equal(key, suite.testRecords[1].key)
+		}),
+		CopyTransform(func(key, value []byte) ([]byte, []byte, error) {
+			return append([]byte("copy_"), key...), value, nil
+		}),
+	)
+	suite.Require().Nil(err)
+	suite.Require().Nil(writer.Close())
+
+	copied, err := suite.cdbHandle.GetReader(files[0])
+	suite.Require().Nil(err)
+	suite.Equal(len(suite.testRecords)-1, copied.Size())
+
+	for i, rec := range suite.testRecords {
+		value, err := copied.Get(append([]byte("copy_"), rec.key...))
+
+		if i == 1 {
+			suite.Equal(ErrEntryNotFound, err)
+			continue
+		}
+
+		suite.Nil(err)
+		suite.Equal(rec.val, value)
+	}
+}
+
+func (suite *CDBTestSuite) TestCopyTransformError() {
+	suite.fillTestCDB()
+	reader := suite.getCDBReader()
+
+	files := suite.createShardFiles(1)
+	defer suite.removeShardFiles(files)
+
+	writer, err := suite.cdbHandle.GetWriter(files[0])
+	suite.Require().Nil(err)
+
+	failure := errors.New("failure")
+	puts := 0
+
+	err = Copy(writer, reader, CopyTransform(func(key, value []byte) ([]byte, []byte, error) {
+		if puts == 2 {
+			return nil, nil, failure
+		}
+
+		puts++
+		return key, value, nil
+	}))
+	suite.Equal(failure, err)
+	suite.Require().Nil(writer.Close())
+
+	copied, err := suite.cdbHandle.GetReader(files[0])
+	suite.Require().Nil(err)
+	suite.Equal(2, copied.Size())
+}
@@ -0,0 +1,326 @@
+package cdb
+
+import (
+	"encoding/binary"
+	"errors"
+	"hash/fnv"
+)
+
+// ErrNoCursor tells that the iterator can't export a cursor, e.g. it merges several databases
+var ErrNoCursor = errors.New("cdb iterator can't export a cursor")
+
+// ErrInvalidCursor tells that the cursor is malformed or belongs to another database
+var ErrInvalidCursor = errors.New("cdb cursor is malformed or belongs to another database")
+
+// Cursor is an opaque position of an iterator, which can be persisted and later passed to
+// Reader.ResumeIterator of a reader of the same database, see Iterator.Cursor.
+type Cursor []byte
+
+// A cursor is stored as:
+//
+//	+------+-------+-------+----------+-----+
+//	| kind | flags | token | position | end |
+//	+------+-------+-------+----------+-----+
+//	  u8     u8      u64      u32       u32
+//
+// for iterators walking the file order, position is the position of the current record,
+// end is the position after the last record of the iterator, see Reader.Segments;
+//
+//	+------+-------+-------+---------+-------+-----+
+//	| kind | flags | token | current | start | end |
+//	+------+-------+-------+---------+-------+-----+
+//	  u8     u8      u64      u32       u32    u32
+//
+// for iterators walking the sorted index, the numbers are the ones of indexIterator;
+//
+//	+------+------+--------------+
+//	| kind | part | inner cursor |
+//	+------+------+--------------+
+//	  u8     u32
+//
+// for iterators walking parts of a sharded database or layers of a stacked one.
+//
+// token is a hash of the table refs and the size of the data section, so a cursor of another
+// database or of another version of the database is rejected.
+const (
+	cursorFile byte = 1 + iota
+	cursorIndex
+	cursorPart
+)
+
+const (
+	// cursorKeysOnly tells that the iterator is a key iterator, see Reader.KeyIterator
+	cursorKeysOnly byte = 1 << iota
+	// cursorReverse tells that the iterator walks the sorted index backwards
+	cursorReverse
+)
+
+// cursorState is a decoded cursor
+type cursorState struct {
+	kind, flags byte
+	token       uint64
+	// position is the record position of a file cursor, end is the end of its segment
+	position, end uint32
+	// current, start and limit are the record numbers of an index cursor
+	current, start, limit uint32
+	// part is the number of the part of a part cursor, inner is the cursor inside that part
+	part  uint32
+	inner Cursor
+}
+
+// encode returns the cursor of the state
+func (s *cursorState) encode() Cursor {
+	switch s.kind {
+	case cursorFile:
+		buf := make([]byte, 18)
+		buf[0], buf[1] = s.kind, s.flags
+		binary.LittleEndian.PutUint64(buf[2:], s.token)
+		binary.LittleEndian.PutUint32(buf[10:], s.position)
+		binary.LittleEndian.PutUint32(buf[14:], s.end)
+
+		return buf
+	case cursorIndex:
+		buf := make([]byte, 22)
+		buf[0], buf[1] = s.kind, s.flags
+		binary.LittleEndian.PutUint64(buf[2:], s.token)
+		binary.LittleEndian.PutUint32(buf[10:], s.current)
+		binary.LittleEndian.PutUint32(buf[14:], s.start)
+		binary.LittleEndian.PutUint32(buf[18:], s.limit)
+
+		return buf
+	default:
+		buf := make([]byte, 5, 5+len(s.inner))
+		buf[0] = s.kind
+		binary.LittleEndian.PutUint32(buf[1:], s.part)
+
+		return append(buf, s.inner...)
+	}
+}
+
+// decodeCursor returns the state of the given cursor
+func decodeCursor(cursor Cursor) (cursorState, error) {
+	if len(cursor) == 0 {
+		return cursorState{}, ErrInvalidCursor
+	}
+
+	s := cursorState{kind: cursor[0]}
+
+	switch {
+	case s.kind == cursorFile && len(cursor) == 18:
+		s.flags = cursor[1]
+		s.token = binary.LittleEndian.Uint64(cursor[2:])
+		s.position = binary.LittleEndian.Uint32(cursor[10:])
+		s.end = binary.LittleEndian.Uint32(cursor[14:])
+	case s.kind == cursorIndex && len(cursor) == 22:
+		s.flags = cursor[1]
+		s.token = binary.LittleEndian.Uint64(cursor[2:])
+		s.current = binary.LittleEndian.Uint32(cursor[10:])
+		s.start = binary.LittleEndian.Uint32(cursor[14:])
+		s.limit = binary.LittleEndian.Uint32(cursor[18:])
+	case s.kind == cursorPart && len(cursor) > 5:
+		s.part = binary.LittleEndian.Uint32(cursor[1:])
+		s.inner = cursor[5:]
+	default:
+		return cursorState{}, ErrInvalidCursor
+	}
+
+	return s, nil
+}
+
+// cursorToken returns the token, which binds cursors to the database and the bucket of the reader
+func (r *readerImpl) cursorToken() uint64 {
+	h := fnv.New64a()
+	buf := make([]byte, 8)
+
+	binary.LittleEndian.PutUint32(buf, r.endPos)
+	binary.LittleEndian.PutUint32(buf[4:], r.bucket)
+	h.Write(buf)
+
+	for _, ref := range r.refs {
+		binary.LittleEndian.PutUint32(buf, ref.position)
+		binary.LittleEndian.PutUint32(buf[4:], ref.length)
+		h.Write(buf)
+	}
+
+	return h.Sum64()
+}
+
+// Cursor returns the cursor of the current record, see Iterator.Cursor
+func (i *iterator) Cursor() (Cursor, error) {
+	s := cursorState{kind: cursorFile, token: i.cdbReader.cursorToken(), position: i.current, end: i.end}
+
+	if i.keysOnly {
+		s.flags |= cursorKeysOnly
+	}
+
+	return s.encode(), nil
+}
+
+// Cursor returns the cursor of the current record in the order of the sorted index, see Iterator.Cursor
+func (i *indexIterator) Cursor() (Cursor, error) {
+	s := cursorState{
+		kind:    cursorIndex,
+		token:   i.cdbReader.cursorToken(),
+		current: uint32(i.next - 1),
+		start:   uint32(i.start),
+		limit:   uint32(i.end),
+	}
+
+	if i.reverse {
+		s.flags |= cursorReverse
+		s.current = uint32(i.next + 1)
+	}
+
+	return s.encode(), nil
+}
+
+// Cursor returns the cursor of the current record inside its part, see Iterator.Cursor
+func (i *concatIterator) Cursor() (Cursor, error) {
+	inner, err := i.iterators[0].Cursor()
+	if err != nil {
+		return nil, err
+	}
+
+	s := cursorState{kind: cursorPart, part: uint32(i.parts[0]), inner: inner}
+
+	return s.encode(), nil
+}
+
+// Cursor returns ErrNoCursor, see Iterator.Cursor
+func (i *mergeIterator) Cursor() (Cursor, error) {
+	return nil, ErrNoCursor
+}
+
+// Cursor returns ErrNoCursor, see Iterator.Cursor
+func (i *filterIterator) Cursor() (Cursor, error) {
+	return nil, ErrNoCursor
+}
+
+// ResumeIterator returns a new Iterator object, which points on the record of the given cursor,
+// see Reader.ResumeIterator
+func (r *readerImpl) ResumeIterator(cursor Cursor) (Iterator, error) {
+	s, err := decodeCursor(cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	if (s.kind != cursorFile && s.kind != cursorIndex) || s.token != r.cursorToken() {
+		return nil, ErrInvalidCursor
+	}
+
+	// The iterator is marked to be reset to the record of the cursor
+	var iterator interface {
+		Iterator
+		mark()
+	}
+
+	if s.kind == cursorFile {
+		if s.position < r.header.dataPosition() || s.position >= s.end || s.end > r.endPos {
+			return nil, ErrInvalidCursor
+		}
+
+		base, err := r.newIterator(s.position, nil, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		base.end = s.end
+
+		base.keysOnly = s.flags&cursorKeysOnly != 0
+		iterator = base
+	} else {
+		if r.index == nil {
+			return nil, ErrNoIndex
+		}
+
+		if s.start > s.current || s.current >= s.limit || int(s.limit) > r.index.count {
+			return nil, ErrInvalidCursor
+		}
+
+		base, err := r.newIterator(0, nil, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		iterator = &indexIterator{
+			iterator: base,
+			next:     int(s.current),
+			start:    int(s.start),
+			end:      int(s.limit),
+			reverse:  s.flags&cursorReverse != 0,
+		}
+	}
+
+	ok, err := iterator.Next()
+	if err != nil {
+		return nil, err
+	}
+
+	// The rest of records is expired
+	if !ok {
+		return nil, ErrEmptyCDB
+	}
+
+	iterator.mark()
+
+	return iterator, nil
+}
+
+// resumeParts returns a new Iterator object, which walks the part of the given part cursor
+// from the record of the cursor and then the following parts one by one. wrap is applied
+// to the iterator of every part, it may return nil to skip the part.
+func resumeParts(parts []Reader, cursor Cursor, wrap func(part int, iterator Iterator) (Iterator, error)) (Iterator, error) {
+	s, err := decodeCursor(cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.kind != cursorPart || int(s.part) >= len(parts) {
+		return nil, ErrInvalidCursor
+	}
+
+	inner, err := decodeCursor(s.inner)
+	if err != nil {
+		return nil, err
+	}
+
+	open := Reader.Iterator
+	if inner.flags&cursorKeysOnly != 0 {
+		open = Reader.KeyIterator
+	}
+
+	concat := &concatIterator{}
+
+	for j := int(s.part); j < len(parts); j++ {
+		var iterator Iterator
+
+		if j == int(s.part) {
+			iterator, err = parts[j].ResumeIterator(s.inner)
+		} else {
+			iterator, err = open(parts[j])
+		}
+
+		if err == ErrEmptyCDB {
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		if iterator, err = wrap(j, iterator); err != nil {
+			return nil, err
+		}
+
+		if iterator != nil {
+			concat.iterators = append(concat.iterators, iterator)
+			concat.parts = append(concat.parts, j)
+		}
+	}
+
+	if len(concat.iterators) == 0 {
+		return nil, ErrEmptyCDB
+	}
+
+	return concat, nil
+}
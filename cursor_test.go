@@ -0,0 +1,141 @@
+package cdb
+
+import (
+	"bytes"
+)
+
+// restOf returns the keys of the records of the iterator starting from the current one
+func (suite *CDBTestSuite) restOf(iterator Iterator) []string {
+	var keys []string
+
+	for ok := true; ok; ok = suite.mustNext(iterator) {
+		key, err := iterator.Key()
+		suite.Require().Nil(err)
+		keys = append(keys, string(key))
+	}
+
+	return keys
+}
+
+func (suite *CDBTestSuite) TestResumeIterator() {
+	suite.fillTestCDB()
+	reader := suite.getCDBReader()
+
+	for _, open := range []func() (Iterator, error){reader.Iterator, reader.KeyIterator} {
+		iterator, err := open()
+		suite.Require().Nil(err)
+
+		for i := 0; i < len(suite.testRecords)/2; i++ {
+			suite.mustNext(iterator)
+		}
+
+		cursor, err := iterator.Cursor()
+		suite.Require().Nil(err)
+
+		// The cursor survives a new reader of the same database
+		resumed, err := suite.getCDBReader().ResumeIterator(cursor)
+		suite.Require().Nil(err)
+		suite.Equal(suite.restOf(iterator), suite.restOf(resumed))
+	}
+
+	_, err := reader.ResumeIterator(Cursor("garbage"))
+	suite.Equal(ErrInvalidCursor, err)
+
+	iterator := suite.mustGetCDBIterator()
+	cursor, err := iterator.Cursor()
+	suite.Require().Nil(err)
+
+	files := suite.createShardFiles(1)
+	defer suite.removeShardFiles(files)
+
+	writer, err := suite.cdbHandle.GetWriter(files[0])
+	suite.Require().Nil(err)
+	for _, rec := range suite.testRecords[1:] {
+		suite.Require().Nil(writer.Put(rec.key, rec.val))
+	}
+	suite.Require().Nil(writer.Close())
+
+	another, err := suite.cdbHandle.GetReader(files[0])
+	suite.Require().Nil(err)
+
+	_, err = another.ResumeIterator(cursor)
+	suite.Equal(ErrInvalidCursor, err)
+}
+
+func (suite *CDBTestSuite) TestResumeReverseIterator() {
+	keys := []string{"d", "b", "a", "c", "b", "e"}
+
+	index := &bytes.Buffer{}
+	writer, err := suite.cdbHandle.GetWriterWithIndex(suite.cdbFile, index)
+	suite.Require().Nil(err)
+
+	for _, key := range keys {
+		suite.Require().Nil(writer.Put([]byte(key), nil))
+	}
+
+	suite.Require().Nil(writer.Close())
+
+	reader, err := suite.cdbHandle.GetReaderWithIndex(suite.cdbFile, bytes.NewReader(index.Bytes()))
+	suite.Require().Nil(err)
+
+	iterator, err := reader.ReverseIterator()
+	suite.Require().Nil(err)
+	suite.mustNext(iterator)
+
+	cursor, err := iterator.Cursor()
+	suite.Require().Nil(err)
+
+	resumed, err := reader.ResumeIterator(cursor)
+	suite.Require().Nil(err)
+	suite.Equal([]string{"d", "c", "b", "b", "a"}, suite.restOf(resumed))
+
+	_, err = suite.getCDBReader().ResumeIterator(cursor)
+	suite.Equal(ErrNoIndex, err)
+}
+
+func (suite *CDBTestSuite) TestResumeShardedIterator() {
+	files := suite.createShardFiles(3)
+	defer suite.removeShardFiles(files)
+
+	writers := make([]Writer, len(files))
+	for i, f := range files {
+		writer, err := suite.cdbHandle.GetWriter(f)
+		suite.Require().Nil(err)
+		writers[i] = writer
+	}
+
+	writer, err := suite.cdbHandle.NewShardedWriter(writers)
+	suite.Require().Nil(err)
+
+	for _, rec := range suite.testRecords {
+		suite.Require().Nil(writer.Put(rec.key, rec.val))
+	}
+
+	suite.Require().Nil(writer.Close())
+
+	readers := make([]Reader, len(files))
+	for i, f := range files {
+		readers[i], err = suite.cdbHandle.GetReader(f)
+		suite.Require().Nil(err)
+	}
+
+	reader, err := suite.cdbHandle.NewShardedReader(readers)
+	suite.Require().Nil(err)
+
+	iterator, err := reader.Iterator()
+	suite.Require().Nil(err)
+
+	for i := 0; i < len(suite.testRecords)*2/3; i++ {
+		suite.mustNext(iterator)
+	}
+
+	cursor, err := iterator.Cursor()
+	suite.Require().Nil(err)
+
+	resumed, err := reader.ResumeIterator(cursor)
+	suite.Require().Nil(err)
+	suite.Equal(suite.restOf(iterator), suite.restOf(resumed))
+
+	_, err = readers[0].ResumeIterator(cursor)
+	suite.Equal(ErrInvalidCursor, err)
+}
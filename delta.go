@@ -0,0 +1,43 @@
+package cdb
+
+import "errors"
+
+// ErrDeleteDisabled tells that it was an attempt to delete a key without the record flags support
+var ErrDeleteDisabled = errors.New("cdb tombstones require the record flags, see CDB.SetDelta")
+
+// SetDelta tells writers to build deltas: databases of upserts and deletions, which are overlaid
+// over a base snapshot by Stack, so an incremental update doesn't rebuild the snapshot. Writer.Delete
+// of a delta writes a tombstone, which hides the key of older layers. A delta stores the record flags
+// and makes writers produce the v2 format, SetRecordFlags enables deletions as well.
+// Like SetHash, it affects only new instances of Writer.
+func (cdb *CDB) SetDelta(enabled bool) {
+	cdb.opts.delta = enabled
+}
+
+// Delete writes a tombstone of the given key, see Writer.Delete
+func (w *writerImpl) Delete(key []byte) error {
+	return w.delete(key, 0)
+}
+
+// Delete writes a tombstone of the given key into the bucket, see Writer.Delete
+func (w *bucketWriter) Delete(key []byte) error {
+	if w.id == 0 {
+		return ErrBucketsDisabled
+	}
+
+	return w.delete(key, w.id)
+}
+
+// Delete writes a tombstone of the given key into the part of the key, see Writer.Delete
+func (w *shardedWriter) Delete(key []byte) error {
+	return w.parts[shardOf(w.hasher, key, len(w.parts))].Delete(key)
+}
+
+// delete writes a tombstone of the given key into the given bucket
+func (w *writerImpl) delete(key []byte, bucket uint32) error {
+	if w.header.flags&flagRecordFlags == 0 {
+		return ErrDeleteDisabled
+	}
+
+	return w.put(key, nil, recordMeta{flags: RecordTombstone, bucket: bucket})
+}
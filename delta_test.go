@@ -0,0 +1,47 @@
+package cdb
+
+func (suite *CDBTestSuite) TestDelta() {
+	suite.Equal(ErrDeleteDisabled, suite.getCDBWriter().Delete([]byte("key")))
+
+	files := suite.createShardFiles(2)
+	defer suite.removeShardFiles(files)
+
+	base, err := suite.cdbHandle.GetWriter(files[0])
+	suite.Require().Nil(err)
+
+	for _, key := range []string{"a", "b", "c"} {
+		suite.Require().Nil(base.Put([]byte(key), []byte("base-"+key)))
+	}
+
+	suite.Require().Nil(base.Close())
+
+	suite.cdbHandle.SetDelta(true)
+
+	delta, err := suite.cdbHandle.GetWriter(files[1])
+	suite.Require().Nil(err)
+	suite.Require().Nil(delta.Put([]byte("b"), []byte("delta-b")))
+	suite.Require().Nil(delta.Delete([]byte("c")))
+	suite.Require().Nil(delta.Put([]byte("d"), []byte("delta-d")))
+	suite.Require().Nil(delta.Close())
+
+	readers := make([]Reader, len(files))
+	for i, f := range files {
+		readers[i], err = suite.cdbHandle.GetReader(f)
+		suite.Require().Nil(err)
+	}
+
+	reader := Stack(readers...)
+
+	for key, value := range map[string]string{"a": "base-a", "b": "delta-b", "d": "delta-d"} {
+		got, err := reader.Get([]byte(key))
+		suite.Nil(err)
+		suite.Equal(value, string(got))
+	}
+
+	_, err = reader.Get([]byte("c"))
+	suite.Equal(ErrEntryNotFound, err)
+
+	iterator, err := reader.Iterator()
+	suite.Require().Nil(err)
+	suite.ElementsMatch([]string{"a=base-a", "b=delta-b", "d=delta-d"}, suite.mergedRecords(iterator))
+}
@@ -0,0 +1,48 @@
+package cdb
+
+import "sort"
+
+// deterministicSeed is the seed of the seeded hash of deterministic writers
+var deterministicSeed = [2]uint64{0x736f6d6570736575, 0x646f72616e646f6d}
+
+// SetDeterministic tells writers to produce byte-identical databases for identical sequences
+// of calls, so snapshot builds can be verified by their hashes. Writers lay out records and hash
+// tables in the call order and zero paddings anyway, the mode fixes the rest: the seeded hash
+// gets a fixed seed instead of a random one, so it no longer protects against crafted keys,
+// and stale bytes after the database are truncated, if the output has the Truncate method
+// like files. Like SetHash, it affects only new instances of Writer.
+func (cdb *CDB) SetDeterministic(enabled bool) {
+	cdb.opts.deterministic = enabled
+}
+
+// truncater is implemented by files and other outputs, which can be cut to the given size
+type truncater interface {
+	Truncate(size int64) error
+}
+
+// truncateOutput cuts the output of the writer right after the database in the deterministic mode
+func (w *writerImpl) truncateOutput(end int64) error {
+	if !w.deterministic {
+		return nil
+	}
+
+	if t, ok := w.writer.(truncater); ok {
+		return t.Truncate(end)
+	}
+
+	return nil
+}
+
+// sortedBucketNames returns the names of the given buckets in the order of their ids
+func sortedBucketNames(buckets map[string]bucketInfo) []string {
+	names := make([]string, 0, len(buckets))
+	for name := range buckets {
+		names = append(names, name)
+	}
+
+	sort.Slice(names, func(i, j int) bool {
+		return buckets[names[i]].id < buckets[names[j]].id
+	})
+
+	return names
+}
@@ -0,0 +1,69 @@
+package cdb
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+)
+
+// buildDeterministicTestCDB writes records into several buckets over stale bytes, returns the database
+func (suite *CDBTestSuite) buildDeterministicTestCDB() []byte {
+	suite.resetTestCDB()
+	_, err := suite.cdbFile.Write(bytes.Repeat([]byte{0xff}, 1<<16))
+	suite.Require().Nil(err)
+	_, err = suite.cdbFile.Seek(0, io.SeekStart)
+	suite.Require().Nil(err)
+
+	writer := suite.getCDBWriter()
+
+	for i := 0; i < 100; i++ {
+		bucket := writer.Bucket("bucket" + strconv.Itoa(i%5))
+		suite.Require().Nil(bucket.Put([]byte(strconv.Itoa(i)), []byte(strconv.Itoa(i))))
+	}
+
+	suite.Require().Nil(writer.Close())
+
+	data, err := ioutil.ReadFile(suite.cdbFile.Name())
+	suite.Require().Nil(err)
+
+	return data
+}
+
+func (suite *CDBTestSuite) TestSetDeterministic() {
+	suite.cdbHandle.SetSeededHash(true)
+	suite.cdbHandle.SetBuckets(true)
+	suite.Require().Nil(suite.cdbHandle.SetAlignment(8))
+	suite.Require().Nil(suite.cdbHandle.SetBloomFilter(10))
+
+	suite.NotEqual(suite.buildDeterministicTestCDB(), suite.buildDeterministicTestCDB(), "seeds are random")
+
+	suite.cdbHandle.SetDeterministic(true)
+	expected := suite.buildDeterministicTestCDB()
+	suite.Equal(expected, suite.buildDeterministicTestCDB())
+
+	value, err := suite.getCDBReader().Bucket("bucket3").Get([]byte("8"))
+	suite.Nil(err)
+	suite.Equal([]byte("8"), value)
+
+	f, err := ioutil.TempFile("", "test_*.cdb")
+	suite.Require().Nil(err)
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	var vacuumed [][]byte
+
+	for i := 0; i < 2; i++ {
+		suite.Require().Nil(f.Truncate(0))
+		_, err = f.Seek(0, io.SeekStart)
+		suite.Require().Nil(err)
+		suite.Require().Nil(suite.cdbHandle.Vacuum(f, suite.cdbFile))
+
+		data, err := ioutil.ReadFile(f.Name())
+		suite.Require().Nil(err)
+		vacuumed = append(vacuumed, data)
+	}
+
+	suite.Equal(vacuumed[0], vacuumed[1])
+}
@@ -0,0 +1,58 @@
+package cdb
+
+import "errors"
+
+// ErrDuplicateKey tells that it was an attempt to put an already written key with the DuplicatesError policy
+var ErrDuplicateKey = errors.New("cdb key is already written")
+
+// DuplicatePolicy tells what Put does with an already written key, see CDB.SetDuplicates
+type DuplicatePolicy int
+
+const (
+	// DuplicatesKeepAll keeps all records of a key, Get returns the first one
+	DuplicatesKeepAll DuplicatePolicy = iota
+	// DuplicatesError makes Put of an already written key fail with ErrDuplicateKey
+	DuplicatesError
+	// DuplicatesKeepFirst makes Put of an already written key do nothing
+	DuplicatesKeepFirst
+	// DuplicatesKeepLast makes the last Put of a key win, older records of the key are removed
+	// from hash tables at Close and marked as superseded, so iterators skip them
+	DuplicatesKeepLast
+)
+
+// SetDuplicates tells writers, what Put does with an already written key of the same bucket.
+// Every policy except DuplicatesKeepAll makes the writer keep all keys in memory,
+// DuplicatesKeepLast requires the record flags and makes writers produce the v2 format.
+// Versioned databases keep the versions of a key instead, see SetVersions.
+// Like SetHash, it affects only new instances of Writer.
+func (cdb *CDB) SetDuplicates(policy DuplicatePolicy) {
+	cdb.opts.duplicates = policy
+}
+
+// checkDuplicate tells if the record of the given key is written according to the duplicate policy.
+// The record, which is replaced with DuplicatesKeepLast, is returned too.
+func (w *writerImpl) checkDuplicate(id string) (write bool, replaced *versionRef, err error) {
+	ref, seen := w.seen[id]
+	if !seen {
+		return true, nil, nil
+	}
+
+	switch w.duplicates {
+	case DuplicatesError:
+		return false, nil, ErrDuplicateKey
+	case DuplicatesKeepFirst:
+		return false, nil, nil
+	}
+
+	return true, &ref, nil
+}
+
+// addKey remembers the record of a key with the given id, the replaced record of the key is dropped
+func (w *writerImpl) addKey(id string, ref versionRef, replaced *versionRef) {
+	if replaced != nil {
+		w.dropped = append(w.dropped, *replaced)
+		w.bucketSizes[replaced.bucket]--
+	}
+
+	w.seen[id] = ref
+}
@@ -0,0 +1,48 @@
+package cdb
+
+func (suite *CDBTestSuite) TestDuplicates() {
+	cases := []struct {
+		policy   DuplicatePolicy
+		err      error
+		value    string
+		iterated []string
+	}{
+		{DuplicatesKeepAll, nil, "1", []string{"a=1", "b=2", "a=3"}},
+		{DuplicatesError, ErrDuplicateKey, "1", []string{"a=1", "b=2"}},
+		{DuplicatesKeepFirst, nil, "1", []string{"a=1", "b=2"}},
+		{DuplicatesKeepLast, nil, "3", []string{"b=2", "a=3"}},
+	}
+
+	for _, c := range cases {
+		suite.resetTestCDB()
+		suite.cdbHandle.SetDuplicates(c.policy)
+
+		writer := suite.getCDBWriter()
+		suite.Require().Nil(writer.Put([]byte("a"), []byte("1")))
+		suite.Require().Nil(writer.Put([]byte("b"), []byte("2")))
+		suite.Equal(c.err, writer.Put([]byte("a"), []byte("3")))
+		suite.Require().Nil(writer.Close())
+
+		reader := suite.getCDBReader()
+		suite.Equal(len(c.iterated), reader.Size())
+
+		value, err := reader.Get([]byte("a"))
+		suite.Nil(err)
+		suite.Equal(c.value, string(value))
+
+		iterator, err := reader.Iterator()
+		suite.Require().Nil(err)
+		suite.Equal(c.iterated, suite.mergedRecords(iterator))
+	}
+}
+
+func (suite *CDBTestSuite) TestDuplicatesInBuckets() {
+	suite.cdbHandle.SetDuplicates(DuplicatesError)
+	suite.cdbHandle.SetBuckets(true)
+
+	writer := suite.getCDBWriter()
+	suite.Require().Nil(writer.Put([]byte("a"), []byte("1")))
+	suite.Nil(writer.Bucket("other").Put([]byte("a"), []byte("2")), "Buckets are independent keyspaces")
+	suite.Equal(ErrDuplicateKey, writer.Bucket("other").Put([]byte("a"), []byte("3")))
+	suite.Require().Nil(writer.Close())
+}
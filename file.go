@@ -0,0 +1,265 @@
+package cdb
+
+import (
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// Maximum number of attempts to find a free temporary name
+const maxTempNameAttempts = 100
+
+// fileReader implements Reader interface, closes the database file on Close
+type fileReader struct {
+	Reader
+	file io.Closer
+}
+
+// fileWriter implements Writer interface, closes the database file on Close
+// and removes it on Abort
+type fileWriter struct {
+	Writer
+	file io.Closer
+	path string
+	// sync tells that the directory of the file is synced on Close, see CDB.SetSync
+	sync bool
+}
+
+// Open opens the database file at the given path with the default options, see CDB.Open.
+func Open(path string) (Reader, error) {
+	return New().Open(path)
+}
+
+// Create creates the database file at the given path with the default options, see CDB.Create.
+func Create(path string) (Writer, error) {
+	return New().Create(path)
+}
+
+// WriteFile builds the database file at the given path with the default options, see CDB.WriteFile.
+func WriteFile(path string, build func(Writer) error) error {
+	return New().WriteFile(path, build)
+}
+
+// Open opens the database file at the given path for reading.
+// Close of the returned reader closes the file.
+func (cdb *CDB) Open(path string) (Reader, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	reader, err := cdb.GetReader(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return &fileReader{reader, f}, nil
+}
+
+// Create creates or truncates the database file at the given path for writing.
+// Close of the returned writer commits the database and closes the file.
+func (cdb *CDB) Create(path string) (Writer, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+
+	writer, err := cdb.GetWriter(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return &fileWriter{writer, f, path, cdb.opts.sync}, nil
+}
+
+// Close releases resources of the reader and closes the file.
+func (r *fileReader) Close() error {
+	err := r.Reader.Close()
+
+	if closeErr := r.file.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
+
+// Close commits the database and closes the file. With the sync enabled the directory is synced too,
+// so the entry of a new file is durable.
+func (w *fileWriter) Close() error {
+	err := w.Writer.Close()
+
+	if closeErr := w.file.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err == nil && w.sync {
+		syncDir(filepath.Dir(w.path))
+	}
+
+	return err
+}
+
+// Abort discards the database, closes and removes the file.
+func (w *fileWriter) Abort() error {
+	err := w.Writer.Abort()
+
+	if closeErr := w.file.Close(); err == nil {
+		err = closeErr
+	}
+
+	if removeErr := os.Remove(w.path); err == nil {
+		err = removeErr
+	}
+
+	return err
+}
+
+// WriteFile builds the database file at the given path atomically: build puts records into
+// a temporary file in the same directory, which is synced and renamed to the path, so readers
+// never observe a partially written database. An error of build is returned and the temporary
+// file is removed then, the file at the path is left untouched. A replaced file keeps its mode,
+// a new file gets the 0644 mode. On Linux the temporary file is unnamed (O_TMPFILE), if the filesystem
+// supports it, so even a crashed build leaves nothing behind. build may close the writer itself,
+// WriteFile closes it anyway.
+func (cdb *CDB) WriteFile(path string, build func(Writer) error) error {
+	if f, ok := openUnnamed(filepath.Dir(path)); ok {
+		return cdb.writeUnnamed(f, path, build)
+	}
+
+	dir, name := filepath.Split(path)
+
+	f, err := ioutil.TempFile(dir, "."+name+".tmp*")
+	if err != nil {
+		return err
+	}
+
+	if err := cdb.writeFile(f, path, build); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+
+	if err := os.Rename(f.Name(), path); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+
+	syncDir(filepath.Dir(path))
+
+	return nil
+}
+
+// writeUnnamed builds the database in the given unnamed file and publishes it at the path.
+// The file gets a name only once it is complete and synced.
+func (cdb *CDB) writeUnnamed(f *os.File, path string, build func(Writer) error) error {
+	if err := cdb.writeFile(f, path, build); err != nil {
+		f.Close()
+		return err
+	}
+
+	// linkat doesn't replace files, so the file is linked under a temporary name and renamed then
+	dir, name := filepath.Split(path)
+
+	var tmp string
+
+	for i := 0; ; i++ {
+		tmp = filepath.Join(dir, "."+name+".tmp"+strconv.Itoa(rand.Int()))
+
+		err := linkUnnamed(f, tmp)
+		if err == nil {
+			break
+		}
+
+		if !os.IsExist(err) || i == maxTempNameAttempts {
+			f.Close()
+			return err
+		}
+	}
+
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	syncDir(filepath.Dir(path))
+
+	return nil
+}
+
+// writeFile builds the database in the given temporary file, gives it the mode of the file at the path and syncs it
+func (cdb *CDB) writeFile(f *os.File, path string, build func(Writer) error) error {
+	writer, err := cdb.GetWriter(f)
+	if err != nil {
+		return err
+	}
+
+	// Abort stops the hashing workers and removes the spill file of a failed build
+	if err := build(writer); err != nil {
+		writer.Abort()
+		return err
+	}
+
+	if err := writer.Close(); err != nil {
+		writer.Abort()
+		return err
+	}
+
+	mode := os.FileMode(0644)
+
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	if err := f.Chmod(mode); err != nil {
+		return err
+	}
+
+	return f.Sync()
+}
+
+// SetSync tells writers to fsync the database on Close, and the sorted index, if there is one,
+// so that a build, which Close returned for, survives a crash. Only files and other writers
+// with the Sync method are synced. Close of a writer returned by Create syncs the directory
+// of the file too. WriteFile always syncs. Like SetHash, it affects only new instances of Writer.
+func (cdb *CDB) SetSync(enabled bool) {
+	cdb.opts.sync = enabled
+}
+
+// syncer is implemented by files and other writers, which can commit written data to stable storage
+type syncer interface {
+	Sync() error
+}
+
+// syncOutput commits the given writer to stable storage, if it is a syncer
+func syncOutput(writer interface{}) error {
+	if s, ok := writer.(syncer); ok {
+		return s.Sync()
+	}
+
+	return nil
+}
+
+// syncDir syncs the directory at the given path, so a rename in it is durable.
+// Errors are ignored: not every platform syncs directories.
+func syncDir(path string) {
+	if d, err := os.Open(path); err == nil {
+		d.Sync()
+		d.Close()
+	}
+}
@@ -0,0 +1,308 @@
+package cdb
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+func (suite *CDBTestSuite) TestOpenCreate() {
+	dir, err := ioutil.TempDir("", "test_cdb")
+	suite.Require().Nil(err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "test.cdb")
+
+	writer, err := Create(path)
+	suite.Require().Nil(err)
+
+	for _, rec := range suite.testRecords {
+		suite.Require().Nil(writer.Put(rec.key, rec.val))
+	}
+
+	suite.Require().Nil(writer.Close())
+
+	reader, err := Open(path)
+	suite.Require().Nil(err)
+
+	for _, rec := range suite.testRecords {
+		value, err := reader.Get(rec.key)
+		suite.Nil(err)
+		suite.Equal(rec.val, value)
+	}
+
+	suite.Nil(reader.Close())
+
+	_, err = Open(filepath.Join(dir, "missing.cdb"))
+	suite.True(os.IsNotExist(err))
+}
+
+func (suite *CDBTestSuite) TestAbort() {
+	dir, err := ioutil.TempDir("", "test_cdb")
+	suite.Require().Nil(err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "test.cdb")
+
+	writer, err := Create(path)
+	suite.Require().Nil(err)
+	suite.Require().Nil(writer.Put([]byte("key"), []byte("value")))
+	suite.Nil(writer.Abort())
+
+	_, err = os.Stat(path)
+	suite.True(os.IsNotExist(err), "The aborted file must be removed")
+
+	// A writer, which doesn't own the file, leaves it to the caller
+	writer = suite.getCDBWriter()
+	suite.Require().Nil(writer.Put([]byte("key"), []byte("value")))
+	suite.Nil(writer.Abort())
+	suite.Equal(ErrWriterAborted, writer.Put([]byte("key"), []byte("value")))
+	suite.Equal(ErrWriterAborted, writer.Close())
+
+	_, err = os.Stat(suite.cdbFile.Name())
+	suite.Nil(err)
+}
+
+func (suite *CDBTestSuite) TestWriteFile() {
+	dir, err := ioutil.TempDir("", "test_cdb")
+	suite.Require().Nil(err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "test.cdb")
+
+	err = WriteFile(path, func(writer Writer) error {
+		for _, rec := range suite.testRecords {
+			if err := writer.Put(rec.key, rec.val); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	suite.Require().Nil(err)
+
+	info, err := os.Stat(path)
+	suite.Require().Nil(err)
+	suite.Equal(os.FileMode(0644), info.Mode().Perm())
+	suite.Require().Nil(os.Chmod(path, 0600))
+
+	failure := errors.New("failure")
+	err = WriteFile(path, func(writer Writer) error {
+		suite.Require().Nil(writer.Put([]byte("partial"), nil))
+		return failure
+	})
+	suite.Equal(failure, err)
+
+	reader, err := Open(path)
+	suite.Require().Nil(err)
+	suite.Equal(len(suite.testRecords), reader.Size(), "The failed build must not replace the file")
+	suite.Nil(reader.Close())
+
+	suite.Require().Nil(WriteFile(path, func(writer Writer) error {
+		return writer.Put([]byte("key"), []byte("value"))
+	}))
+
+	reader, err = Open(path)
+	suite.Require().Nil(err)
+	suite.Equal(1, reader.Size())
+	suite.Nil(reader.Close())
+
+	info, err = os.Stat(path)
+	suite.Require().Nil(err)
+	suite.Equal(os.FileMode(0600), info.Mode().Perm(), "The replaced file must keep its mode")
+
+	// A writer closed by build is not committed twice
+	expected, err := ioutil.ReadFile(path)
+	suite.Require().Nil(err)
+
+	suite.Require().Nil(WriteFile(path, func(writer Writer) error {
+		if err := writer.Put([]byte("key"), []byte("value")); err != nil {
+			return err
+		}
+
+		return writer.Close()
+	}))
+
+	data, err := ioutil.ReadFile(path)
+	suite.Require().Nil(err)
+	suite.Equal(expected, data)
+
+	files, err := ioutil.ReadDir(dir)
+	suite.Require().Nil(err)
+	suite.Len(files, 1, "Temporary files must be removed")
+}
+
+func (suite *CDBTestSuite) TestWriteFileAbort() {
+	dir, err := ioutil.TempDir("", "test_cdb")
+	suite.Require().Nil(err)
+	defer os.RemoveAll(dir)
+
+	spillDir := filepath.Join(dir, "spill")
+	suite.Require().Nil(os.Mkdir(spillDir, 0755))
+
+	suite.cdbHandle.SetSpill(spillDir, 1)
+
+	failure := errors.New("failure")
+	err = suite.cdbHandle.WriteFile(filepath.Join(dir, "test.cdb"), func(writer Writer) error {
+		for _, rec := range suite.testRecords {
+			suite.Require().Nil(writer.Put(rec.key, rec.val))
+		}
+
+		return failure
+	})
+	suite.Equal(failure, err)
+
+	files, err := ioutil.ReadDir(spillDir)
+	suite.Require().Nil(err)
+	suite.Empty(files, "The spill file of a failed build must be removed")
+}
+
+func (suite *CDBTestSuite) TestWriteFileUnnamed() {
+	dir, err := ioutil.TempDir("", "test_cdb")
+	suite.Require().Nil(err)
+	defer os.RemoveAll(dir)
+
+	f, ok := openUnnamed(dir)
+	if !ok {
+		suite.T().Skip("unnamed files are not supported")
+	}
+
+	suite.Nil(f.Close())
+
+	path := filepath.Join(dir, "test.cdb")
+
+	suite.Require().Nil(WriteFile(path, func(writer Writer) error {
+		files, err := ioutil.ReadDir(dir)
+		suite.Require().Nil(err)
+		suite.Empty(files, "The file being built has no name")
+
+		return writer.Put([]byte("key"), []byte("value"))
+	}))
+
+	reader, err := Open(path)
+	suite.Require().Nil(err)
+
+	value, err := reader.Get([]byte("key"))
+	suite.Nil(err)
+	suite.Equal([]byte("value"), value)
+	suite.Nil(reader.Close())
+
+	files, err := ioutil.ReadDir(dir)
+	suite.Require().Nil(err)
+	suite.Len(files, 1, "Temporary names must be removed")
+}
+
+// syncedFile counts syncs of a file
+type syncedFile struct {
+	*os.File
+	syncs int
+}
+
+func (f *syncedFile) Sync() error {
+	f.syncs++
+	return nil
+}
+
+// syncedBuffer counts syncs of a buffer
+type syncedBuffer struct {
+	bytes.Buffer
+	syncs int
+}
+
+func (b *syncedBuffer) Sync() error {
+	b.syncs++
+	return nil
+}
+
+func (suite *CDBTestSuite) TestSetSync() {
+	file := &syncedFile{File: suite.cdbFile}
+	index := &syncedBuffer{}
+
+	writer, err := suite.cdbHandle.GetWriterWithIndex(file, index)
+	suite.Require().Nil(err)
+	suite.Require().Nil(writer.Put([]byte("key"), []byte("value")))
+	suite.Require().Nil(writer.Close())
+	suite.Zero(file.syncs+index.syncs, "nothing is synced by default")
+
+	suite.resetTestCDB()
+	suite.cdbHandle.SetSync(true)
+
+	writer, err = suite.cdbHandle.GetWriterWithIndex(file, index)
+	suite.Require().Nil(err)
+	suite.Require().Nil(writer.Put([]byte("key"), []byte("value")))
+	suite.Require().Nil(writer.Close())
+	suite.Equal(1, file.syncs)
+	suite.Equal(1, index.syncs)
+
+	value, err := suite.getCDBReader().Get([]byte("key"))
+	suite.Nil(err)
+	suite.Equal([]byte("value"), value)
+}
+
+// failingFile fails the next write once broken, counts syncs
+type failingFile struct {
+	syncedFile
+	broken bool
+}
+
+func (f *failingFile) Write(p []byte) (int, error) {
+	if f.broken {
+		f.broken = false
+		return 0, errors.New("write failed")
+	}
+
+	return f.syncedFile.Write(p)
+}
+
+func (suite *CDBTestSuite) TestSetSyncFlushError() {
+	suite.cdbHandle.SetSync(true)
+
+	file := &failingFile{syncedFile: syncedFile{File: suite.cdbFile}}
+
+	writer, err := suite.cdbHandle.GetWriter(file)
+	suite.Require().Nil(err)
+	suite.Require().Nil(writer.Put([]byte("key"), []byte("value")))
+
+	file.broken = true
+	suite.NotNil(writer.Close(), "a failed flush of buffered records is returned")
+	suite.Zero(file.syncs, "nothing is synced after a failed flush")
+}
+
+func (suite *CDBTestSuite) TestSetAccessPattern() {
+	for _, pattern := range []AccessPattern{AccessRandom, AccessSequential, AccessWillNeed} {
+		suite.cdbHandle.SetAccessPattern(pattern)
+		suite.TestShouldReturnAllValues()
+		suite.resetTestCDB()
+
+		suite.fillTestCDB()
+		reader, err := suite.cdbHandle.GetReaderMmap(suite.cdbFile)
+		suite.Require().Nil(err)
+
+		for _, rec := range suite.testRecords {
+			value, err := reader.Get(rec.key)
+			suite.Nil(err)
+			suite.Equal(rec.val, value)
+		}
+
+		suite.Nil(reader.Close())
+		suite.resetTestCDB()
+	}
+}
+
+func (suite *CDBTestSuite) TestSetHugePages() {
+	suite.cdbHandle.SetHugePages(true)
+	suite.fillTestCDB()
+
+	reader, err := suite.cdbHandle.GetReaderMmap(suite.cdbFile)
+	suite.Require().Nil(err)
+
+	for _, rec := range suite.testRecords {
+		value, err := reader.Get(rec.key)
+		suite.Nil(err)
+		suite.Equal(rec.val, value)
+	}
+
+	suite.Nil(reader.Close())
+}
@@ -0,0 +1,172 @@
+package cdb
+
+// IteratorWithFilter returns a new Iterator object, which walks the records of the reader accepted
+// by the given function in the file order. Every value is read to be passed to keep.
+// Returns nil if no record is accepted.
+func IteratorWithFilter(reader Reader, keep func(key, value []byte) bool) (Iterator, error) {
+	iterator, err := reader.Iterator()
+	if err == ErrEmptyCDB {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return newFilterIterator(iterator, reader, func(key, value []byte) (bool, error) {
+		return keep(key, value), nil
+	})
+}
+
+// IteratorWithKeyFilter is like IteratorWithFilter, but records are accepted by keys only, so values
+// of skipped records are never read, see Reader.KeyIterator.
+func IteratorWithKeyFilter(reader Reader, keep func(key []byte) bool) (Iterator, error) {
+	iterator, err := reader.KeyIterator()
+	if err == ErrEmptyCDB {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return newFilterIterator(iterator, nil, func(key, _ []byte) (bool, error) {
+		return keep(key), nil
+	})
+}
+
+// filterIterator implements Iterator interface, walks the records of the given iterator,
+// which are accepted by the keep function.
+type filterIterator struct {
+	Iterator
+	// keep tells if a record is walked, the value is nil unless values are filtered
+	keep func(key, value []byte) (bool, error)
+	// reader provides values of sought keys, it is nil unless values are filtered
+	reader Reader
+	// err is the error, which stopped the iterator
+	err error
+	// records is the number of moves of the iterator, see Iterator.Position
+	records int
+}
+
+// newFilterIterator returns a new filterIterator, which points on the first accepted record
+// of the given iterator. Values are passed to keep, if the reader of the iterator is given.
+// Returns nil if there are no accepted records.
+func newFilterIterator(iterator Iterator, reader Reader, keep func(key, value []byte) (bool, error)) (Iterator, error) {
+	i := &filterIterator{Iterator: iterator, keep: keep, reader: reader}
+
+	ok, err := i.settle()
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	return i, nil
+}
+
+// Next moves the iterator to the next accepted record. Returns true on success otherwise returns false.
+func (i *filterIterator) Next() (bool, error) {
+	if i.err != nil {
+		return false, i.err
+	}
+
+	ok, err := i.Iterator.Next()
+	if err != nil || !ok {
+		return false, err
+	}
+
+	if ok, err = i.settle(); err != nil {
+		i.err = err
+	}
+
+	if ok {
+		i.records++
+	}
+
+	return ok, err
+}
+
+// Reset moves the iterator back to the record it pointed on when it was created, see Iterator.Reset
+func (i *filterIterator) Reset() error {
+	i.err, i.records = nil, 0
+
+	if err := i.Iterator.Reset(); err != nil {
+		return err
+	}
+
+	_, err := i.settle()
+
+	return err
+}
+
+// settle moves the iterator to the first accepted record starting from the current one
+func (i *filterIterator) settle() (bool, error) {
+	for {
+		accepted, err := i.accepted()
+		if err != nil || accepted {
+			return accepted, err
+		}
+
+		ok, err := i.Iterator.Next()
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+}
+
+// accepted tells if the current record is accepted
+func (i *filterIterator) accepted() (bool, error) {
+	key, err := i.Key()
+	if err != nil {
+		return false, err
+	}
+
+	var value []byte
+
+	if i.reader != nil {
+		if value, err = i.Value(); err != nil {
+			return false, err
+		}
+	}
+
+	return i.keep(key, value)
+}
+
+// Err returns the error, which stopped the iterator
+func (i *filterIterator) Err() error {
+	if i.err != nil {
+		return i.err
+	}
+
+	return i.Iterator.Err()
+}
+
+// Seek moves the iterator to the first record associated with the given key,
+// if the record is accepted, see Iterator.Seek
+func (i *filterIterator) Seek(key []byte) error {
+	var value []byte
+
+	if i.reader != nil {
+		var err error
+
+		if value, err = i.reader.Get(key); err != nil {
+			return err
+		}
+	}
+
+	accepted, err := i.keep(key, value)
+	if err != nil {
+		return err
+	}
+
+	if !accepted {
+		return ErrEntryNotFound
+	}
+
+	if err := i.Iterator.Seek(key); err != nil {
+		return err
+	}
+
+	i.records++
+
+	return nil
+}
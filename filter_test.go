@@ -0,0 +1,50 @@
+package cdb
+
+import (
+	"bytes"
+	"strconv"
+)
+
+func (suite *CDBTestSuite) TestIteratorWithFilter() {
+	writer := suite.getCDBWriter()
+	for i := 0; i < 10; i++ {
+		suite.Require().Nil(writer.Put([]byte("key"+strconv.Itoa(i)), []byte(strconv.Itoa(i*i))))
+	}
+	suite.Require().Nil(writer.Close())
+
+	reader := suite.getCDBReader()
+
+	walk := func(iterator Iterator) []string {
+		var actual []string
+
+		for ok := iterator != nil; ok; ok = suite.mustNext(iterator) {
+			key, err := iterator.Key()
+			suite.Require().Nil(err)
+			actual = append(actual, string(key))
+		}
+
+		return actual
+	}
+
+	iterator, err := IteratorWithFilter(reader, func(key, value []byte) bool {
+		return bytes.HasSuffix(value, []byte("6"))
+	})
+	suite.Require().Nil(err)
+	suite.Equal([]string{"key4", "key6"}, walk(iterator))
+
+	suite.Equal(ErrEntryNotFound, iterator.Seek([]byte("key5")))
+	suite.Nil(iterator.Seek([]byte("key4")))
+	suite.Equal([]string{"key4", "key6"}, walk(iterator))
+
+	iterator, err = IteratorWithKeyFilter(reader, func(key []byte) bool {
+		return bytes.HasSuffix(key, []byte("1"))
+	})
+	suite.Require().Nil(err)
+	suite.Equal([]string{"key1"}, walk(iterator))
+
+	iterator, err = IteratorWithKeyFilter(reader, func(key []byte) bool {
+		return false
+	})
+	suite.Nil(err)
+	suite.Nil(iterator)
+}
@@ -0,0 +1,58 @@
+package cdb
+
+import "errors"
+
+// Stop can be returned by the function of ForEach to stop the walk without an error
+var Stop = errors.New("cdb walk is stopped")
+
+// ForEach calls fn for every record of the reader in the file order until fn returns an error,
+// which is returned then, except Stop, which just stops the walk. An empty database is walked
+// without calls. The key and the value belong to fn.
+func ForEach(reader Reader, fn func(key, value []byte) error) error {
+	var fnErr error
+
+	err := walk(reader, true, func(key, value []byte) bool {
+		fnErr = fn(key, value)
+		return fnErr == nil
+	})
+
+	if err != nil {
+		return err
+	}
+
+	if fnErr == Stop {
+		return nil
+	}
+
+	return fnErr
+}
+
+// walk calls yield for every record of the reader until it returns false.
+// Values are read only if withValues is true.
+func walk(reader Reader, withValues bool, yield func(key, value []byte) bool) error {
+	iterator, err := reader.Iterator()
+	if err == ErrEmptyCDB {
+		return nil
+	}
+
+	for ok := err == nil; ok; ok, err = iterator.Next() {
+		key, err := iterator.Key()
+		if err != nil {
+			return err
+		}
+
+		var value []byte
+
+		if withValues {
+			if value, err = iterator.Value(); err != nil {
+				return err
+			}
+		}
+
+		if !yield(key, value) {
+			return nil
+		}
+	}
+
+	return err
+}
@@ -0,0 +1,47 @@
+package cdb
+
+import (
+	"errors"
+)
+
+func (suite *CDBTestSuite) TestForEach() {
+	suite.fillTestCDB()
+	reader := suite.getCDBReader()
+
+	i := 0
+	err := ForEach(reader, func(key, value []byte) error {
+		suite.Equal(suite.testRecords[i].key, key)
+		suite.Equal(suite.testRecords[i].val, value)
+		i++
+		return nil
+	})
+	suite.Nil(err)
+	suite.Equal(len(suite.testRecords), i)
+
+	i = 0
+	err = ForEach(reader, func(key, value []byte) error {
+		i++
+		if i == 2 {
+			return Stop
+		}
+		return nil
+	})
+	suite.Nil(err)
+	suite.Equal(2, i)
+
+	failure := errors.New("failure")
+	err = ForEach(reader, func(key, value []byte) error {
+		return failure
+	})
+	suite.Equal(failure, err)
+}
+
+func (suite *CDBTestSuite) TestForEachEmpty() {
+	suite.writeEmptyCDB()
+
+	err := ForEach(suite.getCDBReader(), func(key, value []byte) error {
+		suite.Fail("Empty database has no records")
+		return nil
+	})
+	suite.Nil(err)
+}
@@ -49,3 +49,15 @@ func (h *hashImpl) BlockSize() int {
 func (h *hashImpl) Size() int {
 	return size
 }
+
+// mix32 returns the murmur3 finalizer of the given hash. It derives a new well distributed
+// value from a key hash, when bits of the hash itself are already used for another purpose.
+func mix32(h uint32) uint32 {
+	h ^= h >> 16
+	h *= 0x85ebca6b
+	h ^= h >> 13
+	h *= 0xc2b2ae35
+	h ^= h >> 16
+
+	return h
+}
@@ -0,0 +1,143 @@
+package cdb
+
+import (
+	"encoding/binary"
+	"hash"
+	"hash/crc32"
+	"math/bits"
+)
+
+const (
+	xxPrime1 uint32 = 2654435761
+	xxPrime2 uint32 = 2246822519
+	xxPrime3 uint32 = 3266489917
+	xxPrime4 uint32 = 668265263
+	xxPrime5 uint32 = 374761393
+)
+
+// castagnoli is the CRC32C table, it is computed with the hardware support where available
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// NewCRC32C returns new instance of hash.Hash32 computing CRC-32C, which uses
+// the hardware support on amd64 and arm64. Use it with SetHash. Databases written with
+// a Hasher must be read with the same one.
+func NewCRC32C() hash.Hash32 {
+	return crc32.New(castagnoli)
+}
+
+// NewXXHash32 returns new instance of hash.Hash32 computing xxHash32 with zero seed,
+// which is much faster than the default hash on long keys. Use it with SetHash.
+// Databases written with a Hasher must be read with the same one.
+func NewXXHash32() hash.Hash32 {
+	h := &xxHash32{}
+	h.Reset()
+
+	return h
+}
+
+// xxHash32 implements hash.Hash32 described https://github.com/Cyan4973/xxHash/blob/dev/doc/xxhash_spec.md
+type xxHash32 struct {
+	v1, v2, v3, v4 uint32
+	// tail keeps the bytes, which don't form a whole 16 bytes stripe yet
+	tail  [16]byte
+	ntail int
+	// length is the number of written bytes
+	length uint64
+}
+
+func (h *xxHash32) Reset() {
+	// Arithmetic on the zero seed wraps around like the reference implementation does
+	var seed uint32
+
+	h.v1 = seed + xxPrime1 + xxPrime2
+	h.v2 = seed + xxPrime2
+	h.v3 = seed
+	h.v4 = seed - xxPrime1
+	h.ntail = 0
+	h.length = 0
+}
+
+func (h *xxHash32) Write(data []byte) (int, error) {
+	n := len(data)
+	h.length += uint64(n)
+
+	if h.ntail > 0 {
+		c := copy(h.tail[h.ntail:], data)
+		h.ntail += c
+		data = data[c:]
+
+		if h.ntail < len(h.tail) {
+			return n, nil
+		}
+
+		h.stripe(h.tail[:])
+		h.ntail = 0
+	}
+
+	for len(data) >= 16 {
+		h.stripe(data)
+		data = data[16:]
+	}
+
+	h.ntail = copy(h.tail[:], data)
+
+	return n, nil
+}
+
+// stripe processes the given 16 bytes stripe
+func (h *xxHash32) stripe(data []byte) {
+	h.v1 = xxRound(h.v1, binary.LittleEndian.Uint32(data))
+	h.v2 = xxRound(h.v2, binary.LittleEndian.Uint32(data[4:]))
+	h.v3 = xxRound(h.v3, binary.LittleEndian.Uint32(data[8:]))
+	h.v4 = xxRound(h.v4, binary.LittleEndian.Uint32(data[12:]))
+}
+
+// xxRound mixes the given lane into the accumulator
+func xxRound(acc, lane uint32) uint32 {
+	return bits.RotateLeft32(acc+lane*xxPrime2, 13) * xxPrime1
+}
+
+func (h *xxHash32) Sum32() uint32 {
+	var sum uint32
+
+	if h.length >= 16 {
+		sum = bits.RotateLeft32(h.v1, 1) + bits.RotateLeft32(h.v2, 7) +
+			bits.RotateLeft32(h.v3, 12) + bits.RotateLeft32(h.v4, 18)
+	} else {
+		sum = xxPrime5
+	}
+
+	sum += uint32(h.length)
+	tail := h.tail[:h.ntail]
+
+	for ; len(tail) >= 4; tail = tail[4:] {
+		sum += binary.LittleEndian.Uint32(tail) * xxPrime3
+		sum = bits.RotateLeft32(sum, 17) * xxPrime4
+	}
+
+	for _, c := range tail {
+		sum += uint32(c) * xxPrime5
+		sum = bits.RotateLeft32(sum, 11) * xxPrime1
+	}
+
+	sum ^= sum >> 15
+	sum *= xxPrime2
+	sum ^= sum >> 13
+	sum *= xxPrime3
+	sum ^= sum >> 16
+
+	return sum
+}
+
+func (h *xxHash32) Sum(b []byte) []byte {
+	s := h.Sum32()
+	return append(b, byte(s>>24), byte(s>>16), byte(s>>8), byte(s))
+}
+
+func (h *xxHash32) BlockSize() int {
+	return 16
+}
+
+func (h *xxHash32) Size() int {
+	return size
+}
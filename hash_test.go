@@ -0,0 +1,98 @@
+package cdb
+
+import (
+	"bytes"
+	"fmt"
+	"hash"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// goldenHashes are the hashes, which on-disk compatibility is checked against the files of testdata
+var goldenHashes = map[string]Hasher{
+	"cdb":      NewHash,
+	"xxhash32": NewXXHash32,
+	"crc32c":   NewCRC32C,
+}
+
+// writeGoldenCDB writes the records of golden files with the given hasher to the given file
+func (suite *CDBTestSuite) writeGoldenCDB(f *os.File, hasher Hasher) {
+	handle := New()
+	handle.SetHash(hasher)
+
+	writer, err := handle.GetWriter(f)
+	suite.Require().Nil(err)
+
+	for i := 0; i < 100; i++ {
+		suite.Require().Nil(writer.Put([]byte(fmt.Sprintf("key-%d", i)), []byte(fmt.Sprintf("value-%d", i))))
+	}
+
+	suite.Require().Nil(writer.Put([]byte(strings.Repeat("long key ", 20)), []byte("long value")))
+	suite.Require().Nil(writer.Close())
+}
+
+func (suite *CDBTestSuite) TestHashGoldenFiles() {
+	for name, hasher := range goldenHashes {
+		golden, err := ioutil.ReadFile(filepath.Join("testdata", name+".cdb"))
+		suite.Require().Nil(err)
+
+		handle := New()
+		handle.SetHash(hasher)
+
+		reader, err := handle.NewReaderFromBytes(golden)
+		suite.Require().Nil(err)
+
+		for i := 0; i < 100; i++ {
+			value, err := reader.Get([]byte(fmt.Sprintf("key-%d", i)))
+			suite.Nil(err, name)
+			suite.Equal(fmt.Sprintf("value-%d", i), string(value), name)
+		}
+
+		value, err := reader.Get([]byte(strings.Repeat("long key ", 20)))
+		suite.Nil(err, name)
+		suite.Equal("long value", string(value), name)
+
+		suite.resetTestCDB()
+		suite.writeGoldenCDB(suite.cdbFile, hasher)
+
+		written, err := ioutil.ReadFile(suite.cdbFile.Name())
+		suite.Require().Nil(err)
+		suite.True(bytes.Equal(golden, written), "%s output differs from the golden file", name)
+	}
+}
+
+func (suite *CDBTestSuite) TestFastHashes() {
+	vectors := []struct {
+		hasher Hasher
+		data   string
+		sum    uint32
+	}{
+		{NewXXHash32, "", 0x02cc5d05},
+		{NewXXHash32, "a", 0x550d7456},
+		{NewXXHash32, "abc", 0x32d153ff},
+		{NewXXHash32, "Nobody inspects the spammish repetition", 0xe2293b2f},
+		{NewCRC32C, "123456789", 0xe3069283},
+	}
+
+	for _, v := range vectors {
+		h := v.hasher()
+		suite.Equal(v.sum, sum32(h, v.data), v.data)
+
+		// Streaming writes give the same result
+		h.Reset()
+		for i := 0; i < len(v.data); i++ {
+			h.Write([]byte{v.data[i]})
+		}
+		suite.Equal(v.sum, h.Sum32(), v.data)
+	}
+}
+
+// sum32 returns the hash of the given data
+func sum32(h hash.Hash32, data string) uint32 {
+	h.Reset()
+	h.Write([]byte(data))
+
+	return h.Sum32()
+}
@@ -0,0 +1,244 @@
+package cdb
+
+import (
+	"encoding/binary"
+	"errors"
+	"hash"
+	"io"
+)
+
+// The v2 format extends the classic layout with a header placed before the table refs:
+//
+//	+------+---------+------------+-----------+-------+-------+-------+-----------+---------+
+//	| zero | magic   | headerSize | tableNum  | flags | align | bloom | valueSize | buckets |
+//	+------+---------+------------+-----------+-------+-------+-------+-----------+---------+
+//	  u32     u32        u32          u32       u32     u32     u32       u32        u32
+//
+// A database with the seeded hash extends the header by the SipHash key:
+//
+//	+-----+-----+-----+-----+
+//	| k0lo| k0hi| k1lo| k1hi|
+//	+-----+-----+-----+-----+
+//	  u32   u32   u32   u32
+//
+// The header is followed by tableNum hash table refs. With the 64-bit hash a slot is extended
+// by the upper half of the key hash:
+//
+//	+------+----------+--------+
+//	| hash | position | hashHi |
+//	+------+----------+--------+
+//	  u32     u32        u32
+//
+// A classic database never has a non empty table at the position 0, so a zero position followed by the magic unambiguously marks v2.
+// The headerSize field allows to append new fields: a reader ignores the fields it doesn't know,
+// and treats the missing ones as zero. All numbers are little endian, like in the classic format.
+const (
+	// Magic number of the v2 format, "cdb2" in little endian
+	v2Magic = 0x32626463
+	// Size of the smallest valid v2 header
+	v2MinHeaderSize = 20
+	// Size of the v2 header written by this package
+	v2HeaderSize = 36
+	// Size of the v2 header with the SipHash key of the seeded hash
+	v2SeededHeaderSize = v2HeaderSize + 16
+	// Upper bound of the v2 header size, protects from reading garbage
+	maxHeaderSize = 4096
+)
+
+// Bits of the v2 header flags
+const (
+	// Keys are stored with the prefix compression
+	flagPrefixCompression = 1 << iota
+	// Records have the expiry field
+	flagExpiry
+	// Records have the flags field
+	flagRecordFlags
+	// All values have the size of the header valueSize field, records have no value size field
+	flagFixedValueSize
+	// Records of the same key are placed into hash tables from the newest to the oldest one
+	flagVersioned
+	// Records have the bucket field
+	flagBuckets
+	// Keys are hashed by SipHash keyed with the header seed instead of the handle Hasher
+	flagSeededHash
+	// Slots keep the upper half of a 64-bit key hash after the position
+	flagHash64
+)
+
+// ErrInvalidHeader tells that the database header is malformed
+var ErrInvalidHeader = errors.New("Invalid db header, impossible to read hashTableRefs structures")
+
+// header describes the layout of a database
+type header struct {
+	// v2 is true for the v2 format
+	v2 bool
+	// size is the size of the header before the table refs, 0 for the classic format
+	size uint32
+	// tableNum is the number of hash tables
+	tableNum uint32
+	// flags is a bitmask of v2 features
+	flags uint32
+	// align is the alignment of record starts, 0 or 1 means no alignment
+	align uint32
+	// bloom is the position of the bloom filter trailer, 0 if there is no filter
+	bloom uint32
+	// valueSize is the size of every value with the fixed value size
+	valueSize uint32
+	// buckets is the position of the bucket directory trailer, 0 if there are no named buckets
+	buckets uint32
+	// seed is the SipHash key of the seeded hash
+	seed [2]uint64
+}
+
+// newHeader returns a header for a database written with the given options
+func newHeader(opts options) header {
+	if !opts.v2() {
+		return header{tableNum: tableNum}
+	}
+
+	h := header{
+		v2:        true,
+		size:      v2HeaderSize,
+		tableNum:  opts.tableNum,
+		flags:     opts.flags(),
+		align:     opts.align,
+		valueSize: opts.valueSize,
+	}
+
+	if opts.seededHash {
+		h.size = v2SeededHeaderSize
+	}
+
+	return h
+}
+
+// slotSize returns the size of a hash table slot in the database
+func (h *header) slotSize() uint32 {
+	if h.flags&flagHash64 != 0 {
+		return slotSize + hashHiSize
+	}
+
+	return slotSize
+}
+
+// keyHash returns the hash stored in slots and, with the 64-bit hash, its upper half
+// computed by the given hasher instance, which the key is written to
+func (h *header) keyHash(hashFunc hash.Hash32) (uint32, uint32) {
+	if h.flags&flagHash64 == 0 {
+		return hashFunc.Sum32(), 0
+	}
+
+	return hashFunc.Sum32(), uint32(hashFunc.(hash.Hash64).Sum64() >> 32)
+}
+
+// seededHasher returns the Hasher of the seeded hash, nil if the database uses the handle Hasher
+func (h *header) seededHasher() Hasher {
+	if h.flags&flagSeededHash == 0 {
+		return nil
+	}
+
+	return newSipHasher(h.seed[0], h.seed[1])
+}
+
+// refsPosition returns the position of the first hash table ref
+func (h *header) refsPosition() uint32 {
+	return h.size
+}
+
+// dataPosition returns the position of the first record
+func (h *header) dataPosition() uint32 {
+	return h.alignPosition(h.size + h.tableNum*8)
+}
+
+// alignPosition rounds the given position up to the record alignment
+func (h *header) alignPosition(pos uint32) uint32 {
+	if h.align <= 1 {
+		return pos
+	}
+
+	if r := pos % h.align; r != 0 {
+		pos += h.align - r
+	}
+
+	return pos
+}
+
+// startSlot returns the slot a probe for the given hash starts with in a table of n slots.
+// The classic format divides the hash by 256, v2 divides it by the table number,
+// so that the bits used to select the table are not reused to select the slot.
+func (h *header) startSlot(hash, n uint32) uint32 {
+	if !h.v2 {
+		return (hash >> 8) % n
+	}
+
+	return (hash / h.tableNum) % n
+}
+
+// readHeader reads a header from the beginning of the given reader
+func readHeader(reader io.ReaderAt) (header, error) {
+	buf := make([]byte, v2MinHeaderSize)
+
+	if _, err := reader.ReadAt(buf[:8], 0); err != nil {
+		return header{}, ErrInvalidHeader
+	}
+
+	if binary.LittleEndian.Uint32(buf) != 0 || binary.LittleEndian.Uint32(buf[4:]) != v2Magic {
+		return header{tableNum: tableNum}, nil
+	}
+
+	if _, err := reader.ReadAt(buf[:12], 0); err != nil {
+		return header{}, ErrInvalidHeader
+	}
+
+	size := binary.LittleEndian.Uint32(buf[8:])
+	if size < v2MinHeaderSize || size > maxHeaderSize {
+		return header{}, ErrInvalidHeader
+	}
+
+	// Unknown trailing fields are ignored, missing ones are left zero
+	buf = make([]byte, size)
+	if size < v2SeededHeaderSize {
+		buf = make([]byte, v2SeededHeaderSize)
+	}
+
+	if _, err := reader.ReadAt(buf[:size], 0); err != nil {
+		return header{}, ErrInvalidHeader
+	}
+
+	h := header{
+		v2:        true,
+		size:      size,
+		tableNum:  binary.LittleEndian.Uint32(buf[12:]),
+		flags:     binary.LittleEndian.Uint32(buf[16:]),
+		align:     binary.LittleEndian.Uint32(buf[20:]),
+		bloom:     binary.LittleEndian.Uint32(buf[24:]),
+		valueSize: binary.LittleEndian.Uint32(buf[28:]),
+		buckets:   binary.LittleEndian.Uint32(buf[32:]),
+		seed:      [2]uint64{binary.LittleEndian.Uint64(buf[36:]), binary.LittleEndian.Uint64(buf[44:])},
+	}
+
+	if h.tableNum == 0 || h.tableNum > maxTableNum || h.align > maxAlign {
+		return header{}, ErrInvalidHeader
+	}
+
+	if h.flags&flagSeededHash != 0 && size < v2SeededHeaderSize {
+		return header{}, ErrInvalidHeader
+	}
+
+	return h, nil
+}
+
+// write writes the header to the given writer. Nothing is written for the classic format.
+func (h *header) write(writer io.Writer) error {
+	if !h.v2 {
+		return nil
+	}
+
+	fields := []uint32{0, v2Magic, h.size, h.tableNum, h.flags, h.align, h.bloom, h.valueSize, h.buckets}
+
+	if h.size >= v2SeededHeaderSize {
+		fields = append(fields, uint32(h.seed[0]), uint32(h.seed[0]>>32), uint32(h.seed[1]), uint32(h.seed[1]>>32))
+	}
+
+	return binary.Write(writer, binary.LittleEndian, fields)
+}
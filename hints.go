@@ -0,0 +1,50 @@
+package cdb
+
+// SetExpectedRecords tells writers the expected number of records, so that hash tables and
+// the collections of keys are allocated once instead of growing during a large build.
+// The hint doesn't limit the number of records, 0 disables it. Like SetHash, it affects
+// only new instances of Writer.
+func (cdb *CDB) SetExpectedRecords(n int) {
+	cdb.opts.expectedRecords = n
+}
+
+// SetExpectedBytes tells writers the expected size of the database, so that the space of
+// a file is allocated up front (fallocate on Linux) and the file is not fragmented by
+// a large build. The size of the file is not changed, 0 disables the hint.
+// Like SetHash, it affects only new instances of Writer.
+func (cdb *CDB) SetExpectedBytes(n int64) {
+	cdb.opts.expectedBytes = n
+}
+
+// reserve preallocates the memory and the file space of the writer according to the size hints
+func (w *writerImpl) reserve(opts options) {
+	if opts.expectedBytes > 0 {
+		allocate(w.writer, w.begin, opts.expectedBytes)
+	}
+
+	n := opts.expectedRecords
+	if n <= 0 {
+		return
+	}
+
+	// Spilled slots don't stay in memory
+	slots := n
+	if w.spillLimit > 0 && w.spillLimit < slots {
+		slots = w.spillLimit
+	}
+
+	// Keys spread unevenly over tables, the slack saves most of the regrowths
+	perTable := slots/len(w.tables) + slots/len(w.tables)/8 + 1
+
+	for i := range w.tables {
+		w.tables[i] = make(hashTable, 0, perTable)
+	}
+
+	if w.versions > 0 {
+		w.history = make(map[string][]versionRef, n)
+	}
+
+	if w.seen != nil {
+		w.seen = make(map[string]versionRef, n)
+	}
+}
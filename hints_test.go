@@ -0,0 +1,27 @@
+package cdb
+
+import "io/ioutil"
+
+func (suite *CDBTestSuite) TestSizeHints() {
+	suite.fillTestCDB()
+
+	expected, err := ioutil.ReadFile(suite.cdbFile.Name())
+	suite.Require().Nil(err)
+
+	suite.resetTestCDB()
+	suite.cdbHandle.SetExpectedRecords(1000)
+	suite.cdbHandle.SetExpectedBytes(1 << 20)
+
+	writer := suite.getCDBWriter()
+	suite.GreaterOrEqual(cap(writer.(*writerImpl).tables[0]), 1000/256)
+
+	for _, rec := range suite.testRecords {
+		suite.Require().Nil(writer.Put(rec.key, rec.val))
+	}
+
+	suite.Require().Nil(writer.Close())
+
+	data, err := ioutil.ReadFile(suite.cdbFile.Name())
+	suite.Require().Nil(err)
+	suite.Equal(expected, data, "hints change neither the database nor the file size")
+}
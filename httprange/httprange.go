@@ -0,0 +1,89 @@
+// Package httprange implements io.ReaderAt over a file served by HTTP, using range requests.
+// It lets a cdb reader serve lookups from a database hosted on a CDN or an object store
+// without downloading it:
+//
+//	r, err := httprange.New(nil, "https://example.com/data.cdb")
+//	reader, err := cdb.New().GetReader(r)
+package httprange
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/mrsndmn/cdb/v2/objstore"
+)
+
+// ErrRangeNotSupported tells that the server ignores range requests
+var ErrRangeNotSupported = errors.New("httprange server doesn't support range requests")
+
+// ErrUnknownSize tells that the server doesn't report the size of the file
+var ErrUnknownSize = errors.New("httprange server doesn't report the file size")
+
+// ReaderAt implements io.ReaderAt over a file served by HTTP. The file is read by blocks,
+// recently read blocks are cached, see objstore.ReaderAt for the settings. Connections are
+// reused by the HTTP client. ReaderAt is safe for concurrent use, the file must not change while it is read.
+type ReaderAt struct {
+	*objstore.ReaderAt
+}
+
+// object implements objstore.Object by range requests
+type object struct {
+	client *http.Client
+	url    string
+}
+
+// New returns a ReaderAt over the file at the given URL. The size of the file is requested
+// by a HEAD request. A nil client means http.DefaultClient.
+func New(client *http.Client, url string) (*ReaderAt, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Head(url)
+	if err != nil {
+		return nil, err
+	}
+
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("httprange HEAD %s: %s", url, resp.Status)
+	}
+
+	if resp.ContentLength < 0 {
+		return nil, ErrUnknownSize
+	}
+
+	return &ReaderAt{objstore.New(&object{client, url}, resp.ContentLength)}, nil
+}
+
+// ReadRange requests len(p) bytes of the file starting from the given offset
+func (o *object) ReadRange(p []byte, off int64) error {
+	req, err := http.NewRequest(http.MethodGet, o.url, nil)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", off, off+int64(len(p))-1))
+
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		return ErrRangeNotSupported
+	}
+
+	if resp.StatusCode != http.StatusPartialContent {
+		return fmt.Errorf("httprange GET %s: %s", o.url, resp.Status)
+	}
+
+	_, err = io.ReadFull(resp.Body, p)
+
+	return err
+}
@@ -0,0 +1,104 @@
+package httprange
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mrsndmn/cdb/v2"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestServer(content []byte, requests *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		atomic.AddInt32(requests, 1)
+		http.ServeContent(w, req, "test.cdb", time.Time{}, bytes.NewReader(content))
+	}))
+}
+
+func TestReaderAt(t *testing.T) {
+	content := make([]byte, 1000)
+	for i := range content {
+		content[i] = byte(i)
+	}
+
+	var requests int32
+	server := newTestServer(content, &requests)
+	defer server.Close()
+
+	r, err := New(server.Client(), server.URL)
+	require.Nil(t, err)
+	require.Nil(t, r.SetBlockSize(100))
+	require.Equal(t, int64(len(content)), r.Size())
+
+	buf := make([]byte, 250)
+	n, err := r.ReadAt(buf, 175)
+	require.Nil(t, err)
+	require.Equal(t, 250, n)
+	require.Equal(t, content[175:425], buf)
+
+	fetched := atomic.LoadInt32(&requests)
+
+	n, err = r.ReadAt(buf[:10], 300)
+	require.Nil(t, err)
+	require.Equal(t, content[300:310], buf[:10])
+	require.Equal(t, fetched, atomic.LoadInt32(&requests), "Cached block must not be fetched again")
+
+	n, err = r.ReadAt(buf, 900)
+	require.Equal(t, io.EOF, err)
+	require.Equal(t, 100, n)
+	require.Equal(t, content[900:], buf[:n])
+
+	n, err = r.ReadAt(buf, 1000)
+	require.Equal(t, io.EOF, err)
+	require.Equal(t, 0, n)
+}
+
+func TestRangeNotSupported(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte("content"))
+	}))
+	defer server.Close()
+
+	r, err := New(server.Client(), server.URL)
+	require.Nil(t, err)
+
+	_, err = r.ReadAt(make([]byte, 1), 0)
+	require.Equal(t, ErrRangeNotSupported, err)
+}
+
+func TestCDBOverHTTP(t *testing.T) {
+	f, err := ioutil.TempFile("", "test_*.cdb")
+	require.Nil(t, err)
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	handle := cdb.New()
+	writer, err := handle.GetWriter(f)
+	require.Nil(t, err)
+	require.Nil(t, writer.Put([]byte("key"), []byte("value")))
+	require.Nil(t, writer.Close())
+
+	content, err := ioutil.ReadFile(f.Name())
+	require.Nil(t, err)
+
+	var requests int32
+	server := newTestServer(content, &requests)
+	defer server.Close()
+
+	r, err := New(server.Client(), server.URL)
+	require.Nil(t, err)
+
+	reader, err := handle.GetReader(r)
+	require.Nil(t, err)
+
+	value, err := reader.Get([]byte("key"))
+	require.Nil(t, err)
+	require.Equal(t, []byte("value"), value)
+}
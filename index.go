@@ -0,0 +1,404 @@
+package cdb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"sort"
+)
+
+// The sorted index is a sidecar stream, which lists positions of records sorted by key:
+//
+//	+-------+-------+------------+-----+------------------+
+//	| magic | count | position 0 | ... | position count-1 |
+//	+-------+-------+------------+-----+------------------+
+//	  u32     u32       u32                   u32
+//
+// Records with equal keys are listed in the insertion order. Keys are compared
+// with bytes.Compare and are read from the database itself, so the index stays small.
+const (
+	// Magic number of the sorted index, "cdbi" in little endian
+	indexMagic = 0x69626463
+	// Size of the sorted index header
+	indexHeaderSize = 8
+)
+
+// ErrNoIndex tells that an operation requires the sorted index, but the reader has none
+var ErrNoIndex = errors.New("cdb has no sorted index")
+
+// ErrInvalidIndex tells that the sorted index is malformed or doesn't belong to the database
+var ErrInvalidIndex = errors.New("Invalid sorted index")
+
+// Bounds tells which bounds of a key range belong to the range, see Reader.Iterate
+type Bounds uint8
+
+const (
+	// IncludeStart tells that records with the start key belong to the range
+	IncludeStart Bounds = 1 << iota
+	// IncludeEnd tells that records with the end key belong to the range
+	IncludeEnd
+	// IncludeBoth tells that the range is closed
+	IncludeBoth = IncludeStart | IncludeEnd
+	// ExcludeBoth tells that the range is open
+	ExcludeBoth Bounds = 0
+)
+
+// indexEntry is a record reference collected by a writer for the sorted index
+type indexEntry struct {
+	key      []byte
+	position uint32
+}
+
+// sortedIndex provides access to the sorted index
+type sortedIndex struct {
+	reader io.ReaderAt
+	count  int
+}
+
+// writeIndex sorts the given entries and writes the sorted index to the given writer
+func writeIndex(writer io.Writer, entries []indexEntry) error {
+	sort.SliceStable(entries, func(i, j int) bool {
+		return bytes.Compare(entries[i].key, entries[j].key) < 0
+	})
+
+	buf := make([]uint32, indexHeaderSize/4+len(entries))
+	buf[0], buf[1] = indexMagic, uint32(len(entries))
+
+	for i, entry := range entries {
+		buf[2+i] = entry.position
+	}
+
+	return binary.Write(writer, binary.LittleEndian, buf)
+}
+
+// readIndex reads the header of the sorted index
+func readIndex(reader io.ReaderAt) (*sortedIndex, error) {
+	buf := make([]byte, indexHeaderSize)
+
+	if _, err := reader.ReadAt(buf, 0); err != nil {
+		return nil, ErrInvalidIndex
+	}
+
+	if binary.LittleEndian.Uint32(buf) != indexMagic {
+		return nil, ErrInvalidIndex
+	}
+
+	return &sortedIndex{
+		reader: reader,
+		count:  int(binary.LittleEndian.Uint32(buf[4:])),
+	}, nil
+}
+
+// position returns the position of the i-th record in the key order
+func (s *sortedIndex) position(i int) (uint32, error) {
+	buf := make([]byte, 4)
+
+	if _, err := s.reader.ReadAt(buf, int64(indexHeaderSize+i*4)); err != nil {
+		return 0, err
+	}
+
+	return binary.LittleEndian.Uint32(buf), nil
+}
+
+// searchIndex returns the number of the first record in the key order, which key is not less than the given one
+func (r *readerImpl) searchIndex(key []byte) (int, error) {
+	return r.searchIndexFrom(key, 0)
+}
+
+// searchIndexAfter returns the number of the first record in the key order, which key is greater than the given one
+func (r *readerImpl) searchIndexAfter(key []byte) (int, error) {
+	return r.searchIndexFrom(key, 1)
+}
+
+// searchIndexFrom returns the number of the first record in the key order, which key compared
+// with the given one by bytes.Compare gives at least c
+func (r *readerImpl) searchIndexFrom(key []byte, c int) (int, error) {
+	lo, hi := 0, r.index.count
+
+	for lo < hi {
+		mid := int(uint(lo+hi) >> 1)
+
+		pos, err := r.index.position(mid)
+		if err != nil {
+			return 0, err
+		}
+
+		layout, err := r.readRecord(pos)
+		if err != nil {
+			return 0, err
+		}
+
+		midKey, err := r.readKey(layout)
+		if err != nil {
+			return 0, err
+		}
+
+		if bytes.Compare(midKey, key) < c {
+			lo = mid + 1
+		} else {
+			hi = mid
+		}
+	}
+
+	return lo, nil
+}
+
+// Range returns a new Iterator object, which walks records with keys in range [start, end)
+// in the key order. A nil start or end means that the range is not bounded from that side,
+// so Range(nil, nil) walks all records in the key order. Returns nil if there is no such record.
+// Requires the sorted index, see CDB.GetReaderWithIndex.
+func (r *readerImpl) Range(start, end []byte) (Iterator, error) {
+	return r.Iterate(start, end, IncludeStart)
+}
+
+// Iterate returns a new Iterator object, which walks records with keys between start and end
+// in the key order, see Reader.Iterate. Requires the sorted index, see CDB.GetReaderWithIndex.
+func (r *readerImpl) Iterate(start, end []byte, bounds Bounds) (Iterator, error) {
+	if r.index == nil {
+		return nil, ErrNoIndex
+	}
+
+	var (
+		from, to = 0, r.index.count
+		err      error
+	)
+
+	if start != nil {
+		if bounds&IncludeStart != 0 {
+			from, err = r.searchIndex(start)
+		} else {
+			from, err = r.searchIndexAfter(start)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if end != nil {
+		if bounds&IncludeEnd != 0 {
+			to, err = r.searchIndexAfter(end)
+		} else {
+			to, err = r.searchIndex(end)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	iterator, err := r.indexIterator(from, to, false)
+	if err != nil {
+		return nil, err
+	}
+
+	// No record is in the range
+	if iterator == nil {
+		return nil, ErrEntryNotFound
+	}
+
+	return iterator, nil
+}
+
+// OrderedIterator returns a new Iterator object, which walks all records in the key order.
+// Requires the sorted index, see CDB.GetReaderWithIndex.
+func (r *readerImpl) OrderedIterator() (Iterator, error) {
+	return r.orderedIterator(false)
+}
+
+// ReverseIterator returns a new Iterator object, which walks all records in the reverse key order.
+// Requires the sorted index, see CDB.GetReaderWithIndex.
+func (r *readerImpl) ReverseIterator() (Iterator, error) {
+	return r.orderedIterator(true)
+}
+
+// orderedIterator implements OrderedIterator and ReverseIterator
+func (r *readerImpl) orderedIterator(reverse bool) (Iterator, error) {
+	if r.index == nil {
+		return nil, ErrNoIndex
+	}
+
+	iterator, err := r.indexIterator(0, r.index.count, reverse)
+	if err != nil {
+		return nil, err
+	}
+
+	// Every record is expired
+	if iterator == nil {
+		return nil, ErrEmptyCDB
+	}
+
+	return iterator, nil
+}
+
+// indexIterator returns a new indexIterator, which points on the first record in the range [from, to)
+// of the sorted index. Returns nil if there is no such record.
+func (r *readerImpl) indexIterator(from, to int, reverse bool) (*indexIterator, error) {
+	if from >= to {
+		return nil, nil
+	}
+
+	base, err := r.newIterator(0, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	iterator := &indexIterator{
+		iterator: base,
+		next:     from,
+		start:    from,
+		end:      to,
+		reverse:  reverse,
+	}
+
+	if reverse {
+		iterator.next = to - 1
+	}
+
+	ok, err := iterator.Next()
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	iterator.mark()
+
+	return iterator, nil
+}
+
+// indexIterator implements Iterator interface, walks records in the order of the sorted index
+type indexIterator struct {
+	*iterator
+	// next is the number of the next record in the key order, start and end are the numbers
+	// of the first record and the record after the last one
+	next, start, end int
+	// reverse tells that records are walked from the end to the start
+	reverse bool
+	// origin is the number of the next record, when the iterator was created
+	origin int
+}
+
+// mark remembers the current state of the iterator as the one restored by Reset
+func (i *indexIterator) mark() {
+	i.iterator.mark()
+	i.origin = i.next
+}
+
+// Reset moves the iterator back to the record it pointed on when it was created, see Iterator.Reset
+func (i *indexIterator) Reset() error {
+	i.next = i.origin
+
+	return i.iterator.Reset()
+}
+
+// Seek moves the iterator to the first record associated with the given key in the order of the iterator,
+// so Next continues in that order. Returns ErrEntryNotFound if the key is out of the range of the iterator.
+func (i *indexIterator) Seek(key []byte) error {
+	n, err := i.cdbReader.searchIndex(key)
+	if err != nil {
+		return err
+	}
+
+	if n < i.start {
+		n = i.start
+	}
+
+	var (
+		found  recordLayout
+		number = -1
+	)
+
+	// Records with equal keys are listed in the insertion order, the reverse order starts from the last one
+	for ; n < i.end; n++ {
+		pos, err := i.cdbReader.index.position(n)
+		if err != nil {
+			return err
+		}
+
+		layout, err := i.cdbReader.readRecord(pos)
+		if err != nil {
+			return err
+		}
+
+		recordKey, err := i.cdbReader.readKey(layout)
+		if err != nil {
+			return err
+		}
+
+		if !bytes.Equal(recordKey, key) {
+			break
+		}
+
+		if i.cdbReader.skip(layout) {
+			continue
+		}
+
+		found, number = layout, n
+
+		if !i.reverse {
+			break
+		}
+	}
+
+	if number < 0 {
+		return ErrEntryNotFound
+	}
+
+	if err := i.setRecord(found, nil); err != nil {
+		return err
+	}
+
+	if i.reverse {
+		i.next = number - 1
+	} else {
+		i.next = number + 1
+	}
+
+	return nil
+}
+
+// Next moves the iterator to the next record in the key order. Returns true on success otherwise returns false.
+func (i *indexIterator) Next() (bool, error) {
+	if i.err != nil {
+		return false, i.err
+	}
+
+	for i.HasNext() {
+		pos, err := i.cdbReader.index.position(i.next)
+		if err != nil {
+			return i.fail(err)
+		}
+
+		if i.reverse {
+			i.next--
+		} else {
+			i.next++
+		}
+
+		layout, err := i.cdbReader.readRecord(pos)
+		if err != nil {
+			return i.fail(err)
+		}
+
+		if i.cdbReader.skip(layout) {
+			continue
+		}
+
+		if err := i.setRecord(layout, nil); err != nil {
+			return i.fail(err)
+		}
+
+		return true, nil
+	}
+
+	return false, nil
+}
+
+// HasNext tells if the iterator can be moved to the next record.
+func (i *indexIterator) HasNext() bool {
+	if i.reverse {
+		return i.next >= i.start
+	}
+
+	return i.next < i.end
+}
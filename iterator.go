@@ -1,6 +1,7 @@
 package cdb
 
 import (
+	"bytes"
 	"errors"
 	"io"
 )
@@ -9,9 +10,41 @@ var ErrEmptyCDB = errors.New("cdb is empty")
 
 // iterator implements Iterator interface
 type iterator struct {
-	position  uint32
+	position uint32
+	// start is the position, which the iterator walks from, end is the position after the last record
+	start, end uint32
+	// current is the position of the current record
+	current   uint32
 	cdbReader *readerImpl
 	record    *record
+	// meta is the meta fields of the current record
+	meta recordMeta
+	// keysOnly tells that keys are read together with record headers, see Reader.KeyIterator
+	keysOnly bool
+	// err is the error, which stopped the iterator
+	err error
+	// counting tells that the iterator walks the data section from its start, so next is
+	// the sequence number of the record at position, see Iterator.Sequence
+	counting bool
+	next     uint32
+	// sequence is the sequence number of the current record, if sequenced is set
+	sequence  uint32
+	sequenced bool
+	// records is the number of records the iterator has pointed on, see Iterator.Position
+	records int
+	// origin is the state of the iterator, when it was created, see Iterator.Reset
+	origin *iteratorState
+}
+
+// iteratorState is the state of an iterator, which is restored by Reset
+type iteratorState struct {
+	position, current uint32
+	meta              recordMeta
+	key, value        sectionReaderFactory
+	counting          bool
+	next, sequence    uint32
+	sequenced         bool
+	records           int
 }
 
 // record implements Record interface
@@ -24,6 +57,11 @@ type record struct {
 type sectionReaderFactory struct {
 	reader         io.ReaderAt
 	position, size uint32
+	// data is the value, if it is already read
+	data []byte
+	// record is the position of the record of a found value, meta is its meta fields
+	record uint32
+	meta   recordMeta
 }
 
 // create returns a new instance of SectionReader
@@ -45,28 +83,196 @@ func readSection(readerAt io.ReaderAt, position int64, size uint32) ([]byte, err
 }
 
 // Next moves the iterator to the next record. Returns true on success otherwise returns false.
+// A reader in the skip expired mode skips expired records, see CDB.SetSkipExpired.
 func (i *iterator) Next() (bool, error) {
-	if !i.HasNext() {
-		return false, nil
+	if i.err != nil {
+		return false, i.err
 	}
 
-	var keySize, valSize uint32
+	var (
+		layout recordLayout
+		key    []byte
+		err    error
+	)
+
+	for {
+		if !i.HasNext() {
+			return false, nil
+		}
+
+		if i.keysOnly {
+			layout, key, err = i.cdbReader.readRecordKey(i.position)
+		} else {
+			layout, err = i.cdbReader.readRecord(i.position)
+		}
+
+		if err != nil {
+			return i.fail(err)
+		}
+
+		if !i.cdbReader.skip(layout) {
+			break
+		}
 
-	if err := i.cdbReader.readPair(i.position, &keySize, &valSize); err != nil {
-		return false, err
+		i.position = i.cdbReader.header.alignPosition(layout.end())
+		i.next++
 	}
 
-	i.record.keySectionFactory.position = i.position + 8
-	i.record.keySectionFactory.size = keySize
+	if err := i.setRecord(layout, key); err != nil {
+		return i.fail(err)
+	}
 
-	i.record.valueSectionFactory.position = i.position + 8 + keySize
-	i.record.valueSectionFactory.size = valSize
+	if i.counting {
+		i.sequence, i.sequenced = i.next, true
+	}
 
-	i.position += keySize + valSize + 8
+	i.next++
+
+	i.position = i.cdbReader.header.alignPosition(layout.end())
 
 	return true, nil
 }
 
+// fail stops the iterator with the given error
+func (i *iterator) fail(err error) (bool, error) {
+	i.err = err
+	return false, err
+}
+
+// Err returns the error, which stopped the iterator, see Iterator.Err
+func (i *iterator) Err() error {
+	return i.err
+}
+
+// Seek moves the iterator to the first record associated with the given key, see Iterator.Seek
+func (i *iterator) Seek(key []byte) error {
+	section, err := i.cdbReader.findEntry(key)
+	if err != nil {
+		return err
+	}
+
+	if section == nil {
+		return ErrEntryNotFound
+	}
+
+	layout, err := i.cdbReader.readRecord(section.record)
+	if err != nil {
+		return err
+	}
+
+	if err := i.setRecord(layout, nil); err != nil {
+		return err
+	}
+
+	i.position = i.cdbReader.header.alignPosition(layout.end())
+	i.counting = false
+
+	return nil
+}
+
+// mark remembers the current state of the iterator as the one restored by Reset
+func (i *iterator) mark() {
+	i.origin = &iteratorState{
+		position:  i.position,
+		current:   i.current,
+		meta:      i.meta,
+		key:       *i.record.keySectionFactory,
+		value:     *i.record.valueSectionFactory,
+		counting:  i.counting,
+		next:      i.next,
+		sequence:  i.sequence,
+		sequenced: i.sequenced,
+		records:   i.records,
+	}
+}
+
+// Reset moves the iterator back to the record it pointed on when it was created, see Iterator.Reset
+func (i *iterator) Reset() error {
+	s := i.origin
+
+	i.position, i.current, i.meta = s.position, s.current, s.meta
+	*i.record.keySectionFactory, *i.record.valueSectionFactory = s.key, s.value
+	i.counting, i.next, i.sequence, i.sequenced = s.counting, s.next, s.sequence, s.sequenced
+	i.records = s.records
+	i.err = nil
+
+	return nil
+}
+
+// Sequence returns the sequence number of the current record, see Iterator.Sequence.
+// It is counted while the iterator walks from the start of the data section,
+// otherwise record headers are walked up to the current record once.
+func (i *iterator) Sequence() (uint32, error) {
+	if i.sequenced || i.current == 0 {
+		return i.sequence, nil
+	}
+
+	r := i.cdbReader
+	var n uint32
+
+	for pos := r.header.dataPosition(); pos < i.current; n++ {
+		layout, err := r.readRecord(pos)
+		if err != nil {
+			return 0, err
+		}
+
+		pos = r.header.alignPosition(layout.end())
+
+		if pos > i.current {
+			return 0, corrupted(nil, int64(i.current), -1, "record is not on the record boundary")
+		}
+	}
+
+	i.sequence, i.sequenced = n, true
+
+	return n, nil
+}
+
+// setRecord points the current record to the given one. key is the full key of the record,
+// if it is already read, otherwise nil.
+func (i *iterator) setRecord(layout recordLayout, key []byte) error {
+	if err := i.setKey(layout, key); err != nil {
+		return err
+	}
+
+	i.record.valueSectionFactory.position = layout.valPosition
+	i.record.valueSectionFactory.size = layout.valSize
+	i.meta = layout.recordMeta
+	i.current = layout.position
+	i.sequenced = false
+	i.records++
+
+	return nil
+}
+
+// setKey points the key section of the current record to the key of the given record.
+// A compressed key is restored in memory, otherwise it is read lazily from the database.
+func (i *iterator) setKey(layout recordLayout, key []byte) error {
+	keyFactory := i.record.keySectionFactory
+
+	if key == nil && !layout.compressed() {
+		keyFactory.reader = i.cdbReader.reader
+		keyFactory.position = layout.keyPosition
+		keyFactory.size = layout.keySize
+
+		return nil
+	}
+
+	if key == nil {
+		var err error
+
+		if key, err = i.cdbReader.readKey(layout); err != nil {
+			return err
+		}
+	}
+
+	keyFactory.reader = bytes.NewReader(key)
+	keyFactory.position = 0
+	keyFactory.size = layout.keySize
+
+	return nil
+}
+
 // Key returns key's []byte slice. It is usually easier to use and
 // faster then iterator.Record().Key(). Because it doesn't requiers allocation for SectionReader
 func (i *iterator) Key() ([]byte, error) {
@@ -97,12 +303,22 @@ func (i *iterator) Record() Record {
 	}
 }
 
+// Flags returns the raw flags of the current record.
+func (i *iterator) Flags() RecordFlags {
+	return i.meta.flags
+}
+
+// Offset returns the offset of the current record relative to the database start, see Reader.GetAt.
+func (i *iterator) Offset() uint32 {
+	return i.current
+}
+
 // HasNext tells if the iterator can be moved to the next record.
 func (i *iterator) HasNext() bool {
 	if i.cdbReader.IsEmpty() {
 		return false
 	}
-	return i.position < i.cdbReader.endPos
+	return i.position < i.end
 }
 
 // Key returns io.Reader with given record's key and key size.
@@ -1,9 +1,12 @@
 package cdb
 
 import (
+	"bytes"
+	"io/ioutil"
 	"os"
 	"strconv"
 	"testing"
+	"time"
 )
 
 func (suite *CDBTestSuite) getCDBIterator() (Iterator, error) {
@@ -105,6 +108,426 @@ func (suite *CDBTestSuite) TestIteratorAt() {
 	}
 }
 
+func (suite *CDBTestSuite) TestIteratorSeek() {
+	suite.fillTestCDB()
+	iterator := suite.mustGetCDBIterator()
+
+	for i := len(suite.testRecords) - 1; i >= 0; i-- {
+		suite.Require().Nil(iterator.Seek(suite.testRecords[i].key))
+		suite.EqualKeyValue(iterator, suite.testRecords[i])
+
+		for _, testRec := range suite.testRecords[i+1:] {
+			suite.mustNext(iterator)
+			suite.EqualKeyValue(iterator, testRec)
+		}
+
+		suite.False(iterator.HasNext())
+	}
+
+	suite.Require().Nil(iterator.Seek(suite.testRecords[0].key))
+	suite.Equal(ErrEntryNotFound, iterator.Seek([]byte("missing key")))
+	suite.EqualKeyValue(iterator, suite.testRecords[0])
+}
+
+func (suite *CDBTestSuite) TestIterateBounds() {
+	keys := []string{"d", "b", "a", "c", "b", "e"}
+
+	index := &bytes.Buffer{}
+	writer, err := suite.cdbHandle.GetWriterWithIndex(suite.cdbFile, index)
+	suite.Require().Nil(err)
+
+	for i, key := range keys {
+		suite.Require().Nil(writer.Put([]byte(key), []byte(strconv.Itoa(i))))
+	}
+
+	suite.Require().Nil(writer.Close())
+
+	_, err = suite.getCDBReader().Iterate(nil, nil, IncludeBoth)
+	suite.Equal(ErrNoIndex, err)
+
+	reader, err := suite.cdbHandle.GetReaderWithIndex(suite.cdbFile, bytes.NewReader(index.Bytes()))
+	suite.Require().Nil(err)
+
+	cases := []struct {
+		start, end []byte
+		bounds     Bounds
+		expected   []string
+	}{
+		{[]byte("b"), []byte("d"), IncludeStart, []string{"b1", "b4", "c3"}},
+		{[]byte("b"), []byte("d"), IncludeEnd, []string{"c3", "d0"}},
+		{[]byte("b"), []byte("d"), IncludeBoth, []string{"b1", "b4", "c3", "d0"}},
+		{[]byte("b"), []byte("d"), ExcludeBoth, []string{"c3"}},
+		{[]byte("b"), []byte("c"), ExcludeBoth, nil},
+		{[]byte("c"), []byte("c"), IncludeBoth, []string{"c3"}},
+		{nil, []byte("b"), IncludeEnd, []string{"a2", "b1", "b4"}},
+		{[]byte("d"), nil, ExcludeBoth, []string{"e5"}},
+	}
+
+	for _, c := range cases {
+		iterator, err := reader.Iterate(c.start, c.end, c.bounds)
+		if c.expected == nil {
+			suite.Equal(ErrEntryNotFound, err, "Iterate(%q, %q, %d)", c.start, c.end, c.bounds)
+			continue
+		}
+
+		suite.Require().Nil(err)
+
+		var actual []string
+
+		for ok := true; ok; ok = suite.mustNext(iterator) {
+			key, _ := iterator.Key()
+			value, _ := iterator.Value()
+			actual = append(actual, string(key)+string(value))
+		}
+
+		suite.Equal(c.expected, actual, "Iterate(%q, %q, %d)", c.start, c.end, c.bounds)
+	}
+}
+
+func (suite *CDBTestSuite) TestOrderedIterator() {
+	keys := []string{"d", "b", "a", "c", "b", "e"}
+
+	index := &bytes.Buffer{}
+	writer, err := suite.cdbHandle.GetWriterWithIndex(suite.cdbFile, index)
+	suite.Require().Nil(err)
+
+	for i, key := range keys {
+		suite.Require().Nil(writer.Put([]byte(key), []byte(strconv.Itoa(i))))
+	}
+
+	suite.Require().Nil(writer.Close())
+
+	_, err = suite.getCDBReader().OrderedIterator()
+	suite.Equal(ErrNoIndex, err)
+
+	_, err = suite.getCDBReader().ReverseIterator()
+	suite.Equal(ErrNoIndex, err)
+
+	reader, err := suite.cdbHandle.GetReaderWithIndex(suite.cdbFile, bytes.NewReader(index.Bytes()))
+	suite.Require().Nil(err)
+
+	walk := func(iterator Iterator) []string {
+		var actual []string
+
+		for ok := true; ok; ok = suite.mustNext(iterator) {
+			key, _ := iterator.Key()
+			value, _ := iterator.Value()
+			actual = append(actual, string(key)+string(value))
+		}
+
+		return actual
+	}
+
+	iterator, err := reader.OrderedIterator()
+	suite.Require().Nil(err)
+	suite.Equal([]string{"a2", "b1", "b4", "c3", "d0", "e5"}, walk(iterator))
+
+	iterator, err = reader.ReverseIterator()
+	suite.Require().Nil(err)
+	suite.Equal([]string{"e5", "d0", "c3", "b4", "b1", "a2"}, walk(iterator))
+
+	suite.Require().Nil(iterator.Seek([]byte("b")))
+	suite.Equal([]string{"b4", "b1", "a2"}, walk(iterator))
+}
+
+func (suite *CDBTestSuite) TestRangeSeek() {
+	keys := []string{"d", "b", "a", "c", "b", "e"}
+
+	index := &bytes.Buffer{}
+	writer, err := suite.cdbHandle.GetWriterWithIndex(suite.cdbFile, index)
+	suite.Require().Nil(err)
+
+	for i, key := range keys {
+		suite.Require().Nil(writer.Put([]byte(key), []byte(strconv.Itoa(i))))
+	}
+
+	suite.Require().Nil(writer.Close())
+
+	reader, err := suite.cdbHandle.GetReaderWithIndex(suite.cdbFile, bytes.NewReader(index.Bytes()))
+	suite.Require().Nil(err)
+
+	iterator, err := reader.Range([]byte("b"), []byte("e"))
+	suite.Require().Nil(err)
+
+	suite.Require().Nil(iterator.Seek([]byte("c")))
+	suite.EqualKeyValue(iterator, testCDBRecord{[]byte("c"), []byte("3")})
+	suite.mustNext(iterator)
+	suite.EqualKeyValue(iterator, testCDBRecord{[]byte("d"), []byte("0")})
+
+	suite.Require().Nil(iterator.Seek([]byte("b")))
+	suite.EqualKeyValue(iterator, testCDBRecord{[]byte("b"), []byte("1")})
+	suite.mustNext(iterator)
+	suite.EqualKeyValue(iterator, testCDBRecord{[]byte("b"), []byte("4")})
+
+	suite.Equal(ErrEntryNotFound, iterator.Seek([]byte("a")))
+	suite.Equal(ErrEntryNotFound, iterator.Seek([]byte("e")))
+	suite.EqualKeyValue(iterator, testCDBRecord{[]byte("b"), []byte("4")})
+}
+
+func (suite *CDBTestSuite) TestKeyIterator() {
+	suite.cdbHandle.SetKeyPrefixCompression(true)
+
+	records := []testCDBRecord{
+		{[]byte("users/alice"), bytes.Repeat([]byte("a"), 1<<12)},
+		{[]byte("users/alice/avatar"), []byte("png")},
+		{bytes.Repeat([]byte("k"), 1<<10), []byte("long key")},
+		{[]byte("empty"), nil},
+	}
+
+	writer := suite.getCDBWriter()
+	for _, rec := range records {
+		suite.Require().Nil(writer.Put(rec.key, rec.val))
+	}
+	suite.Require().Nil(writer.Close())
+
+	counter := &countingReaderAt{ReaderAt: suite.cdbFile}
+	reader, err := suite.cdbHandle.GetReader(counter)
+	suite.Require().Nil(err)
+
+	counter.reads = 0
+	iterator, err := reader.KeyIterator()
+	suite.Require().Nil(err)
+
+	for i, rec := range records {
+		key, err := iterator.Key()
+		suite.Nil(err)
+		suite.Equal(rec.key, key)
+
+		ok := suite.mustNext(iterator)
+		suite.Equal(i < len(records)-1, ok)
+	}
+
+	// The compressed and the long keys take extra reads
+	suite.Equal(len(records)+2, counter.reads)
+
+	iterator, err = reader.KeyIterator()
+	suite.Require().Nil(err)
+	suite.EqualKeyValue(iterator, records[0])
+}
+
+func (suite *CDBTestSuite) TestIteratorReadsValuesLazily() {
+	value := make([]byte, 1<<20)
+
+	writer := suite.getCDBWriter()
+	suite.Require().Nil(writer.Put([]byte("large"), value))
+	suite.Require().Nil(writer.Put([]byte("small"), []byte("value")))
+	suite.Require().Nil(writer.Close())
+
+	counter := &bytesCountingReaderAt{ReaderAt: suite.cdbFile}
+	reader, err := suite.cdbHandle.GetReader(counter)
+	suite.Require().Nil(err)
+
+	for _, open := range []func() (Iterator, error){reader.Iterator, reader.KeyIterator} {
+		counter.bytes = 0
+
+		iterator, err := open()
+		suite.Require().Nil(err)
+
+		for ok := true; ok; ok = suite.mustNext(iterator) {
+			_, err := iterator.Key()
+			suite.Nil(err)
+		}
+
+		suite.True(counter.bytes < 2048, "Keys must be iterated without values, read %d bytes", counter.bytes)
+
+		iterator, err = open()
+		suite.Require().Nil(err)
+
+		valueReader, size := iterator.Record().Value()
+		suite.Equal(uint32(len(value)), size)
+		suite.True(counter.bytes < 4096, "Record must not read the value, read %d bytes", counter.bytes)
+
+		read, err := ioutil.ReadAll(valueReader)
+		suite.Nil(err)
+		suite.Equal(value, read)
+	}
+}
+
+func (suite *CDBTestSuite) TestIteratorErr() {
+	suite.fillTestCDB()
+
+	iterator := suite.mustGetCDBIterator()
+	suite.mustNext(iterator)
+	offset := iterator.Offset()
+
+	for iterator.HasNext() {
+		suite.mustNext(iterator)
+	}
+	suite.Nil(iterator.Err(), "The end of data is not an error")
+
+	// The key size of the second record points past the data section
+	_, err := suite.cdbFile.WriteAt([]byte{0xff, 0xff, 0xff, 0xff}, int64(offset))
+	suite.Require().Nil(err)
+
+	iterator = suite.mustGetCDBIterator()
+	ok, err := iterator.Next()
+	suite.False(ok)
+	suite.IsType(&CorruptionError{}, err)
+	suite.Equal(err, iterator.Err())
+
+	ok, err = iterator.Next()
+	suite.False(ok)
+	suite.Equal(iterator.Err(), err, "The iterator must stay stopped")
+}
+
+func (suite *CDBTestSuite) TestIteratorFlags() {
+	suite.cdbHandle.SetExpiry(true)
+	suite.cdbHandle.SetRecordFlags(true)
+
+	writer := suite.getCDBWriter()
+	suite.Require().Nil(writer.Put([]byte("key1"), []byte("val1")))
+	suite.Require().Nil(writer.PutWithExpiry([]byte("key2"), []byte("val2"), time.Now().Add(time.Hour)))
+	suite.Require().Nil(writer.(*writerImpl).put([]byte("key3"), []byte("val3"), recordMeta{flags: RecordTombstone | RecordCompressed}))
+	suite.Require().Nil(writer.Close())
+
+	iterator := suite.mustGetCDBIterator()
+	suite.Equal(RecordFlags(0), iterator.Flags())
+
+	iterator.Next()
+	suite.Equal(RecordHasTTL, iterator.Flags())
+
+	iterator.Next()
+	suite.Equal(RecordTombstone|RecordCompressed, iterator.Flags())
+	suite.EqualKeyValue(iterator, testCDBRecord{[]byte("key3"), []byte("val3")})
+}
+
+func (suite *CDBTestSuite) TestIteratorSequence() {
+	suite.cdbHandle.SetVersions(1)
+
+	keys := []string{"d", "b", "a", "b", "c"}
+	writer := suite.getCDBWriter()
+
+	for i, key := range keys {
+		suite.Require().Nil(writer.Put([]byte(key), []byte(strconv.Itoa(i))))
+	}
+
+	suite.Require().Nil(writer.Close())
+
+	reader := suite.getCDBReader()
+	iterator, err := reader.Iterator()
+	suite.Require().Nil(err)
+
+	var actual []string
+
+	for ok := true; ok; ok = suite.mustNext(iterator) {
+		key, _ := iterator.Key()
+		value, _ := iterator.Value()
+		seq, err := iterator.Sequence()
+		suite.Require().Nil(err)
+		suite.Equal(value, []byte(strconv.Itoa(int(seq))))
+		actual = append(actual, string(key)+string(value))
+	}
+
+	// The first "b" is superseded, but it keeps its sequence number
+	suite.Equal([]string{"d0", "a2", "b3", "c4"}, actual)
+
+	iterator, err = reader.Iterator()
+	suite.Require().Nil(err)
+	suite.Require().Nil(iterator.Seek([]byte("a")))
+
+	seq, err := iterator.Sequence()
+	suite.Nil(err)
+	suite.Equal(uint32(2), seq)
+
+	suite.mustNext(iterator)
+	seq, err = iterator.Sequence()
+	suite.Nil(err)
+	suite.Equal(uint32(3), seq)
+
+	iterator, err = reader.IteratorAt([]byte("c"))
+	suite.Require().Nil(err)
+	seq, err = iterator.Sequence()
+	suite.Nil(err)
+	suite.Equal(uint32(4), seq)
+}
+
+func (suite *CDBTestSuite) TestIteratorReset() {
+	suite.fillTestCDB()
+	reader := suite.getCDBReader()
+
+	iterator, err := reader.Iterator()
+	suite.Require().Nil(err)
+
+	walked := suite.restOf(iterator)
+	suite.Len(walked, len(suite.testRecords))
+	suite.False(iterator.HasNext())
+
+	suite.Require().Nil(iterator.Reset())
+	suite.EqualKeyValue(iterator, suite.testRecords[0])
+
+	seq, err := iterator.Sequence()
+	suite.Nil(err)
+	suite.Equal(uint32(0), seq)
+	suite.Equal(walked, suite.restOf(iterator))
+
+	// Seek doesn't change the record the iterator is reset to
+	suite.Require().Nil(iterator.Seek(suite.testRecords[3].key))
+	suite.Require().Nil(iterator.Reset())
+	suite.Equal(walked, suite.restOf(iterator))
+
+	iterator, err = reader.IteratorAt(suite.testRecords[2].key)
+	suite.Require().Nil(err)
+	suite.mustNext(iterator)
+	suite.Require().Nil(iterator.Reset())
+	suite.EqualKeyValue(iterator, suite.testRecords[2])
+	suite.Equal(walked[2:], suite.restOf(iterator))
+}
+
+func (suite *CDBTestSuite) TestRange() {
+	keys := []string{"d", "b", "a", "c", "b", "e"}
+
+	index := &bytes.Buffer{}
+	writer, err := suite.cdbHandle.GetWriterWithIndex(suite.cdbFile, index)
+	suite.Require().Nil(err)
+
+	for i, key := range keys {
+		suite.Require().Nil(writer.Put([]byte(key), []byte(strconv.Itoa(i))))
+	}
+
+	suite.Require().Nil(writer.Close())
+
+	_, err = suite.getCDBReader().Range(nil, nil)
+	suite.Equal(ErrNoIndex, err)
+
+	reader, err := suite.cdbHandle.GetReaderWithIndex(suite.cdbFile, bytes.NewReader(index.Bytes()))
+	suite.Require().Nil(err)
+
+	cases := []struct {
+		start, end []byte
+		expected   []string
+	}{
+		{nil, nil, []string{"a2", "b1", "b4", "c3", "d0", "e5"}},
+		{[]byte("b"), []byte("d"), []string{"b1", "b4", "c3"}},
+		{[]byte("bb"), nil, []string{"c3", "d0", "e5"}},
+		{nil, []byte("b"), []string{"a2"}},
+		{[]byte("x"), nil, nil},
+	}
+
+	for _, c := range cases {
+		iterator, err := reader.Range(c.start, c.end)
+		if c.expected == nil {
+			suite.Equal(ErrEntryNotFound, err, "Range(%q, %q)", c.start, c.end)
+			continue
+		}
+
+		suite.Require().Nil(err)
+
+		var actual []string
+
+		for {
+			key, _ := iterator.Key()
+			value, _ := iterator.Value()
+			actual = append(actual, string(key)+string(value))
+
+			if ok, err := iterator.Next(); err != nil || !ok {
+				break
+			}
+		}
+
+		suite.Equal(c.expected, actual, "Range(%q, %q)", c.start, c.end)
+	}
+}
+
 func BenchmarkIteratorAt(b *testing.B) {
 
 	n := 1000
@@ -201,3 +624,59 @@ func getTestCDBIterator(b *testing.B) (Iterator, *os.File) {
 
 	return iter, f
 }
+
+func (suite *CDBTestSuite) TestGetAt() {
+	suite.fillTestCDB()
+
+	reader := suite.getCDBReader()
+	iterator, err := reader.Iterator()
+	suite.Require().Nil(err)
+
+	offsets := make([]uint32, 0, len(suite.testRecords))
+
+	for _, rec := range suite.testRecords {
+		offsets = append(offsets, iterator.Offset())
+
+		key, value, err := reader.GetAt(iterator.Offset())
+		suite.Nil(err)
+		suite.Equal(rec.key, key)
+		suite.Equal(rec.val, value)
+
+		suite.mustNext(iterator)
+	}
+
+	for i := 1; i < len(offsets); i++ {
+		suite.True(offsets[i-1] < offsets[i])
+	}
+
+	_, _, err = reader.GetAt(0)
+	suite.Equal(ErrInvalidOffset, err)
+
+	_, _, err = reader.GetAt(maxUint)
+	suite.Equal(ErrInvalidOffset, err)
+}
+
+func (suite *CDBTestSuite) TestNotFound() {
+	suite.fillTestCDB()
+
+	reader := suite.getCDBReader()
+	missing := []byte("missing key")
+
+	_, err := reader.Get(missing)
+	suite.Equal(ErrEntryNotFound, err)
+
+	_, err = reader.IteratorAt(missing)
+	suite.Equal(ErrEntryNotFound, err)
+
+	value, err := GetOrDefault(reader, missing, []byte("default"))
+	suite.Nil(err)
+	suite.Equal([]byte("default"), value)
+
+	value, err = GetOrDefault(reader, missing, nil)
+	suite.Nil(err)
+	suite.Nil(value)
+
+	value, err = GetOrDefault(reader, suite.testRecords[0].key, nil)
+	suite.Nil(err)
+	suite.Equal(suite.testRecords[0].val, value)
+}
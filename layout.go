@@ -0,0 +1,339 @@
+package cdb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+)
+
+// A classic record is stored as:
+//
+//	+---------+---------+-----+-------+
+//	| keySize | valSize | key | value |
+//	+---------+---------+-----+-------+
+//	   u32       u32
+//
+// The v2 format appends optional fields to the record header, in the following order:
+//
+//	+------------+---------+--------+--------+--------+--------+-------+------------+-------+
+//	| suffixSize | valSize | anchor | shared | expiry | bucket | flags | key suffix | value |
+//	+------------+---------+--------+--------+--------+--------+-------+------------+-------+
+//	    u32         u32       u32      u32      i64      u32      u8
+//
+// valSize is absent with the fixed value size, the header keeps the size of all values then.
+//
+// anchor and shared are present with the key prefix compression. The full key is the first
+// shared bytes of the key of the anchor record followed by the suffix. An anchor record always
+// stores its key in full (shared is 0), so a key is restored with at most one extra read,
+// without walking a chain of records.
+//
+// expiry is present with the expiry support, it is the expiration time of the record
+// in nanoseconds since the Unix epoch, 0 means that the record never expires.
+//
+// bucket is present with the buckets support, it is the id of the bucket of the record.
+//
+// flags is present with the record flags support, it is a bitmask of RecordFlags.
+// It is always the last field of the record header.
+const (
+	// Size of the classic record header
+	recordHeaderSize = 8
+	// Size of the value size field
+	valSizeFieldSize = 4
+	// Size of the fields of the key prefix compression
+	prefixFieldsSize = 8
+	// Size of the expiry field
+	expiryFieldSize = 8
+	// Size of the record flags field
+	flagsFieldSize = 1
+	// Maximal size of the record header
+	maxRecordHeaderSize = recordHeaderSize + prefixFieldsSize + expiryFieldSize + bucketFieldSize + flagsFieldSize
+	// Minimal shared prefix worth to be compressed, otherwise the record becomes a new anchor
+	minSharedPrefix = prefixFieldsSize
+)
+
+// recordLayout describes where the parts of a record are placed in the data section
+type recordLayout struct {
+	// position is the position of the record start
+	position uint32
+	// keySize is the size of the full key, valSize is the size of the value
+	keySize, valSize uint32
+	// keyPosition is the position of the stored key bytes, valPosition is the position of the value
+	keyPosition, valPosition uint32
+	// anchor is the position of the record, which key shares the first shared bytes with this one
+	anchor, shared uint32
+	recordMeta
+}
+
+// recordMeta holds the optional per record fields of the v2 format
+type recordMeta struct {
+	// expiry is the expiration time in nanoseconds since the Unix epoch, 0 if the record never expires
+	expiry int64
+	// flags is the record flags, 0 if the database has no record flags
+	flags RecordFlags
+	// bucket is the id of the bucket of the record, 0 for the root bucket
+	bucket uint32
+}
+
+// end returns the position right after the record
+func (l *recordLayout) end() uint32 {
+	return l.valPosition + l.valSize
+}
+
+// compressed tells if the key is stored without its shared prefix
+func (l *recordLayout) compressed() bool {
+	return l.shared != 0
+}
+
+// expired tells if the record is expired at the given time in nanoseconds since the Unix epoch
+func (m *recordMeta) expired(now int64) bool {
+	return m.expiry != 0 && m.expiry <= now
+}
+
+// recordHeaderSize returns the size of a record header in the database
+func (h *header) recordHeaderSize() uint32 {
+	size := uint32(recordHeaderSize)
+
+	if h.flags&flagFixedValueSize != 0 {
+		size -= valSizeFieldSize
+	}
+
+	if h.flags&flagPrefixCompression != 0 {
+		size += prefixFieldsSize
+	}
+
+	if h.flags&flagExpiry != 0 {
+		size += expiryFieldSize
+	}
+
+	if h.flags&flagBuckets != 0 {
+		size += bucketFieldSize
+	}
+
+	if h.flags&flagRecordFlags != 0 {
+		size += flagsFieldSize
+	}
+
+	return size
+}
+
+// readRecord reads the layout of the record started at the given position
+func (r *readerImpl) readRecord(pos uint32) (recordLayout, error) {
+	scratch := r.getBuffer(maxRecordHeaderSize)
+	defer r.putBuffer(scratch)
+
+	buf := (*scratch)[:r.header.recordHeaderSize()]
+
+	if _, err := r.reader.ReadAt(buf, int64(pos)); err != nil {
+		return recordLayout{}, corrupted(err, int64(pos), -1, "record header is out of the database")
+	}
+
+	return r.parseRecord(buf, pos)
+}
+
+// parseRecord parses the header of the record started at the given position
+func (r *readerImpl) parseRecord(buf []byte, pos uint32) (recordLayout, error) {
+	return r.header.parseRecord(buf, pos, r.endPos)
+}
+
+// parseRecord parses the header of the record started at the given position
+// of the data section, which ends at the given end
+func (h *header) parseRecord(buf []byte, pos, end uint32) (recordLayout, error) {
+	size := h.recordHeaderSize()
+
+	l := recordLayout{
+		position:    pos,
+		keySize:     binary.LittleEndian.Uint32(buf),
+		keyPosition: pos + size,
+	}
+
+	fields := buf[4:]
+
+	if h.flags&flagFixedValueSize != 0 {
+		l.valSize = h.valueSize
+	} else {
+		l.valSize = binary.LittleEndian.Uint32(fields)
+		fields = fields[valSizeFieldSize:]
+	}
+
+	if h.flags&flagPrefixCompression != 0 {
+		l.anchor = binary.LittleEndian.Uint32(fields)
+		l.shared = binary.LittleEndian.Uint32(fields[4:])
+		fields = fields[prefixFieldsSize:]
+	}
+
+	if h.flags&flagExpiry != 0 {
+		l.expiry = int64(binary.LittleEndian.Uint64(fields))
+		fields = fields[expiryFieldSize:]
+	}
+
+	if h.flags&flagBuckets != 0 {
+		l.bucket = binary.LittleEndian.Uint32(fields)
+		fields = fields[bucketFieldSize:]
+	}
+
+	if h.flags&flagRecordFlags != 0 {
+		l.flags = RecordFlags(fields[0])
+	}
+
+	// The record must lie inside the data section, its anchor must precede it
+	if uint64(l.keyPosition)+uint64(l.keySize)+uint64(l.valSize) > uint64(end) ||
+		l.shared > maxUint-l.keySize || (l.shared != 0 && l.anchor >= pos) {
+		return recordLayout{}, corrupted(nil, int64(pos), -1, "record is out of the data section")
+	}
+
+	l.valPosition = l.keyPosition + l.keySize
+	l.keySize += l.shared
+
+	return l, nil
+}
+
+// readKey reads the full key of the given record
+func (r *readerImpl) readKey(l recordLayout) ([]byte, error) {
+	key := make([]byte, l.keySize)
+	suffix := key[l.shared:]
+
+	if _, err := r.reader.ReadAt(suffix, int64(l.keyPosition)); err != nil && !(err == io.EOF && len(suffix) == 0) {
+		return nil, err
+	}
+
+	if !l.compressed() {
+		return key, nil
+	}
+
+	if _, err := r.reader.ReadAt(key[:l.shared], int64(l.anchor+r.header.recordHeaderSize())); err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+// readRecordKey reads the layout and the full key of the record started at the given position.
+// The record header and the key suffix are read at once, unless the key is long, so that
+// a scan of keys of a file-backed database takes a read per record.
+func (r *readerImpl) readRecordKey(pos uint32) (recordLayout, []byte, error) {
+	if r.mem != nil {
+		layout, err := r.readRecord(pos)
+		if err != nil {
+			return recordLayout{}, nil, err
+		}
+
+		key, err := r.readKey(layout)
+
+		return layout, key, err
+	}
+
+	headerSize := int(r.header.recordHeaderSize())
+
+	scratch := r.getBuffer(recordPrefetchSize)
+	defer r.putBuffer(scratch)
+
+	buf := *scratch
+	n, err := r.reader.ReadAt(buf, int64(pos))
+
+	// A speculative read may run past the end of the database
+	if n < headerSize || (err != nil && err != io.EOF) {
+		return recordLayout{}, nil, corrupted(err, int64(pos), -1, "record header is out of the database")
+	}
+
+	layout, err := r.parseRecord(buf, pos)
+	if err != nil {
+		return recordLayout{}, nil, err
+	}
+
+	suffixEnd := headerSize + int(layout.keySize-layout.shared)
+
+	if suffixEnd > n {
+		key, err := r.readKey(layout)
+
+		return layout, key, err
+	}
+
+	key := make([]byte, layout.keySize)
+	copy(key[layout.shared:], buf[headerSize:suffixEnd])
+
+	if layout.compressed() {
+		if _, err := r.reader.ReadAt(key[:layout.shared], int64(layout.anchor+r.header.recordHeaderSize())); err != nil {
+			return recordLayout{}, nil, corrupted(err, int64(layout.anchor), -1, "anchor record is out of the database")
+		}
+	}
+
+	return layout, key, nil
+}
+
+// prefixEquals tells if the shared prefix of the given compressed record is the prefix of the given key
+func (r *readerImpl) prefixEquals(l recordLayout, key []byte) (bool, error) {
+	scratch := r.getBuffer(int(l.shared))
+	defer r.putBuffer(scratch)
+
+	prefix := *scratch
+
+	if _, err := r.reader.ReadAt(prefix, int64(l.anchor+r.header.recordHeaderSize())); err != nil {
+		return false, corrupted(err, int64(l.anchor), -1, "anchor record is out of the database")
+	}
+
+	return bytes.Equal(prefix, key[:l.shared]), nil
+}
+
+// writeRecordHeader writes the header of a record with the given key, value size and meta fields.
+// Returns the number of stored key bytes.
+func (w *writerImpl) writeRecordHeader(key []byte, valSize uint32, meta recordMeta) (int, error) {
+	buf := make([]byte, maxRecordHeaderSize)
+	fields := buf[4:]
+	stored := len(key)
+
+	if w.header.flags&flagFixedValueSize == 0 {
+		binary.LittleEndian.PutUint32(fields, valSize)
+		fields = fields[valSizeFieldSize:]
+	}
+
+	if w.header.flags&flagPrefixCompression != 0 {
+		shared := commonPrefix(w.anchorKey, key)
+
+		if shared < minSharedPrefix {
+			w.anchorKey = append(w.anchorKey[:0], key...)
+			w.anchor = uint32(w.current)
+		} else {
+			binary.LittleEndian.PutUint32(fields, w.anchor)
+			binary.LittleEndian.PutUint32(fields[4:], uint32(shared))
+			stored -= shared
+		}
+
+		fields = fields[prefixFieldsSize:]
+	}
+
+	if w.header.flags&flagExpiry != 0 {
+		binary.LittleEndian.PutUint64(fields, uint64(meta.expiry))
+		fields = fields[expiryFieldSize:]
+	}
+
+	if w.header.flags&flagBuckets != 0 {
+		binary.LittleEndian.PutUint32(fields, meta.bucket)
+		fields = fields[bucketFieldSize:]
+	}
+
+	if w.header.flags&flagRecordFlags != 0 {
+		fields[0] = byte(meta.flags)
+	}
+
+	binary.LittleEndian.PutUint32(buf, uint32(stored))
+
+	_, err := w.buffer.Write(buf[:w.header.recordHeaderSize()])
+
+	return stored, err
+}
+
+// commonPrefix returns the length of the common prefix of a and b
+func commonPrefix(a, b []byte) int {
+	n := len(a)
+	if len(b) < n {
+		n = len(b)
+	}
+
+	for i := 0; i < n; i++ {
+		if a[i] != b[i] {
+			return i
+		}
+	}
+
+	return n
+}
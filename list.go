@@ -0,0 +1,51 @@
+package cdb
+
+import (
+	"bytes"
+	"errors"
+)
+
+// ErrInvalidLimit tells that the page size of a listing or the size of a batch is not positive
+var ErrInvalidLimit = errors.New("cdb page limit must be positive")
+
+// ListKeys returns a page of at most limit distinct keys of the reader, which are greater than the given
+// one, in the key order. A nil after starts from the least key. next is the key to pass as after
+// to get the following page, it is nil on the last page. Requires the sorted index, see Reader.Iterate.
+func ListKeys(reader Reader, after []byte, limit int) (keys [][]byte, next []byte, err error) {
+	if limit <= 0 {
+		return nil, nil, ErrInvalidLimit
+	}
+
+	iterator, err := reader.Iterate(after, nil, ExcludeBoth)
+	if err == ErrEntryNotFound {
+		return nil, nil, nil
+	}
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for ok := true; ok; ok, err = iterator.Next() {
+		key, err := iterator.Key()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		// Records with equal keys are adjacent in the key order
+		if len(keys) > 0 && bytes.Equal(key, keys[len(keys)-1]) {
+			continue
+		}
+
+		if len(keys) == limit {
+			return keys, keys[limit-1], nil
+		}
+
+		keys = append(keys, key)
+	}
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return keys, nil, nil
+}
@@ -0,0 +1,66 @@
+package cdb
+
+import (
+	"bytes"
+	"fmt"
+)
+
+func (suite *CDBTestSuite) TestListKeys() {
+	index := &bytes.Buffer{}
+	writer, err := suite.cdbHandle.GetWriterWithIndex(suite.cdbFile, index)
+	suite.Require().Nil(err)
+
+	var expected [][]byte
+	for i := 9; i >= 0; i-- {
+		key := []byte(fmt.Sprintf("key%02d", i))
+		expected = append([][]byte{key}, expected...)
+
+		suite.Require().Nil(writer.Put(key, []byte("first")))
+		suite.Require().Nil(writer.Put(key, []byte("second")))
+	}
+
+	suite.Require().Nil(writer.Close())
+
+	_, _, err = ListKeys(suite.getCDBReader(), nil, 3)
+	suite.Equal(ErrNoIndex, err)
+
+	reader, err := suite.cdbHandle.GetReaderWithIndex(suite.cdbFile, bytes.NewReader(index.Bytes()))
+	suite.Require().Nil(err)
+
+	_, _, err = ListKeys(reader, nil, 0)
+	suite.Equal(ErrInvalidLimit, err)
+
+	var (
+		listed [][]byte
+		pages  int
+		after  []byte
+	)
+
+	for {
+		keys, next, err := ListKeys(reader, after, 3)
+		suite.Require().Nil(err)
+		suite.True(len(keys) <= 3)
+
+		listed = append(listed, keys...)
+		pages++
+
+		if next == nil {
+			break
+		}
+
+		after = next
+	}
+
+	suite.Equal(expected, listed)
+	suite.Equal(4, pages)
+
+	keys, next, err := ListKeys(reader, []byte("key09"), 3)
+	suite.Nil(err)
+	suite.Empty(keys)
+	suite.Nil(next)
+
+	keys, next, err = ListKeys(reader, []byte("key06"), 3)
+	suite.Nil(err)
+	suite.Equal(expected[7:], keys)
+	suite.Nil(next, "The page ends with the last key")
+}
@@ -0,0 +1,80 @@
+package cdb
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+// eventLogger receives debug events of readers, *slog.Logger implements it, see CDB.SetLogger
+type eventLogger interface {
+	Debug(msg string, args ...interface{})
+	Warn(msg string, args ...interface{})
+}
+
+// slowReaderAt implements io.ReaderAt, logs reads of the underlying reader slower than the threshold
+type slowReaderAt struct {
+	reader    io.ReaderAt
+	logger    eventLogger
+	threshold time.Duration
+}
+
+// SetSlowReadThreshold tells readers to log reads of the database, which take longer than
+// the given duration, as warnings. It requires a logger, see CDB.SetLogger. 0 disables it, it's the default.
+// Like SetHash, it affects only new instances of Reader.
+func (cdb *CDB) SetSlowReadThreshold(threshold time.Duration) {
+	cdb.opts.slowRead = threshold
+}
+
+// ReadAt reads len(p) bytes at the given offset, logs the read if it is slow
+func (r *slowReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	return r.readAtContext(context.Background(), p, off)
+}
+
+// readAtContext reads len(p) bytes at the given offset, passes the context to the underlying reader
+func (r *slowReaderAt) readAtContext(ctx context.Context, p []byte, off int64) (int, error) {
+	start := time.Now()
+	n, err := readAtContext(ctx, r.reader, p, off)
+
+	if d := time.Since(start); d > r.threshold {
+		r.logger.Warn("cdb slow read", "offset", off, "size", len(p), "duration", d)
+	}
+
+	return n, err
+}
+
+// unwrap returns the underlying reader
+func (r *slowReaderAt) unwrap() io.ReaderAt {
+	return r.reader
+}
+
+// logInitialized logs the layout of an initialized reader
+func (r *readerImpl) logInitialized() {
+	used, slots, maxSlots := 0, 0, uint32(0)
+
+	for _, ref := range r.refs {
+		if ref.length != 0 {
+			used++
+		}
+
+		slots += int(ref.length)
+
+		if ref.length > maxSlots {
+			maxSlots = ref.length
+		}
+	}
+
+	r.opts.logger.Debug("cdb reader initialized",
+		"records", r.total, "v2", r.header.v2, "flags", r.header.flags, "align", r.header.align,
+		"bloom", r.bloom != nil, "buckets", len(r.buckets))
+	r.opts.logger.Debug("cdb hash tables",
+		"tables", len(r.refs), "used", used, "slots", slots, "max_slots", maxSlots)
+}
+
+// logCorruption logs the given error, if it is a CorruptionError
+func (r *readerImpl) logCorruption(err error) {
+	if corruption, ok := err.(*CorruptionError); ok && r.opts.logger != nil {
+		r.opts.logger.Warn("cdb corruption",
+			"offset", corruption.Offset, "table", corruption.Table, "reason", corruption.Reason)
+	}
+}
@@ -0,0 +1,18 @@
+//go:build go1.21
+// +build go1.21
+
+package cdb
+
+import "log/slog"
+
+// SetLogger sets the logger of readers, nil disables logging, it's the default. Readers log
+// their initialization and hash table statistics as debug messages, corruption and slow reads
+// as warnings, see SetSlowReadThreshold. Like SetHash, it affects only new instances of Reader.
+func (cdb *CDB) SetLogger(logger *slog.Logger) {
+	if logger == nil {
+		cdb.opts.logger = nil
+		return
+	}
+
+	cdb.opts.logger = logger
+}
@@ -0,0 +1,41 @@
+//go:build go1.21
+// +build go1.21
+
+package cdb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"log/slog"
+	"time"
+)
+
+func (suite *CDBTestSuite) TestLogger() {
+	suite.fillTestCDB()
+
+	var out bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&out, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	suite.cdbHandle.SetLogger(logger)
+	suite.cdbHandle.SetSlowReadThreshold(time.Nanosecond)
+
+	reader := suite.getCDBReader().(*readerImpl)
+	suite.Contains(out.String(), "cdb reader initialized")
+	suite.Contains(out.String(), "cdb hash tables")
+
+	_, err := reader.Get(suite.testRecords[0].key)
+	suite.Nil(err)
+	suite.Contains(out.String(), "cdb slow read")
+
+	size := make([]byte, 4)
+	binary.LittleEndian.PutUint32(size, maxUint)
+	_, err = suite.cdbFile.WriteAt(size, int64(reader.header.dataPosition()+4))
+	suite.Require().Nil(err)
+
+	_, err = reader.Get(suite.testRecords[0].key)
+	suite.IsType(&CorruptionError{}, err)
+	suite.Contains(out.String(), "cdb corruption")
+
+	suite.cdbHandle.SetLogger(nil)
+	suite.Nil(suite.cdbHandle.opts.logger)
+}
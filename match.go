@@ -0,0 +1,53 @@
+package cdb
+
+import (
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Match returns a new Iterator object, which walks records with keys matching the given glob pattern.
+// The pattern syntax is the one of path.Match, so wildcards don't match the '/' separator.
+// The literal prefix of the pattern narrows the scan, see Reader.IteratePrefix.
+// Returns path.ErrBadPattern if the pattern is malformed and nil if there is no such record.
+func Match(reader Reader, pattern string) (Iterator, error) {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+
+	prefix := pattern
+	if n := strings.IndexAny(pattern, `*?[\`); n >= 0 {
+		prefix = pattern[:n]
+	}
+
+	return matchPrefix(reader, prefix, func(key []byte) bool {
+		matched, _ := path.Match(pattern, string(key))
+		return matched
+	})
+}
+
+// MatchRegexp returns a new Iterator object, which walks records with keys matching the given regexp.
+// The literal prefix of a regexp anchored at the text start narrows the scan, see Reader.IteratePrefix.
+// Returns nil if there is no such record.
+func MatchRegexp(reader Reader, re *regexp.Regexp) (Iterator, error) {
+	var prefix string
+
+	if strings.HasPrefix(re.String(), "^") {
+		prefix, _ = re.LiteralPrefix()
+	}
+
+	return matchPrefix(reader, prefix, re.Match)
+}
+
+// matchPrefix returns a new Iterator object, which walks records with keys starting with the given prefix
+// and accepted by the match function
+func matchPrefix(reader Reader, prefix string, match func(key []byte) bool) (Iterator, error) {
+	iterator, err := reader.IteratePrefix([]byte(prefix))
+	if iterator == nil {
+		return nil, err
+	}
+
+	return newFilterIterator(iterator, nil, func(key, _ []byte) (bool, error) {
+		return match(key), nil
+	})
+}
@@ -0,0 +1,50 @@
+package cdb
+
+import (
+	"bytes"
+	"path"
+	"regexp"
+)
+
+func (suite *CDBTestSuite) TestMatch() {
+	keys := []string{"user/2", "group/1", "user/10", "user", "users/1", "user/1/avatar", "a*b"}
+
+	index := &bytes.Buffer{}
+	writer, err := suite.cdbHandle.GetWriterWithIndex(suite.cdbFile, index)
+	suite.Require().Nil(err)
+
+	for _, key := range keys {
+		suite.Require().Nil(writer.Put([]byte(key), []byte(key)))
+	}
+
+	suite.Require().Nil(writer.Close())
+
+	indexed, err := suite.cdbHandle.GetReaderWithIndex(suite.cdbFile, bytes.NewReader(index.Bytes()))
+	suite.Require().Nil(err)
+
+	walk := func(iterator Iterator, err error) []string {
+		suite.Require().Nil(err)
+
+		var actual []string
+
+		for ok := iterator != nil; ok; ok = suite.mustNext(iterator) {
+			key, err := iterator.Key()
+			suite.Require().Nil(err)
+			actual = append(actual, string(key))
+		}
+
+		return actual
+	}
+
+	suite.Equal([]string{"user/10", "user/2"}, walk(Match(indexed, "user/*")))
+	suite.Equal([]string{"user/2", "user/10"}, walk(Match(suite.getCDBReader(), "user/*")))
+	suite.Equal([]string{"group/1", "users/1"}, walk(Match(indexed, "*/1")))
+	suite.Equal([]string{"a*b"}, walk(Match(indexed, `a\*b`)))
+	suite.Nil(walk(Match(indexed, "nobody/*")))
+
+	_, err = Match(indexed, "user/[")
+	suite.Equal(path.ErrBadPattern, err)
+
+	suite.Equal([]string{"user/1/avatar", "user/10", "user/2"}, walk(MatchRegexp(indexed, regexp.MustCompile(`^user/\d`))))
+	suite.Equal([]string{"group/1", "user/1/avatar", "users/1"}, walk(MatchRegexp(indexed, regexp.MustCompile(`/1\b`))))
+}
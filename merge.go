@@ -0,0 +1,100 @@
+package cdb
+
+// Dedup tells MergeIterators, which records of a key found in several readers are walked
+type Dedup int
+
+const (
+	// KeepAll walks the records of all readers
+	KeepAll Dedup = iota
+	// KeepFirst walks the records of a key from the first reader having the key only
+	KeepFirst
+	// KeepLast walks the records of a key from the last reader having the key only,
+	// e.g. readers of snapshots given from the oldest one
+	KeepLast
+)
+
+// MergeIterators returns a new Iterator object, which walks the records of all readers.
+// Records are merged in the key order, if every reader has the sorted index, otherwise the readers
+// are walked one by one in the file order. Records with equal keys of different readers are
+// yielded in the order of readers and filtered by the dedup policy, which checks keys with Has
+// of the other readers. Returns ErrEmptyCDB if there are no records to walk.
+func MergeIterators(dedup Dedup, readers ...Reader) (Iterator, error) {
+	iterator, err := mergeReaders(readers, dedup, Reader.OrderedIterator, true)
+	if err != ErrNoIndex {
+		return iterator, err
+	}
+
+	return mergeReaders(readers, dedup, Reader.Iterator, false)
+}
+
+// mergeReaders returns a new Iterator object, which merges the iterators opened by the given function
+// in the key order, if ordered is true, otherwise concatenates them
+func mergeReaders(readers []Reader, dedup Dedup, open func(Reader) (Iterator, error), ordered bool) (Iterator, error) {
+	concat := &concatIterator{}
+
+	for j, reader := range readers {
+		iterator, err := open(reader)
+
+		if err == ErrEmptyCDB {
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		if iterator, err = dedupIterator(iterator, shadowing(readers, j, dedup)); err != nil {
+			return nil, err
+		}
+
+		if iterator != nil {
+			concat.iterators = append(concat.iterators, iterator)
+			concat.parts = append(concat.parts, j)
+		}
+	}
+
+	if len(concat.iterators) == 0 {
+		return nil, ErrEmptyCDB
+	}
+
+	if !ordered {
+		return concat, nil
+	}
+
+	iterator, err := newMergeIterator(concat.iterators, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return iterator, nil
+}
+
+// shadowing returns the readers, which records of the j-th reader with the same keys are dropped for
+func shadowing(readers []Reader, j int, dedup Dedup) []Reader {
+	switch dedup {
+	case KeepFirst:
+		return readers[:j]
+	case KeepLast:
+		return readers[j+1:]
+	}
+
+	return nil
+}
+
+// dedupIterator returns a new Iterator object, which walks the records of the given iterator with keys,
+// which none of the shadowing readers has. Returns nil if there are no such records.
+func dedupIterator(iterator Iterator, shadowing []Reader) (Iterator, error) {
+	if len(shadowing) == 0 {
+		return iterator, nil
+	}
+
+	return newFilterIterator(iterator, nil, func(key, _ []byte) (bool, error) {
+		for _, reader := range shadowing {
+			if exists, err := reader.Has(key); err != nil || exists {
+				return false, err
+			}
+		}
+
+		return true, nil
+	})
+}
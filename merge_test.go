@@ -0,0 +1,88 @@
+package cdb
+
+import (
+	"bytes"
+	"os"
+)
+
+// writeMergeSources writes databases with the given records "key=value" and returns readers of them,
+// the readers use the sorted index, if withIndex is true
+func (suite *CDBTestSuite) writeMergeSources(files []*os.File, withIndex bool, sources ...[]string) []Reader {
+	readers := make([]Reader, len(sources))
+
+	for j, records := range sources {
+		index := &bytes.Buffer{}
+		writer, err := suite.cdbHandle.GetWriterWithIndex(files[j], index)
+		suite.Require().Nil(err)
+
+		for _, rec := range records {
+			pair := bytes.SplitN([]byte(rec), []byte("="), 2)
+			suite.Require().Nil(writer.Put(pair[0], pair[1]))
+		}
+
+		suite.Require().Nil(writer.Close())
+
+		if withIndex {
+			readers[j], err = suite.cdbHandle.GetReaderWithIndex(files[j], bytes.NewReader(index.Bytes()))
+		} else {
+			readers[j], err = suite.cdbHandle.GetReader(files[j])
+		}
+
+		suite.Require().Nil(err)
+	}
+
+	return readers
+}
+
+// mergedRecords returns the records "key=value" walked by the iterator
+func (suite *CDBTestSuite) mergedRecords(iterator Iterator) []string {
+	var records []string
+
+	for ok := true; ok; ok = suite.mustNext(iterator) {
+		key, err := iterator.Key()
+		suite.Require().Nil(err)
+		value, err := iterator.Value()
+		suite.Require().Nil(err)
+		records = append(records, string(key)+"="+string(value))
+	}
+
+	return records
+}
+
+func (suite *CDBTestSuite) TestMergeIterators() {
+	files := suite.createShardFiles(3)
+	defer suite.removeShardFiles(files)
+
+	sources := [][]string{
+		{"c=old", "a=old", "d=old"},
+		{},
+		{"b=new", "c=new"},
+	}
+
+	cases := []struct {
+		dedup            Dedup
+		ordered, written []string
+	}{
+		{KeepAll, []string{"a=old", "b=new", "c=old", "c=new", "d=old"}, []string{"c=old", "a=old", "d=old", "b=new", "c=new"}},
+		{KeepFirst, []string{"a=old", "b=new", "c=old", "d=old"}, []string{"c=old", "a=old", "d=old", "b=new"}},
+		{KeepLast, []string{"a=old", "b=new", "c=new", "d=old"}, []string{"a=old", "d=old", "b=new", "c=new"}},
+	}
+
+	for _, withIndex := range []bool{true, false} {
+		readers := suite.writeMergeSources(files, withIndex, sources...)
+
+		for _, c := range cases {
+			iterator, err := MergeIterators(c.dedup, readers...)
+			suite.Require().Nil(err)
+
+			if withIndex {
+				suite.Equal(c.ordered, suite.mergedRecords(iterator))
+			} else {
+				suite.Equal(c.written, suite.mergedRecords(iterator))
+			}
+		}
+	}
+
+	_, err := MergeIterators(KeepAll)
+	suite.Equal(ErrEmptyCDB, err)
+}
@@ -0,0 +1,128 @@
+package cdb
+
+import (
+	"expvar"
+	"strconv"
+	"time"
+)
+
+// Metrics receives statistics of lookups of readers, see CDB.SetMetrics.
+// Implementations must be safe for concurrent use and fast, they are called on every lookup.
+// ExpvarMetrics publishes them by expvar. A Prometheus adapter feeds client_golang collectors,
+// which are registered by the application:
+//
+//	type promMetrics struct {
+//		lookups   *prometheus.CounterVec // labeled by result: hit or miss
+//		bytesRead prometheus.Counter
+//		probes    prometheus.Histogram
+//		duration  prometheus.Histogram
+//	}
+//
+//	func (m promMetrics) ObserveLookup(stats cdb.LookupStats) {
+//		result := "miss"
+//		if stats.Found {
+//			result = "hit"
+//		}
+//
+//		m.lookups.WithLabelValues(result).Inc()
+//		m.bytesRead.Add(float64(stats.BytesRead))
+//		m.probes.Observe(float64(stats.Probes))
+//		m.duration.Observe(stats.Duration.Seconds())
+//	}
+type Metrics interface {
+	// ObserveLookup is called after every lookup of a key
+	ObserveLookup(stats LookupStats)
+}
+
+// LookupStats describes a lookup of a key
+type LookupStats struct {
+	// Found tells if the key was found
+	Found bool
+	// Probes is the number of probed slots
+	Probes int
+	// Reads is the number of reads of the database
+	Reads int
+	// BytesRead is the number of bytes read by probing: slots, records and values read with them
+	BytesRead int
+	// Duration is the duration of the lookup
+	Duration time.Duration
+}
+
+// Number of buckets of the probe length histogram of ExpvarMetrics
+const probeBuckets = 8
+
+// ExpvarMetrics implements Metrics by expvar counters:
+//
+//	lookups, hits, misses, bytes_read, lookup_ns
+//	probes_le_1, probes_le_2, probes_le_4, ..., probes_le_64, probes_inf
+//
+// where probes_le_N counts lookups, which probed at most N slots and more than N/2.
+type ExpvarMetrics struct {
+	vars                                     *expvar.Map
+	lookups, hits, misses, bytesRead, lookup *expvar.Int
+	probes                                   [probeBuckets]*expvar.Int
+}
+
+// SetMetrics sets the receiver of lookup statistics, nil disables metrics, it's the default.
+// Like SetHash, it affects only new instances of Reader.
+func (cdb *CDB) SetMetrics(metrics Metrics) {
+	cdb.opts.metrics = metrics
+}
+
+// NewExpvarMetrics returns ExpvarMetrics, which counters are published as the expvar map with the given name.
+// Like expvar.Publish, it panics if the name is already used.
+func NewExpvarMetrics(name string) *ExpvarMetrics {
+	m := &ExpvarMetrics{
+		vars:      expvar.NewMap(name),
+		lookups:   new(expvar.Int),
+		hits:      new(expvar.Int),
+		misses:    new(expvar.Int),
+		bytesRead: new(expvar.Int),
+		lookup:    new(expvar.Int),
+	}
+
+	m.vars.Set("lookups", m.lookups)
+	m.vars.Set("hits", m.hits)
+	m.vars.Set("misses", m.misses)
+	m.vars.Set("bytes_read", m.bytesRead)
+	m.vars.Set("lookup_ns", m.lookup)
+
+	for i := range m.probes {
+		m.probes[i] = new(expvar.Int)
+
+		name := "probes_inf"
+		if i < probeBuckets-1 {
+			name = "probes_le_" + strconv.Itoa(1<<uint(i))
+		}
+
+		m.vars.Set(name, m.probes[i])
+	}
+
+	return m
+}
+
+// ObserveLookup updates the counters
+func (m *ExpvarMetrics) ObserveLookup(stats LookupStats) {
+	m.lookups.Add(1)
+
+	if stats.Found {
+		m.hits.Add(1)
+	} else {
+		m.misses.Add(1)
+	}
+
+	m.bytesRead.Add(int64(stats.BytesRead))
+	m.lookup.Add(int64(stats.Duration))
+
+	bucket := 0
+	for bucket < probeBuckets-1 && stats.Probes > 1<<uint(bucket) {
+		bucket++
+	}
+
+	m.probes[bucket].Add(1)
+}
+
+// Vars returns the published expvar map
+func (m *ExpvarMetrics) Vars() *expvar.Map {
+	return m.vars
+}
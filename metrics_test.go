@@ -0,0 +1,63 @@
+package cdb
+
+import (
+	"expvar"
+	"sync"
+)
+
+// recordingMetrics implements Metrics, keeps observed lookups
+type recordingMetrics struct {
+	mu      sync.Mutex
+	lookups []LookupStats
+}
+
+func (m *recordingMetrics) ObserveLookup(stats LookupStats) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.lookups = append(m.lookups, stats)
+}
+
+func (suite *CDBTestSuite) TestMetrics() {
+	suite.fillTestCDB()
+
+	metrics := &recordingMetrics{}
+	suite.cdbHandle.SetMetrics(metrics)
+	reader := suite.getCDBReader()
+
+	rec := suite.testRecords[0]
+	_, err := reader.Get(rec.key)
+	suite.Require().Nil(err)
+
+	_, err = reader.Get([]byte("missing key"))
+	suite.Equal(ErrEntryNotFound, err)
+
+	suite.Require().Len(metrics.lookups, 2)
+	suite.True(metrics.lookups[0].Found)
+	suite.True(metrics.lookups[0].Probes >= 1)
+	suite.True(metrics.lookups[0].BytesRead >= slotSize+len(rec.key)+len(rec.val))
+	suite.False(metrics.lookups[1].Found)
+}
+
+func (suite *CDBTestSuite) TestExpvarMetrics() {
+	suite.fillTestCDB()
+
+	metrics := NewExpvarMetrics("cdb_test_lookups")
+	suite.cdbHandle.SetMetrics(metrics)
+	reader := suite.getCDBReader()
+
+	for _, rec := range suite.testRecords {
+		_, err := reader.Get(rec.key)
+		suite.Require().Nil(err)
+	}
+
+	_, err := reader.Has([]byte("missing key"))
+	suite.Nil(err)
+
+	vars := metrics.Vars()
+	suite.Equal(int64(len(suite.testRecords)+1), vars.Get("lookups").(*expvar.Int).Value())
+	suite.Equal(int64(len(suite.testRecords)), vars.Get("hits").(*expvar.Int).Value())
+	suite.Equal(int64(1), vars.Get("misses").(*expvar.Int).Value())
+	suite.NotNil(vars.Get("probes_le_1"))
+	suite.NotNil(vars.Get("probes_inf"))
+}
@@ -0,0 +1,126 @@
+package cdb
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"sync"
+)
+
+// memReaderAt implements io.ReaderAt over a byte slice, like a mapped file
+type memReaderAt struct {
+	data []byte
+}
+
+// ReadAt reads len(p) bytes at the given offset
+func (m *memReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 || off > int64(len(m.data)) {
+		return 0, io.EOF
+	}
+
+	n := copy(p, m.data[off:])
+
+	if n < len(p) {
+		return n, io.EOF
+	}
+
+	return n, nil
+}
+
+// Size returns the size of the data
+func (m *memReaderAt) Size() int64 {
+	return int64(len(m.data))
+}
+
+// GetReaderMmap returns a new Reader object, which memory-maps the given file.
+// Get returns value slices pointing into the mapping, so lookups neither allocate
+// nor make syscalls. Such a slice must not be modified and is valid only until
+// the reader is closed, see CopyValue. The mapping is released by Close of the
+// returned reader. The file may be closed right after the call.
+func (cdb *CDB) GetReaderMmap(f *os.File) (Reader, error) {
+	data, err := mmapFile(f)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := newReader(&memReaderAt{data}, cdb.Hasher, cdb.opts)
+	if err != nil {
+		munmapFile(data)
+		return nil, err
+	}
+
+	r.mem = data
+
+	if cdb.opts.access != AccessNormal {
+		madvise(data, cdb.opts.access)
+	}
+
+	if cdb.opts.hugePages {
+		madviseHugePage(data)
+	}
+
+	var once sync.Once
+
+	r.closer = closerFunc(func() error {
+		err := error(nil)
+		once.Do(func() {
+			err = munmapFile(data)
+		})
+
+		return err
+	})
+
+	return r, nil
+}
+
+// GetWriterMmap returns a new Writer object, which writes the given file through a writable mapping
+// instead of write syscalls, which speeds up builds on fast disks. The mapping grows with ftruncate,
+// Close and Abort of the returned writer release it and cut the file to the written size, the file
+// stays open. The database starts at the current offset of the file. Platforms without mmap get
+// a usual writer of the file.
+func (cdb *CDB) GetWriterMmap(f *os.File) (Writer, error) {
+	return cdb.getWriterMmap(f)
+}
+
+// NewReaderFromBytes returns a new Reader object, which serves lookups from the given database image.
+// Like with GetReaderMmap, Get returns value slices pointing into the image, which must not be modified.
+func (cdb *CDB) NewReaderFromBytes(data []byte) (Reader, error) {
+	r, err := newReader(&memReaderAt{data}, cdb.Hasher, cdb.opts)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mem = data
+
+	return r, nil
+}
+
+// GetReaderInMemory reads the whole database from the given reader and returns a new Reader object,
+// which serves lookups from memory, see NewReaderFromBytes. It suits small databases, when
+// syscalls dominate the lookup latency. The given reader may be closed right after the call.
+func (cdb *CDB) GetReaderInMemory(reader io.Reader) (Reader, error) {
+	data, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	return cdb.NewReaderFromBytes(data)
+}
+
+// CopyValue returns a copy of the given value, which stays valid after the reader
+// is closed. Use it for values returned by a memory-mapped reader, which must outlive it.
+func CopyValue(value []byte) []byte {
+	if value == nil {
+		return nil
+	}
+
+	return append(make([]byte, 0, len(value)), value...)
+}
+
+// closerFunc implements io.Closer by calling itself
+type closerFunc func() error
+
+// Close calls the function
+func (f closerFunc) Close() error {
+	return f()
+}
@@ -0,0 +1,29 @@
+//go:build !linux && !darwin && !freebsd && !netbsd && !openbsd
+// +build !linux,!darwin,!freebsd,!netbsd,!openbsd
+
+package cdb
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+)
+
+// mmapFile reads the whole given file into memory, there is no mmap on the platform
+func mmapFile(f *os.File) ([]byte, error) {
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	return ioutil.ReadAll(f)
+}
+
+// munmapFile releases the memory returned by mmapFile, the garbage collector does it
+func munmapFile(data []byte) error {
+	return nil
+}
+
+// getWriterMmap returns a usual Writer of the file, there is no mmap on the platform
+func (cdb *CDB) getWriterMmap(f *os.File) (Writer, error) {
+	return cdb.GetWriter(f)
+}
@@ -0,0 +1,218 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd
+// +build linux darwin freebsd netbsd openbsd
+
+package cdb
+
+import (
+	"errors"
+	"io"
+	"os"
+	"syscall"
+)
+
+// Minimal growth of the mapping of a writer
+const mmapWriterChunk = 1 << 20
+
+// errNegativeSeek tells that it was an attempt to seek before the start of the file
+var errNegativeSeek = errors.New("cdb seek to a negative position")
+
+// mmapFile maps the whole given file into memory for reading
+func mmapFile(f *os.File) ([]byte, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	if info.Size() == 0 {
+		return []byte{}, nil
+	}
+
+	if info.Size() != int64(int(info.Size())) {
+		return nil, ErrOutOfMemory
+	}
+
+	return syscall.Mmap(int(f.Fd()), 0, int(info.Size()), syscall.PROT_READ, syscall.MAP_SHARED)
+}
+
+// munmapFile releases the mapping returned by mmapFile
+func munmapFile(data []byte) error {
+	if len(data) == 0 {
+		return nil
+	}
+
+	return syscall.Munmap(data)
+}
+
+// mmapWriter implements io.WriteSeeker over a writable mapping of a file, which grows with ftruncate
+type mmapWriter struct {
+	f    *os.File
+	data []byte
+	// pos is the current position, size is the size of the written file
+	pos, size int64
+}
+
+// mmapFileWriter implements Writer interface, releases the mapping on Close and Abort
+type mmapFileWriter struct {
+	Writer
+	m *mmapWriter
+}
+
+// getWriterMmap returns a Writer, which writes the file through a writable mapping, see GetWriterMmap
+func (cdb *CDB) getWriterMmap(f *os.File) (Writer, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	pos, err := f.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &mmapWriter{f: f, pos: pos, size: info.Size()}
+
+	writer, err := newWriter(m, cdb.Hasher, cdb.opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &mmapFileWriter{writer, m}, nil
+}
+
+// Write copies p to the mapping at the current position, the mapping grows if it is too small
+func (m *mmapWriter) Write(p []byte) (int, error) {
+	end := m.pos + int64(len(p))
+
+	if end > int64(len(m.data)) {
+		if err := m.grow(end); err != nil {
+			return 0, err
+		}
+	}
+
+	copy(m.data[m.pos:], p)
+	m.pos = end
+
+	if end > m.size {
+		m.size = end
+	}
+
+	return len(p), nil
+}
+
+// Seek sets the position of the next Write
+func (m *mmapWriter) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekCurrent:
+		offset += m.pos
+	case io.SeekEnd:
+		offset += m.size
+	}
+
+	if offset < 0 {
+		return m.pos, errNegativeSeek
+	}
+
+	m.pos = offset
+
+	return offset, nil
+}
+
+// Truncate cuts the written file to the given size, a mapped file is cut, when the mapping is released
+func (m *mmapWriter) Truncate(size int64) error {
+	if size < 0 {
+		return errNegativeSeek
+	}
+
+	m.size = size
+
+	if m.data == nil {
+		return m.f.Truncate(size)
+	}
+
+	return nil
+}
+
+// Sync releases the mapping and commits the file to stable storage
+func (m *mmapWriter) Sync() error {
+	if err := m.release(); err != nil {
+		return err
+	}
+
+	return m.f.Sync()
+}
+
+// grow maps at least end bytes of the file, the mapping at least doubles
+func (m *mmapWriter) grow(end int64) error {
+	n := 2 * int64(len(m.data))
+	if n < end {
+		n = end
+	}
+
+	n = (n + mmapWriterChunk - 1) / mmapWriterChunk * mmapWriterChunk
+
+	if n != int64(int(n)) {
+		return ErrOutOfMemory
+	}
+
+	if err := munmapFile(m.data); err != nil {
+		return err
+	}
+
+	m.data = nil
+
+	if err := m.f.Truncate(n); err != nil {
+		return err
+	}
+
+	data, err := syscall.Mmap(int(m.f.Fd()), 0, int(n), syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_SHARED)
+	if err != nil {
+		return err
+	}
+
+	m.data = data
+
+	return nil
+}
+
+// release unmaps the file, cuts the growth slack off and moves the file offset to the current position
+func (m *mmapWriter) release() error {
+	if m.data == nil {
+		return nil
+	}
+
+	if err := munmapFile(m.data); err != nil {
+		return err
+	}
+
+	m.data = nil
+
+	if err := m.f.Truncate(m.size); err != nil {
+		return err
+	}
+
+	_, err := m.f.Seek(m.pos, io.SeekStart)
+
+	return err
+}
+
+// Close commits the database and releases the mapping.
+func (w *mmapFileWriter) Close() error {
+	err := w.Writer.Close()
+
+	if releaseErr := w.m.release(); err == nil {
+		err = releaseErr
+	}
+
+	return err
+}
+
+// Abort discards the database and releases the mapping.
+func (w *mmapFileWriter) Abort() error {
+	err := w.Writer.Abort()
+
+	if releaseErr := w.m.release(); err == nil {
+		err = releaseErr
+	}
+
+	return err
+}
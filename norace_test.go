@@ -0,0 +1,7 @@
+//go:build !race
+// +build !race
+
+package cdb
+
+// raceEnabled tells that tests run with the race detector, which makes sync.Pool drop items
+const raceEnabled = false
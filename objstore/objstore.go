@@ -0,0 +1,286 @@
+// Package objstore implements io.ReaderAt over objects of cloud storages, like S3 or GCS.
+// An object is read by blocks, which are fetched in parallel and cached, so that a multi-gigabyte
+// database can be queried lazily. The package doesn't depend on storage SDKs, an Object adapter
+// issues ranged reads with the SDK of choice:
+//
+//	type s3Object struct {
+//		client *s3.Client
+//		bucket, key string
+//	}
+//
+//	func (o *s3Object) ReadRange(p []byte, off int64) error {
+//		out, err := o.client.GetObject(ctx, &s3.GetObjectInput{
+//			Bucket: &o.bucket,
+//			Key:    &o.key,
+//			Range:  aws.String(fmt.Sprintf("bytes=%d-%d", off, off+int64(len(p))-1)),
+//		})
+//		if err != nil {
+//			return err
+//		}
+//		defer out.Body.Close()
+//
+//		_, err = io.ReadFull(out.Body, p)
+//		return err
+//	}
+//
+//	reader, err := cdb.New().GetReader(objstore.New(&s3Object{client, bucket, key}, size))
+package objstore
+
+import (
+	"container/list"
+	"errors"
+	"io"
+	"sync"
+)
+
+const (
+	// DefaultBlockSize is the default size of blocks, which are fetched and cached
+	DefaultBlockSize = 64 << 10
+	// DefaultCacheBlocks is the default number of cached blocks
+	DefaultCacheBlocks = 256
+	// DefaultParallelism is the default number of blocks, which are fetched at once
+	DefaultParallelism = 4
+)
+
+// ErrInvalidBlockSize tells that the requested block size is not positive
+var ErrInvalidBlockSize = errors.New("objstore block size must be positive")
+
+// ErrInvalidParallelism tells that the requested number of parallel fetches is not positive
+var ErrInvalidParallelism = errors.New("objstore parallelism must be positive")
+
+// ErrNegativeOffset tells that it was an attempt to read at a negative offset
+var ErrNegativeOffset = errors.New("objstore negative offset")
+
+// Object is a ranged reader of a stored object. ReadRange must be safe for concurrent use.
+type Object interface {
+	// ReadRange reads exactly len(p) bytes of the object starting from the given offset
+	ReadRange(p []byte, off int64) error
+}
+
+// ReaderAt implements io.ReaderAt over an Object. The object is read by blocks, recently
+// read blocks are cached. ReaderAt is safe for concurrent use, the object must not change
+// while it is read.
+type ReaderAt struct {
+	object      Object
+	size        int64
+	blockSize   int64
+	parallelism int
+
+	mu sync.Mutex
+	// lru keeps *block objects from the most to the least recently used one
+	lru       *list.List
+	blocks    map[int64]*list.Element
+	maxBlocks int
+}
+
+// block is a cached block of the object
+type block struct {
+	index int64
+	data  []byte
+}
+
+// New returns a ReaderAt over the given object of the given size
+func New(object Object, size int64) *ReaderAt {
+	return &ReaderAt{
+		object:      object,
+		size:        size,
+		blockSize:   DefaultBlockSize,
+		parallelism: DefaultParallelism,
+		lru:         list.New(),
+		blocks:      make(map[int64]*list.Element),
+		maxBlocks:   DefaultCacheBlocks,
+	}
+}
+
+// SetBlockSize sets the size of blocks, which are fetched by a single ranged read.
+// Cached blocks are dropped.
+func (r *ReaderAt) SetBlockSize(n int) error {
+	if n <= 0 {
+		return ErrInvalidBlockSize
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.blockSize = int64(n)
+	r.lru.Init()
+	r.blocks = make(map[int64]*list.Element)
+
+	return nil
+}
+
+// SetCacheBlocks sets the maximal number of cached blocks, 0 disables the cache
+func (r *ReaderAt) SetCacheBlocks(n int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.maxBlocks = n
+	r.evict()
+}
+
+// SetParallelism sets the maximal number of blocks, which a single ReadAt fetches at once
+func (r *ReaderAt) SetParallelism(n int) error {
+	if n <= 0 {
+		return ErrInvalidParallelism
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.parallelism = n
+
+	return nil
+}
+
+// Size returns the size of the object
+func (r *ReaderAt) Size() int64 {
+	return r.size
+}
+
+// CachedBlocks returns the number of cached blocks
+func (r *ReaderAt) CachedBlocks() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.lru.Len()
+}
+
+// ReadAt reads len(p) bytes of the object at the given offset. Missing blocks are fetched in parallel.
+func (r *ReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, ErrNegativeOffset
+	}
+
+	if off >= r.size || len(p) == 0 {
+		if len(p) == 0 {
+			return 0, nil
+		}
+
+		return 0, io.EOF
+	}
+
+	end := off + int64(len(p))
+	if end > r.size {
+		end = r.size
+	}
+
+	r.mu.Lock()
+	blockSize, parallelism := r.blockSize, r.parallelism
+	r.mu.Unlock()
+
+	first := off / blockSize
+	blocks, err := r.load(first, (end-1)/blockSize, blockSize, parallelism)
+	if err != nil {
+		return 0, err
+	}
+
+	n := 0
+
+	for i, data := range blocks {
+		if i == 0 {
+			data = data[off-first*blockSize:]
+		}
+
+		n += copy(p[n:end-off], data)
+	}
+
+	if n < len(p) {
+		return n, io.EOF
+	}
+
+	return n, nil
+}
+
+// load returns the blocks in range [first, last], fetching missing ones
+func (r *ReaderAt) load(first, last, blockSize int64, parallelism int) ([][]byte, error) {
+	blocks := make([][]byte, last-first+1)
+	var missing []int64
+
+	r.mu.Lock()
+
+	for index := first; index <= last; index++ {
+		if element, ok := r.blocks[index]; ok && r.blockSize == blockSize {
+			r.lru.MoveToFront(element)
+			blocks[index-first] = element.Value.(*block).data
+		} else {
+			missing = append(missing, index)
+		}
+	}
+
+	r.mu.Unlock()
+
+	if len(missing) == 0 {
+		return blocks, nil
+	}
+
+	var (
+		wg       sync.WaitGroup
+		errOnce  sync.Once
+		firstErr error
+		tokens   = make(chan struct{}, parallelism)
+	)
+
+	for _, index := range missing {
+		wg.Add(1)
+		tokens <- struct{}{}
+
+		go func(index int64) {
+			defer wg.Done()
+			defer func() { <-tokens }()
+
+			data, err := r.fetch(index, blockSize)
+			if err != nil {
+				errOnce.Do(func() { firstErr = err })
+				return
+			}
+
+			blocks[index-first] = data
+		}(index)
+	}
+
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+
+	r.mu.Lock()
+
+	for _, index := range missing {
+		if _, ok := r.blocks[index]; !ok && r.blockSize == blockSize && r.maxBlocks > 0 {
+			r.blocks[index] = r.lru.PushFront(&block{index, blocks[index-first]})
+		}
+	}
+
+	r.evict()
+	r.mu.Unlock()
+
+	return blocks, nil
+}
+
+// fetch reads the block with the given index
+func (r *ReaderAt) fetch(index, blockSize int64) ([]byte, error) {
+	off := index * blockSize
+	n := blockSize
+
+	if off+n > r.size {
+		n = r.size - off
+	}
+
+	data := make([]byte, n)
+
+	if err := r.object.ReadRange(data, off); err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+// evict drops the least recently used blocks over the limit
+func (r *ReaderAt) evict() {
+	for r.lru.Len() > r.maxBlocks {
+		element := r.lru.Back()
+		r.lru.Remove(element)
+		delete(r.blocks, element.Value.(*block).index)
+	}
+}
@@ -0,0 +1,89 @@
+package objstore
+
+import (
+	"io"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// testObject implements Object over a byte slice, counts ranged reads
+type testObject struct {
+	content []byte
+	reads   int32
+}
+
+func (o *testObject) ReadRange(p []byte, off int64) error {
+	atomic.AddInt32(&o.reads, 1)
+
+	if off+int64(len(p)) > int64(len(o.content)) {
+		return io.ErrUnexpectedEOF
+	}
+
+	copy(p, o.content[off:])
+
+	return nil
+}
+
+func newTestObject(size int) *testObject {
+	content := make([]byte, size)
+	for i := range content {
+		content[i] = byte(i)
+	}
+
+	return &testObject{content: content}
+}
+
+func TestReaderAt(t *testing.T) {
+	object := newTestObject(1000)
+
+	r := New(object, int64(len(object.content)))
+	require.Nil(t, r.SetBlockSize(100))
+	require.Nil(t, r.SetParallelism(2))
+
+	buf := make([]byte, 250)
+	n, err := r.ReadAt(buf, 175)
+	require.Nil(t, err)
+	require.Equal(t, 250, n)
+	require.Equal(t, object.content[175:425], buf)
+	require.Equal(t, int32(4), atomic.LoadInt32(&object.reads))
+
+	n, err = r.ReadAt(buf[:10], 300)
+	require.Nil(t, err)
+	require.Equal(t, 10, n)
+	require.Equal(t, object.content[300:310], buf[:10])
+	require.Equal(t, int32(4), atomic.LoadInt32(&object.reads), "Cached block must not be fetched again")
+
+	n, err = r.ReadAt(buf, 900)
+	require.Equal(t, io.EOF, err)
+	require.Equal(t, 100, n)
+	require.Equal(t, object.content[900:], buf[:n])
+
+	n, err = r.ReadAt(buf, 1000)
+	require.Equal(t, io.EOF, err)
+	require.Equal(t, 0, n)
+
+	_, err = r.ReadAt(buf, -1)
+	require.Equal(t, ErrNegativeOffset, err)
+}
+
+func TestCacheLimit(t *testing.T) {
+	object := newTestObject(1000)
+
+	r := New(object, int64(len(object.content)))
+	require.Nil(t, r.SetBlockSize(100))
+	r.SetCacheBlocks(2)
+
+	buf := make([]byte, 1000)
+	_, err := r.ReadAt(buf, 0)
+	require.Nil(t, err)
+	require.Equal(t, object.content, buf)
+	require.Equal(t, 2, r.CachedBlocks())
+
+	r.SetCacheBlocks(0)
+	require.Equal(t, 0, r.CachedBlocks())
+
+	require.Equal(t, ErrInvalidBlockSize, r.SetBlockSize(0))
+	require.Equal(t, ErrInvalidParallelism, r.SetParallelism(0))
+}
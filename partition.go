@@ -0,0 +1,101 @@
+package cdb
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// partFormat is the name format of part files of a partitioned database
+const partFormat = "part-%03d.cdb"
+
+// ErrMissingPart tells that the numbering of part files of a partitioned database has a gap
+var ErrMissingPart = errors.New("cdb partitioned database misses a part file")
+
+// CreatePartitioned creates n part files part-000.cdb … part-NNN.cdb in the given directory
+// and returns a sharded writer over them, see NewShardedWriter. If a part can't be created,
+// the parts created before are removed. Close of the returned writer commits and closes all parts.
+func (cdb *CDB) CreatePartitioned(dir string, n int) (Writer, error) {
+	if n <= 0 {
+		return nil, ErrNoShards
+	}
+
+	parts := make([]Writer, 0, n)
+
+	for i := 0; i < n; i++ {
+		part, err := cdb.Create(filepath.Join(dir, fmt.Sprintf(partFormat, i)))
+		if err != nil {
+			abortWriters(parts)
+			return nil, err
+		}
+
+		parts = append(parts, part)
+	}
+
+	return cdb.NewShardedWriter(parts)
+}
+
+// OpenPartitioned opens the part files part-000.cdb … part-NNN.cdb of the given directory
+// and returns a sharded reader over them, see NewShardedReader. The parts must be written
+// by CreatePartitioned or NewShardedWriter of a handle with the same Hasher.
+// Close of the returned reader closes all parts.
+func (cdb *CDB) OpenPartitioned(dir string) (Reader, error) {
+	paths, err := partPaths(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	parts := make([]Reader, 0, len(paths))
+
+	for _, path := range paths {
+		part, err := cdb.Open(path)
+		if err != nil {
+			(&shardedReader{parts: parts}).Close()
+			return nil, err
+		}
+
+		parts = append(parts, part)
+	}
+
+	return cdb.NewShardedReader(parts)
+}
+
+// partPaths returns the paths of part files of the given directory in the order of parts
+func partPaths(dir string) ([]string, error) {
+	matches, err := filepath.Glob(filepath.Join(dir, "part-*.cdb"))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(matches) == 0 {
+		return nil, ErrNoShards
+	}
+
+	paths := make([]string, len(matches))
+
+	for _, path := range matches {
+		number := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(path), "part-"), ".cdb")
+
+		i, err := strconv.Atoi(number)
+		if err != nil || i < 0 || i >= len(paths) {
+			return nil, ErrMissingPart
+		}
+
+		if paths[i] != "" {
+			return nil, ErrMissingPart
+		}
+
+		paths[i] = path
+	}
+
+	return paths, nil
+}
+
+// abortWriters aborts the given writers, so that their files are released and removed
+func abortWriters(writers []Writer) {
+	for _, writer := range writers {
+		writer.Abort()
+	}
+}
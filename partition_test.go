@@ -0,0 +1,57 @@
+package cdb
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+func (suite *CDBTestSuite) TestPartitioned() {
+	dir, err := ioutil.TempDir("", "test_cdb")
+	suite.Require().Nil(err)
+	defer os.RemoveAll(dir)
+
+	writer, err := suite.cdbHandle.CreatePartitioned(dir, 3)
+	suite.Require().Nil(err)
+
+	for _, rec := range suite.testRecords {
+		suite.Require().Nil(writer.Put(rec.key, rec.val))
+	}
+
+	suite.Require().Nil(writer.Close())
+
+	for _, name := range []string{"part-000.cdb", "part-001.cdb", "part-002.cdb"} {
+		_, err := os.Stat(filepath.Join(dir, name))
+		suite.Nil(err)
+	}
+
+	reader, err := suite.cdbHandle.OpenPartitioned(dir)
+	suite.Require().Nil(err)
+	suite.Equal(len(suite.testRecords), reader.Size())
+	suite.Equal(len(suite.testRecords), suite.countIteratedRecords(reader))
+
+	for _, rec := range suite.testRecords {
+		value, err := reader.Get(rec.key)
+		suite.Nil(err)
+		suite.Equal(rec.val, value)
+	}
+
+	suite.Nil(reader.Close())
+
+	suite.Require().Nil(os.Remove(filepath.Join(dir, "part-001.cdb")))
+	_, err = suite.cdbHandle.OpenPartitioned(dir)
+	suite.Equal(ErrMissingPart, err)
+
+	_, err = suite.cdbHandle.OpenPartitioned(filepath.Join(dir, "missing"))
+	suite.Equal(ErrNoShards, err)
+
+	// A failed creation leaves no part behind
+	failed := filepath.Join(dir, "failed")
+	suite.Require().Nil(os.MkdirAll(filepath.Join(failed, "part-001.cdb"), 0755))
+
+	_, err = suite.cdbHandle.CreatePartitioned(failed, 3)
+	suite.NotNil(err)
+
+	_, err = os.Stat(filepath.Join(failed, "part-000.cdb"))
+	suite.True(os.IsNotExist(err), "created parts must be removed")
+}
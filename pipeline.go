@@ -0,0 +1,157 @@
+package cdb
+
+// Number of records hashed by a worker at once
+const hashBatchSize = 1024
+
+// SetHashWorkers tells writers to hash keys in n background goroutines, which pays off for bulk
+// loads of long keys. Records are still written in the Put order by the goroutine calling Put,
+// the hashes are collected in that order too, so the database is the same as without workers.
+// Close or Abort stops the workers, 0 disables them. Like SetHash, it affects only new instances of Writer.
+func (cdb *CDB) SetHashWorkers(n int) {
+	cdb.opts.hashWorkers = n
+}
+
+// hashBatch is a batch of records, which slots are computed by a hashing worker
+type hashBatch struct {
+	// keys holds the bucket names and the keys of records one after another, ends are their ends
+	keys      []byte
+	ends      []int
+	positions []uint32
+	slots     []slot
+	done      chan struct{}
+}
+
+// hashPipeline distributes batches of records over hashing workers
+type hashPipeline struct {
+	batches chan *hashBatch
+	// current is the batch being filled, pending are the batches being hashed in the Put order
+	current *hashBatch
+	pending []*hashBatch
+	workers int
+}
+
+// newHashPipeline starts n hashing workers of the writer
+func (w *writerImpl) newHashPipeline(n int) *hashPipeline {
+	p := &hashPipeline{
+		batches: make(chan *hashBatch, n),
+		workers: n,
+	}
+
+	for i := 0; i < n; i++ {
+		go w.hashWorker(p.batches)
+	}
+
+	return p
+}
+
+// hashWorker computes slots of the batches until the channel is closed
+func (w *writerImpl) hashWorker(batches <-chan *hashBatch) {
+	hashFunc := w.hasher()
+
+	for batch := range batches {
+		start := 0
+
+		for i, end := range batch.ends {
+			hashFunc.Reset()
+			hashFunc.Write(batch.keys[start:end])
+			h, hi := w.header.keyHash(hashFunc)
+
+			batch.slots[i] = slot{h, batch.positions[i], hi}
+			start = end
+		}
+
+		close(batch.done)
+	}
+}
+
+// enqueue adds the record at the given position to the current batch, full batches are sent to workers
+func (w *writerImpl) enqueue(bucketName, key []byte, position uint32) error {
+	p := w.pipeline
+
+	if p.current == nil {
+		p.current = &hashBatch{
+			keys:      make([]byte, 0, hashBatchSize*(len(bucketName)+len(key))),
+			ends:      make([]int, 0, hashBatchSize),
+			positions: make([]uint32, 0, hashBatchSize),
+		}
+	}
+
+	b := p.current
+	b.keys = append(append(b.keys, bucketName...), key...)
+	b.ends = append(b.ends, len(b.keys))
+	b.positions = append(b.positions, position)
+
+	if len(b.ends) < hashBatchSize {
+		return nil
+	}
+
+	return w.dispatch()
+}
+
+// dispatch sends the current batch to workers. Hashed batches are collected, once there are
+// twice as many pending batches as workers, so memory of the pipeline is bounded.
+func (w *writerImpl) dispatch() error {
+	p := w.pipeline
+
+	if b := p.current; b != nil {
+		b.slots = make([]slot, len(b.ends))
+		b.done = make(chan struct{})
+		p.pending = append(p.pending, b)
+		p.current = nil
+		p.batches <- b
+	}
+
+	for len(p.pending) > 2*p.workers {
+		if err := w.collect(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// collect waits for the oldest pending batch and adds its slots to hash tables
+func (w *writerImpl) collect() error {
+	p := w.pipeline
+	b := p.pending[0]
+	p.pending = p.pending[1:]
+
+	<-b.done
+
+	for _, s := range b.slots {
+		if err := w.addSlot(s); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// drain adds slots of all enqueued records to hash tables
+func (w *writerImpl) drain() error {
+	if w.pipeline == nil {
+		return nil
+	}
+
+	if err := w.dispatch(); err != nil {
+		return err
+	}
+
+	for len(w.pipeline.pending) > 0 {
+		if err := w.collect(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// stopWorkers stops the hashing workers, enqueued records are dropped
+func (w *writerImpl) stopWorkers() {
+	if w.pipeline == nil {
+		return
+	}
+
+	close(w.pipeline.batches)
+	w.pipeline = nil
+}
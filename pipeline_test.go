@@ -0,0 +1,50 @@
+package cdb
+
+import (
+	"io/ioutil"
+	"strconv"
+)
+
+// buildPipelineTestCDB writes several batches of records into two buckets, returns the database
+func (suite *CDBTestSuite) buildPipelineTestCDB() []byte {
+	suite.resetTestCDB()
+
+	writer := suite.getCDBWriter()
+
+	for i := 0; i < 3*hashBatchSize+7; i++ {
+		key := []byte("some/long/common/prefix/" + strconv.Itoa(i%2000))
+		suite.Require().Nil(writer.Put(key, []byte(strconv.Itoa(i))))
+		suite.Require().Nil(writer.Bucket("other").Put(key, nil))
+	}
+
+	suite.Require().Nil(writer.Close())
+
+	data, err := ioutil.ReadFile(suite.cdbFile.Name())
+	suite.Require().Nil(err)
+
+	return data
+}
+
+func (suite *CDBTestSuite) TestHashWorkers() {
+	for _, configure := range []func(){
+		func() {},
+		func() { suite.cdbHandle.SetVersions(1) },
+		func() { suite.cdbHandle.SetSpill("", 100) },
+	} {
+		suite.cdbHandle = New()
+		suite.cdbHandle.SetBuckets(true)
+		configure()
+		expected := suite.buildPipelineTestCDB()
+
+		suite.cdbHandle.SetHashWorkers(3)
+		suite.Equal(expected, suite.buildPipelineTestCDB(), "workers don't change the database")
+
+		value, err := suite.getCDBReader().Get([]byte("some/long/common/prefix/5"))
+		suite.Nil(err)
+		suite.NotEmpty(value)
+	}
+
+	writer := suite.getCDBWriter()
+	suite.Require().Nil(writer.Put([]byte("key"), []byte("value")))
+	suite.Nil(writer.Abort())
+}
@@ -0,0 +1,172 @@
+package cdb
+
+// Position describes the progress of an iterator, see Iterator.Position
+type Position struct {
+	// Records is the number of records the iterator has pointed on, the current one included
+	Records int
+	// Offset is the offset of the current record, see Iterator.Offset
+	Offset uint32
+	// Done is the approximate fraction of the walk, which is done, from 0 to 1.
+	// Iterators over several databases count every database as an equal share.
+	Done float64
+}
+
+// skipNext moves the iterator n records forward by calls of Next, see Iterator.Skip
+func skipNext(iterator Iterator, n int) (int, error) {
+	for k := 0; k < n; k++ {
+		ok, err := iterator.Next()
+		if err != nil || !ok {
+			return k, err
+		}
+	}
+
+	return n, nil
+}
+
+// fraction returns done divided by total, 1 if there is nothing to do
+func fraction(done, total int) float64 {
+	if total <= 0 {
+		return 1
+	}
+
+	return float64(done) / float64(total)
+}
+
+// skips tells if iterators may skip records of the database, see readerImpl.skip
+func (r *readerImpl) skips() bool {
+	return r.header.flags&(flagRecordFlags|flagBuckets) != 0 || (r.opts.skipExpired && r.header.flags&flagExpiry != 0)
+}
+
+// Skip moves the iterator n records forward, see Iterator.Skip
+func (i *iterator) Skip(n int) (int, error) {
+	return skipNext(i, n)
+}
+
+// Position returns the progress of the iterator, the done fraction is the share of walked bytes
+func (i *iterator) Position() Position {
+	return Position{
+		Records: i.records,
+		Offset:  i.current,
+		Done:    fraction(int(i.position)-int(i.start), int(i.end)-int(i.start)),
+	}
+}
+
+// Skip moves the iterator n records forward, see Iterator.Skip. Unless the database has records,
+// which iterators skip, records are skipped by their numbers in the sorted index without reading them.
+func (i *indexIterator) Skip(n int) (int, error) {
+	if n <= 0 {
+		return 0, nil
+	}
+
+	if i.cdbReader.skips() {
+		return skipNext(i, n)
+	}
+
+	left := i.end - i.next
+	if i.reverse {
+		left = i.next - i.start + 1
+	}
+
+	if n > left {
+		n = left
+	}
+
+	if n == 0 {
+		return 0, nil
+	}
+
+	if i.reverse {
+		i.next -= n - 1
+	} else {
+		i.next += n - 1
+	}
+
+	if _, err := i.Next(); err != nil {
+		return 0, err
+	}
+
+	// Next counts the last skipped record only
+	i.records += n - 1
+
+	return n, nil
+}
+
+// Position returns the progress of the iterator, the done fraction is the share of walked records
+func (i *indexIterator) Position() Position {
+	walked := i.next - i.start
+	if i.reverse {
+		walked = i.end - 1 - i.next
+	}
+
+	return Position{
+		Records: i.records,
+		Offset:  i.current,
+		Done:    fraction(walked, i.end-i.start),
+	}
+}
+
+// Skip moves the iterator n records forward, see Iterator.Skip
+func (i *concatIterator) Skip(n int) (int, error) {
+	return skipNext(i, n)
+}
+
+// Position returns the progress of the iterator, see Iterator.Position
+func (i *concatIterator) Position() Position {
+	inner := i.iterators[0].Position()
+	total := len(i.all)
+
+	if total == 0 {
+		total = len(i.iterators)
+	}
+
+	return Position{
+		Records: i.walked + inner.Records,
+		Offset:  inner.Offset,
+		Done:    (float64(total-len(i.iterators)) + inner.Done) / float64(total),
+	}
+}
+
+// Skip moves the iterator n records forward, see Iterator.Skip
+func (i *mergeIterator) Skip(n int) (int, error) {
+	return skipNext(i, n)
+}
+
+// Position returns the progress of the iterator, see Iterator.Position
+func (i *mergeIterator) Position() Position {
+	// The current records of the iterators, except the merged one, are pending
+	p := Position{Records: 1 - len(i.iterators), Offset: i.iterators[i.current].Offset()}
+
+	for _, iterator := range i.all {
+		inner := iterator.Position()
+		p.Records += inner.Records
+		p.Done += inner.Done / float64(len(i.all))
+	}
+
+	return p
+}
+
+// Skip moves the iterator n records forward, see Iterator.Skip
+func (i *stackIterator) Skip(n int) (int, error) {
+	return skipNext(i, n)
+}
+
+// Position returns the progress of the iterator, records are the visible ones, see Iterator.Position
+func (i *stackIterator) Position() Position {
+	p := i.Iterator.Position()
+	p.Records = i.records + 1
+
+	return p
+}
+
+// Skip moves the iterator n records forward, see Iterator.Skip
+func (i *filterIterator) Skip(n int) (int, error) {
+	return skipNext(i, n)
+}
+
+// Position returns the progress of the iterator, records are the accepted ones, see Iterator.Position
+func (i *filterIterator) Position() Position {
+	p := i.Iterator.Position()
+	p.Records = i.records + 1
+
+	return p
+}
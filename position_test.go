@@ -0,0 +1,114 @@
+package cdb
+
+import (
+	"bytes"
+	"strconv"
+)
+
+func (suite *CDBTestSuite) TestIteratorSkip() {
+	suite.fillTestCDB()
+	iterator := suite.mustGetCDBIterator()
+
+	p := iterator.Position()
+	suite.Equal(1, p.Records)
+	suite.InDelta(0.1, p.Done, 0.01, "test records have the same size")
+
+	skipped, err := iterator.Skip(4)
+	suite.Nil(err)
+	suite.Equal(4, skipped)
+	suite.EqualKeyValue(iterator, suite.testRecords[4])
+
+	p = iterator.Position()
+	suite.Equal(5, p.Records)
+	suite.Equal(iterator.Offset(), p.Offset)
+	suite.InDelta(0.5, p.Done, 0.01)
+
+	skipped, err = iterator.Skip(10)
+	suite.Nil(err)
+	suite.Equal(5, skipped)
+	suite.EqualKeyValue(iterator, suite.testRecords[9])
+	suite.Equal(10, iterator.Position().Records)
+	suite.Equal(1.0, iterator.Position().Done)
+}
+
+func (suite *CDBTestSuite) TestIndexIteratorSkip() {
+	index := &bytes.Buffer{}
+	writer, err := suite.cdbHandle.GetWriterWithIndex(suite.cdbFile, index)
+	suite.Require().Nil(err)
+
+	for i := 9; i >= 0; i-- {
+		suite.Require().Nil(writer.Put([]byte(strconv.Itoa(i)), nil))
+	}
+
+	suite.Require().Nil(writer.Close())
+
+	reader, err := suite.cdbHandle.GetReaderWithIndex(suite.cdbFile, bytes.NewReader(index.Bytes()))
+	suite.Require().Nil(err)
+
+	for _, reverse := range []bool{false, true} {
+		open := reader.OrderedIterator
+		if reverse {
+			open = reader.ReverseIterator
+		}
+
+		iterator, err := open()
+		suite.Require().Nil(err)
+
+		skipped, err := iterator.Skip(3)
+		suite.Nil(err)
+		suite.Equal(3, skipped)
+
+		key, err := iterator.Key()
+		suite.Nil(err)
+
+		if reverse {
+			suite.Equal("6", string(key))
+		} else {
+			suite.Equal("3", string(key))
+		}
+
+		suite.Equal(Position{Records: 4, Offset: iterator.Offset(), Done: 0.4}, iterator.Position())
+
+		skipped, err = iterator.Skip(100)
+		suite.Nil(err)
+		suite.Equal(6, skipped)
+		suite.Equal(10, iterator.Position().Records)
+		suite.Equal(1.0, iterator.Position().Done)
+		suite.False(iterator.HasNext())
+	}
+}
+
+func (suite *CDBTestSuite) TestShardedIteratorPosition() {
+	files := suite.createShardFiles(2)
+	defer suite.removeShardFiles(files)
+
+	readers := make([]Reader, len(files))
+
+	for i, f := range files {
+		writer, err := suite.cdbHandle.GetWriter(f)
+		suite.Require().Nil(err)
+
+		for _, rec := range suite.testRecords {
+			suite.Require().Nil(writer.Put(rec.key, rec.val))
+		}
+
+		suite.Require().Nil(writer.Close())
+
+		readers[i], err = suite.cdbHandle.GetReader(f)
+		suite.Require().Nil(err)
+	}
+
+	reader, err := suite.cdbHandle.NewShardedReader(readers)
+	suite.Require().Nil(err)
+
+	iterator, err := reader.Iterator()
+	suite.Require().Nil(err)
+
+	skipped, err := iterator.Skip(14)
+	suite.Nil(err)
+	suite.Equal(14, skipped)
+
+	p := iterator.Position()
+	suite.Equal(15, p.Records)
+	suite.InDelta(0.75, p.Done, 0.01)
+}
@@ -0,0 +1,52 @@
+package cdb
+
+import "bytes"
+
+// IteratePrefix returns a new Iterator object, which walks records with keys starting with the given prefix,
+// see Reader.IteratePrefix
+func (r *readerImpl) IteratePrefix(prefix []byte) (Iterator, error) {
+	return iteratePrefix(r, prefix)
+}
+
+// iteratePrefix returns a new Iterator object, which walks records of the reader with keys starting
+// with the given prefix. Records are walked in the key order with the sorted index, otherwise
+// every record of the reader is scanned in the file order. Returns nil if there is no such record.
+func iteratePrefix(reader Reader, prefix []byte) (Iterator, error) {
+	iterator, err := reader.Range(prefix, prefixEnd(prefix))
+	if err != ErrNoIndex {
+		if err == ErrEntryNotFound {
+			return nil, nil
+		}
+
+		return iterator, err
+	}
+
+	iterator, err = reader.Iterator()
+
+	if err == ErrEmptyCDB {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return newFilterIterator(iterator, nil, func(key, _ []byte) (bool, error) {
+		return bytes.HasPrefix(key, prefix), nil
+	})
+}
+
+// prefixEnd returns the least key, which is greater than all keys starting with the given prefix.
+// Returns nil if there is no such key, i.e. the prefix consists of 0xff bytes only.
+func prefixEnd(prefix []byte) []byte {
+	for i := len(prefix) - 1; i >= 0; i-- {
+		if prefix[i] != 0xff {
+			end := append([]byte(nil), prefix[:i+1]...)
+			end[i]++
+
+			return end
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,53 @@
+package cdb
+
+import (
+	"bytes"
+)
+
+func (suite *CDBTestSuite) TestIteratePrefix() {
+	keys := []string{"user/2", "group/1", "user/1", "user", "users/1", "user/3"}
+
+	index := &bytes.Buffer{}
+	writer, err := suite.cdbHandle.GetWriterWithIndex(suite.cdbFile, index)
+	suite.Require().Nil(err)
+
+	for _, key := range keys {
+		suite.Require().Nil(writer.Put([]byte(key), []byte(key)))
+	}
+
+	suite.Require().Nil(writer.Close())
+
+	indexed, err := suite.cdbHandle.GetReaderWithIndex(suite.cdbFile, bytes.NewReader(index.Bytes()))
+	suite.Require().Nil(err)
+
+	walk := func(reader Reader, prefix string) []string {
+		iterator, err := reader.IteratePrefix([]byte(prefix))
+		suite.Require().Nil(err)
+
+		var actual []string
+
+		for ok := iterator != nil; ok; ok = suite.mustNext(iterator) {
+			key, err := iterator.Key()
+			suite.Require().Nil(err)
+			actual = append(actual, string(key))
+		}
+
+		return actual
+	}
+
+	suite.Equal([]string{"user/1", "user/2", "user/3"}, walk(indexed, "user/"))
+	suite.Equal([]string{"user/2", "user/1", "user/3"}, walk(suite.getCDBReader(), "user/"), "The scan keeps the file order")
+
+	for _, reader := range []Reader{indexed, suite.getCDBReader()} {
+		suite.Len(walk(reader, ""), len(keys))
+		suite.Len(walk(reader, "user"), 5)
+		suite.Nil(walk(reader, "x"))
+	}
+}
+
+func (suite *CDBTestSuite) TestPrefixEnd() {
+	suite.Equal([]byte("ab"), prefixEnd([]byte("aa")))
+	suite.Equal([]byte("b"), prefixEnd([]byte("a\xff\xff")))
+	suite.Nil(prefixEnd([]byte("\xff\xff")))
+	suite.Nil(prefixEnd(nil))
+}
@@ -0,0 +1,81 @@
+package cdb
+
+import "time"
+
+// BuildPhase is the phase of a database build, see BuildProgress
+type BuildPhase int
+
+const (
+	// BuildPutting is the phase of Put calls
+	BuildPutting BuildPhase = iota
+	// BuildClosing is the phase of Close, which writes hash tables
+	BuildClosing
+)
+
+// BuildProgress describes the progress of a writer, see CDB.SetProgress
+type BuildProgress struct {
+	Phase BuildPhase
+	// Records is the number of put records
+	Records int
+	// Tables is the number of written hash tables out of TableNum ones, they are written by Close
+	Tables, TableNum int
+	// Elapsed is the time since the writer is created
+	Elapsed time.Duration
+	// ETA is the estimated remaining time of the phase, 0 if it is unknown. The ETA of putting
+	// requires the expected number of records, see SetExpectedRecords.
+	ETA time.Duration
+}
+
+// SetProgress sets the progress hook of writers: fn is called after every n put records
+// and before and after every hash table written by Close, so build tools can display progress
+// and export metrics. A non-positive n reports the progress of Close only, nil fn disables the hook,
+// it's the default. fn is called by the goroutine calling Put and Close.
+// Like SetHash, it affects only new instances of Writer.
+func (cdb *CDB) SetProgress(fn func(BuildProgress), n int) {
+	cdb.opts.progress = fn
+	cdb.opts.progressEvery = n
+}
+
+// eta estimates the remaining time of a phase, which took elapsed to do done out of total steps
+func eta(elapsed time.Duration, done, total int) time.Duration {
+	if done <= 0 || total <= done {
+		return 0
+	}
+
+	return time.Duration(float64(elapsed) * float64(total-done) / float64(done))
+}
+
+// reportPut reports the progress of putting, if it is due
+func (w *writerImpl) reportPut() {
+	if w.progress == nil || w.progressEvery <= 0 || w.records%w.progressEvery != 0 {
+		return
+	}
+
+	elapsed := w.now().Sub(w.started)
+
+	w.progress(BuildProgress{
+		Phase:    BuildPutting,
+		Records:  w.records,
+		TableNum: len(w.tables),
+		Elapsed:  elapsed,
+		ETA:      eta(elapsed, w.records, w.expectedRecords),
+	})
+}
+
+// reportTables reports the progress of Close, which started at the given time and wrote n hash tables
+func (w *writerImpl) reportTables(n int, closing time.Time) {
+	if w.progress == nil {
+		return
+	}
+
+	now := w.now()
+
+	w.progress(BuildProgress{
+		Phase:    BuildClosing,
+		Records:  w.records,
+		Tables:   n,
+		TableNum: len(w.tables),
+		Elapsed:  now.Sub(w.started),
+		ETA:      eta(now.Sub(closing), n, len(w.tables)),
+	})
+}
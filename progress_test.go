@@ -0,0 +1,33 @@
+package cdb
+
+import "time"
+
+func (suite *CDBTestSuite) TestSetProgress() {
+	now := time.Now()
+	suite.cdbHandle.opts.now = func() time.Time {
+		now = now.Add(time.Second)
+		return now
+	}
+
+	var reports []BuildProgress
+
+	suite.cdbHandle.SetExpectedRecords(10)
+	suite.cdbHandle.SetProgress(func(p BuildProgress) { reports = append(reports, p) }, 4)
+	suite.Require().Nil(suite.cdbHandle.SetTableNum(2))
+	suite.fillTestCDB()
+
+	// Putting is reported after 4 and 8 records, Close before and after every table
+	suite.Require().Len(reports, 5)
+
+	suite.Equal(BuildProgress{Phase: BuildPutting, Records: 4, TableNum: 2, Elapsed: time.Second, ETA: 1500 * time.Millisecond}, reports[0])
+	suite.Equal(BuildProgress{Phase: BuildPutting, Records: 8, TableNum: 2, Elapsed: 2 * time.Second, ETA: 500 * time.Millisecond}, reports[1])
+
+	for i, p := range reports[2:] {
+		suite.Equal(BuildClosing, p.Phase)
+		suite.Equal(10, p.Records)
+		suite.Equal(i, p.Tables)
+	}
+
+	suite.Equal(2*time.Second, reports[3].ETA, "the first table took two ticks of the clock")
+	suite.Zero(reports[4].ETA)
+}
@@ -0,0 +1,71 @@
+package cdb
+
+import (
+	"encoding/binary"
+	"io"
+)
+
+// PutReader saves a new associated pair <key, value>, the value is read from r, see Writer.PutReader
+func (w *writerImpl) PutReader(key []byte, r io.Reader, size int64) error {
+	return w.writeRecord(key, nil, r, size, recordMeta{})
+}
+
+// PutReader saves a new associated pair <key, value> into the bucket, the value is read from r,
+// see Writer.PutReader
+func (w *bucketWriter) PutReader(key []byte, r io.Reader, size int64) error {
+	if w.id == 0 {
+		return ErrBucketsDisabled
+	}
+
+	return w.writeRecord(key, nil, r, size, recordMeta{bucket: w.id})
+}
+
+// PutReader saves a new associated pair <key, value> into the part of the key, see Writer.PutReader
+func (w *shardedWriter) PutReader(key []byte, r io.Reader, size int64) error {
+	return w.parts[shardOf(w.hasher, key, len(w.parts))].PutReader(key, r, size)
+}
+
+// writeValue copies the value of a record from r to the buffer, returns the size of the value.
+// A value of an unknown size is copied until the end of r, then its size field, which is reserved
+// at the given position of the record header, is patched.
+func (w *writerImpl) writeValue(r io.Reader, size int64, valuePosition uint32) (int, error) {
+	if size >= 0 {
+		n, err := io.CopyN(w.buffer, r, size)
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+
+		return int(n), err
+	}
+
+	// The limit stops the copy of an endless reader right after the 4 gb boundary
+	limit := int64(maxUint) - int64(valuePosition) + 1
+
+	n, err := io.Copy(w.buffer, io.LimitReader(r, limit))
+	if err != nil {
+		return int(n), err
+	}
+
+	if n == limit {
+		return int(n), ErrTooBig
+	}
+
+	if err := w.buffer.Flush(); err != nil {
+		return int(n), err
+	}
+
+	// The value size is the field next to the key size, see writerImpl.writeRecordHeader
+	position := w.begin + w.current
+
+	if _, err := w.writer.Seek(position+4, io.SeekStart); err != nil {
+		return int(n), err
+	}
+
+	if err := binary.Write(w.writer, binary.LittleEndian, uint32(n)); err != nil {
+		return int(n), err
+	}
+
+	_, err = w.writer.Seek(w.begin+int64(valuePosition)+n, io.SeekStart)
+
+	return int(n), err
+}
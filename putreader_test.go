@@ -0,0 +1,57 @@
+package cdb
+
+import (
+	"bytes"
+	"io"
+	"strings"
+)
+
+func (suite *CDBTestSuite) TestPutReader() {
+	suite.cdbHandle.SetKeyPrefixCompression(true)
+	suite.cdbHandle.SetBuckets(true)
+
+	large := bytes.Repeat([]byte("0123456789"), 10000)
+
+	writer := suite.getCDBWriter()
+	suite.Require().Nil(writer.PutReader([]byte("common/prefix/sized"), bytes.NewReader(large), int64(len(large))))
+	// MultiReader hides the size of the value
+	suite.Require().Nil(writer.PutReader([]byte("common/prefix/unsized"), io.MultiReader(bytes.NewReader(large)), -1))
+	suite.Require().Nil(writer.Bucket("other").PutReader([]byte("empty"), strings.NewReader(""), -1))
+	suite.Require().Nil(writer.Put([]byte("after"), []byte("value")))
+	suite.Require().Nil(writer.Close())
+
+	reader := suite.getCDBReader()
+
+	for _, key := range []string{"common/prefix/sized", "common/prefix/unsized"} {
+		value, err := reader.Get([]byte(key))
+		suite.Nil(err)
+		suite.Equal(large, value)
+	}
+
+	value, err := reader.Get([]byte("after"))
+	suite.Nil(err)
+	suite.Equal([]byte("value"), value)
+
+	value, err = reader.Bucket("other").Get([]byte("empty"))
+	suite.Nil(err)
+	suite.Empty(value)
+}
+
+func (suite *CDBTestSuite) TestPutReaderShort() {
+	writer := suite.getCDBWriter()
+	suite.Equal(io.ErrUnexpectedEOF, writer.PutReader([]byte("key"), strings.NewReader("abc"), 4))
+	suite.Nil(writer.Abort())
+}
+
+func (suite *CDBTestSuite) TestPutReaderFixedValueSize() {
+	suite.Require().Nil(suite.cdbHandle.SetFixedValueSize(3))
+
+	writer := suite.getCDBWriter()
+	suite.Equal(ErrInvalidValueSize, writer.PutReader([]byte("a"), strings.NewReader("abcd"), 4))
+	suite.Require().Nil(writer.PutReader([]byte("b"), strings.NewReader("xyz"), -1))
+	suite.Require().Nil(writer.Close())
+
+	value, err := suite.getCDBReader().Get([]byte("b"))
+	suite.Nil(err)
+	suite.Equal([]byte("xyz"), value)
+}
@@ -2,14 +2,72 @@ package cdb
 
 import (
 	"bytes"
+	"context"
 	"encoding/binary"
 	"errors"
+	"fmt"
+	"hash"
 	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
-// EntryDoesNotExists could be returned for Get method is cdb has no such key
+const (
+	// Initial size of scratch buffers, enough for a record header and a short key
+	scratchSize = 64
+	// Size of the speculative read of a record, which usually covers its header, key and value
+	recordPrefetchSize = 512
+)
+
+// ErrEntryNotFound is returned by every lookup of a missing key: Get and its variants, IteratorAt and GetAt.
+// It is never wrapped, so both err == ErrEntryNotFound and errors.Is work. See GetOrDefault.
 var ErrEntryNotFound = errors.New("cdb entry not found")
 
+// ErrInvalidOffset tells that there is no record at the given offset
+var ErrInvalidOffset = errors.New("cdb has no record at the offset")
+
+// ErrCorrupted tells that a lookup met data, which can't belong to a valid database
+var ErrCorrupted = errors.New("cdb is corrupted")
+
+// CorruptionError describes the damaged place of a database. It stands for ErrCorrupted,
+// see Is. Offset is relative to the database start, Table is -1 if no hash table is involved.
+type CorruptionError struct {
+	Offset int64
+	Table  int
+	Reason string
+}
+
+// Error returns the description of the damage
+func (e *CorruptionError) Error() string {
+	if e.Table < 0 {
+		return fmt.Sprintf("cdb is corrupted at offset %d: %s", e.Offset, e.Reason)
+	}
+
+	return fmt.Sprintf("cdb is corrupted at offset %d, hash table %d: %s", e.Offset, e.Table, e.Reason)
+}
+
+// Is tells that the error is ErrCorrupted
+func (e *CorruptionError) Is(target error) bool {
+	return target == ErrCorrupted
+}
+
+// corrupted returns a CorruptionError, converting short reads. Other errors are returned as is.
+func corrupted(err error, offset int64, table int, reason string) error {
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return err
+	}
+
+	return &CorruptionError{offset, table, reason}
+}
+
+// ErrNoHash64 tells that the database uses a 64-bit hash, but the handle has a 32-bit one, see CDB.SetHash64
+var ErrNoHash64 = errors.New("cdb database uses a 64-bit hash, set it by SetHash64")
+
+// ErrInvalidRange tells that the requested range of a value is out of the value
+var ErrInvalidRange = errors.New("cdb value range is out of the value")
+
 // hashTableRef is a pointer that state a position and a length of the hash table
 // position is the starting byte position of the hash table.
 // The length is the number of slots in the hash table.
@@ -19,52 +77,214 @@ type hashTableRef struct {
 
 // readerImpl implements Reader interface
 type readerImpl struct {
-	refs   [tableNum]hashTableRef
+	refs   []hashTableRef
+	header header
 	reader io.ReaderAt
 	hasher Hasher
-	endPos uint32
-	size   int
+	// hashPool keeps hasher instances, so that concurrent lookups neither contend nor allocate
+	hashPool *sync.Pool
+	// bufPool keeps scratch buffers for reading record headers and keys
+	bufPool *sync.Pool
+	opts    options
+	index   *sortedIndex
+	bloom   *bloomFilter
+	endPos  uint32
+	size    int
+	// total is the number of records of all buckets, rootSize is the number of records of the root bucket
+	total, rootSize int
+	// bucket and bucketName are the id and the name of the bucket, which the reader gives access to
+	bucket     uint32
+	bucketName []byte
+	buckets    map[string]bucketInfo
+	// allBuckets tells iterators to walk records of all buckets
+	allBuckets bool
+	// mem is the whole database, if it is in memory, values are sliced from it without copying
+	mem []byte
+	// closer releases resources of the reader
+	closer io.Closer
+	// ctx is the context of lookups, it is set by withContext
+	ctx context.Context
+	// lazyStats keeps the statistics of hash tables, counters count lookups, see Stats
+	lazyStats *lazyStats
+	counters  *readerCounters
+	// owned is the hasher and the scratch buffers of a clone, nil for a reader shared by goroutines
+	owned *ownedState
+}
+
+// ownedState keeps the hasher and the scratch buffers of a clone, see Reader.Clone
+type ownedState struct {
+	hashFunc hash.Hash32
+	// buffers is a stack of free scratch buffers, lookups take a few of them at once
+	buffers []*[]byte
 }
 
 // newReader returns a new readerImpl object on success, otherwise returns nil and an error
-func newReader(reader io.ReaderAt, hasher Hasher) (*readerImpl, error) {
+func newReader(reader io.ReaderAt, hasher Hasher, opts options) (*readerImpl, error) {
+	if opts.retry.attempts > 0 {
+		reader = &retryReaderAt{reader, opts.retry}
+	}
+
+	if opts.logger != nil && opts.slowRead > 0 {
+		reader = &slowReaderAt{reader, opts.logger, opts.slowRead}
+	}
+
 	r := &readerImpl{
 		reader: reader,
 		hasher: hasher,
+		bufPool: &sync.Pool{
+			New: func() interface{} {
+				buf := make([]byte, 0, opts.scratchSize)
+				return &buf
+			},
+		},
+		opts:      opts,
+		lazyStats: &lazyStats{},
+		counters:  &readerCounters{},
+	}
+
+	// The hasher may be replaced by the seeded one of the header
+	r.hashPool = &sync.Pool{
+		New: func() interface{} {
+			return r.hasher()
+		},
 	}
 
 	if err := r.initialize(); err != nil {
+		r.logCorruption(err)
 		return nil, err
 	}
 
+	if opts.logger != nil {
+		r.logInitialized()
+	}
+
+	adviseFile(reader, opts.access)
+
 	return r, nil
 }
 
-// initialize reads hashTableRefs from r.reader
+// initialize reads the header and hashTableRefs from r.reader
 func (r *readerImpl) initialize() error {
-	buf := make([]byte, tablesRefsSize)
-	_, err := r.reader.ReadAt(buf, 0)
-
+	h, err := readHeader(r.reader)
 	if err != nil {
-		return errors.New("Invalid db header, impossible to read hashTableRefs structures")
+		return err
+	}
+
+	r.header = h
+
+	if seeded := h.seededHasher(); seeded != nil {
+		r.hasher = seeded
 	}
 
-	for i := range &r.refs {
+	if _, wide := r.hasher().(hash.Hash64); h.flags&flagHash64 != 0 && !wide {
+		return ErrNoHash64
+	}
+
+	r.refs = make([]hashTableRef, h.tableNum)
+
+	buf := make([]byte, h.tableNum*8)
+	if _, err := r.reader.ReadAt(buf, int64(h.refsPosition())); err != nil {
+		return ErrInvalidHeader
+	}
+
+	for i := range r.refs {
 		j := i * 8
 		r.refs[i].position, r.refs[i].length = binary.LittleEndian.Uint32(buf[j:j+4]), binary.LittleEndian.Uint32(buf[j+4:j+8])
 		r.size += int(r.refs[i].length >> 1)
 	}
 
-	for _, ref := range &r.refs {
+	for _, ref := range r.refs {
 		if ref.position != 0 {
 			r.endPos = ref.position
 			break
 		}
 	}
 
+	if err := r.validateRefs(); err != nil {
+		return err
+	}
+
+	if h.bloom != 0 {
+		if r.bloom, err = readBloomFilter(r.reader, h.bloom); err != nil {
+			return err
+		}
+	}
+
+	r.total, r.rootSize = r.size, r.size
+
+	if h.buckets != 0 {
+		if r.buckets, err = readBucketDirectory(r.reader, h.buckets); err != nil {
+			return err
+		}
+
+		for _, info := range r.buckets {
+			r.rootSize -= info.size
+		}
+
+		r.size = r.rootSize
+	}
+
 	return nil
 }
 
+// validateRefs checks that every hash table lies after the data section and inside the database
+func (r *readerImpl) validateRefs() error {
+	size, sized := readerSize(r.reader)
+
+	for i, ref := range r.refs {
+		if ref.length == 0 {
+			continue
+		}
+
+		end := uint64(ref.position) + uint64(ref.length)*uint64(r.header.slotSize())
+
+		if ref.position < r.header.dataPosition() || ref.position < r.endPos || end > maxUint || (sized && end > uint64(size)) {
+			return corrupted(nil, int64(r.header.refsPosition())+int64(i)*slotSize, i,
+				fmt.Sprintf("hash table of %d slots at position %d is out of the database", ref.length, ref.position))
+		}
+	}
+
+	return nil
+}
+
+// readerSize returns the size of the given reader, if it is known
+func readerSize(reader io.ReaderAt) (int64, bool) {
+	switch reader := reader.(type) {
+	case interface{ unwrap() io.ReaderAt }:
+		return readerSize(reader.unwrap())
+	case interface{ Size() int64 }:
+		return reader.Size(), true
+	case interface{ Stat() (os.FileInfo, error) }:
+		info, err := reader.Stat()
+		if err != nil || !info.Mode().IsRegular() {
+			return 0, false
+		}
+
+		return info.Size(), true
+	}
+
+	return 0, false
+}
+
+// Close releases resources of the reader, like a memory mapping
+func (r *readerImpl) Close() error {
+	if r.closer == nil {
+		return nil
+	}
+
+	return r.closer.Close()
+}
+
+// Clone returns a Reader, which owns its hasher and scratch buffers, see Reader.Clone
+func (r *readerImpl) Clone() Reader {
+	clone := *r
+	// Resources belong to the parent reader
+	clone.closer = nil
+	clone.owned = &ownedState{hashFunc: r.hasher()}
+
+	return &clone
+}
+
 // IsEmpty returns true if cdb has no records
 func (r *readerImpl) IsEmpty() bool {
 	return r.endPos == 0
@@ -72,6 +292,83 @@ func (r *readerImpl) IsEmpty() bool {
 
 // Get returns the first value associated with the given key
 func (r *readerImpl) Get(key []byte) ([]byte, error) {
+	if r.opts.tracer != nil {
+		return r.tracedGet(key)
+	}
+
+	return r.GetVersion(key, 0)
+}
+
+// GetAt returns the key and the value of the record at the given offset, see Reader.GetAt
+func (r *readerImpl) GetAt(offset uint32) ([]byte, []byte, error) {
+	if offset < r.header.dataPosition() || offset >= r.endPos || r.header.alignPosition(offset) != offset {
+		return nil, nil, ErrInvalidOffset
+	}
+
+	layout, err := r.readRecord(offset)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if r.skip(layout) {
+		return nil, nil, ErrEntryNotFound
+	}
+
+	key, err := r.readKey(layout)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	value, err := r.readValue(sectionReaderFactory{
+		reader:   r.reader,
+		position: layout.valPosition,
+		size:     layout.valSize,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return key, value, nil
+}
+
+// GetOrDefault returns the first value associated with the given key or the given default value,
+// if there is no such key. Errors other than ErrEntryNotFound are returned as is.
+func GetOrDefault(reader Reader, key, def []byte) ([]byte, error) {
+	value, err := reader.Get(key)
+
+	if err == ErrEntryNotFound {
+		return def, nil
+	}
+
+	return value, err
+}
+
+// GetSize returns the length of the first value associated with the given key, see Reader.GetSize
+func (r *readerImpl) GetSize(key []byte) (int, error) {
+	size := -1
+
+	err := r.forEachEntry(key, false, func(section sectionReaderFactory) bool {
+		size = int(section.size)
+		return false
+	})
+
+	if err != nil {
+		return 0, err
+	}
+
+	if size < 0 {
+		return 0, ErrEntryNotFound
+	}
+
+	return size, nil
+}
+
+// GetRange returns the part of the first value associated with the given key, see Reader.GetRange
+func (r *readerImpl) GetRange(key []byte, offset, length int) ([]byte, error) {
+	if offset < 0 || length < 0 {
+		return nil, ErrInvalidRange
+	}
+
 	valueSection, err := r.findEntry(key)
 
 	if err != nil {
@@ -81,34 +378,254 @@ func (r *readerImpl) Get(key []byte) ([]byte, error) {
 		return nil, ErrEntryNotFound
 	}
 
-	value := make([]byte, valueSection.size)
+	if uint64(offset) > uint64(valueSection.size) {
+		return nil, ErrInvalidRange
+	}
+
+	if rest := int(valueSection.size) - offset; length > rest {
+		length = rest
+	}
+
+	return r.readValue(sectionReaderFactory{
+		reader:   valueSection.reader,
+		position: valueSection.position + uint32(offset),
+		size:     uint32(length),
+	})
+}
+
+// GetStream returns a stream of the first value associated with the given key, see Reader.GetStream
+func (r *readerImpl) GetStream(key []byte) (io.ReadCloser, error) {
+	valueSection, err := r.findEntry(key)
 
-	if _, err = valueSection.reader.ReadAt(value, int64(valueSection.position)); err != nil {
+	if err != nil {
 		return nil, err
 	}
+	if valueSection == nil {
+		return nil, ErrEntryNotFound
+	}
 
-	return value, nil
+	return valueStream{io.NewSectionReader(valueSection.reader, int64(valueSection.position), int64(valueSection.size))}, nil
+}
+
+// valueStream implements io.ReadCloser over a value section. Closing is a no-op, the database stays open.
+type valueStream struct {
+	*io.SectionReader
+}
+
+// Close does nothing
+func (valueStream) Close() error {
+	return nil
+}
+
+// GetInto copies the first value associated with the given key into dst, see Reader.GetInto
+func (r *readerImpl) GetInto(key, dst []byte) ([]byte, int, error) {
+	var (
+		valueSection sectionReaderFactory
+		found        bool
+	)
+
+	err := r.forEachEntry(key, false, func(section sectionReaderFactory) bool {
+		valueSection, found = section, true
+		return false
+	})
+
+	if err != nil {
+		return dst, 0, err
+	}
+
+	if !found {
+		return dst, 0, ErrEntryNotFound
+	}
+
+	dst, err = r.readValueInto(valueSection, dst)
+	if err != nil {
+		return dst, 0, err
+	}
+
+	return dst, len(dst), nil
 }
 
 // Has returns true if the given key exists, otherwise returns false.
 func (r *readerImpl) Has(key []byte) (bool, error) {
-	valueSection, err := r.findEntry(key)
+	found := false
+
+	err := r.forEachEntry(key, false, func(section sectionReaderFactory) bool {
+		found = true
+		return false
+	})
+
+	return found, err
+}
+
+// GetVersion returns the n-th value associated with the given key, see Reader.GetVersion
+func (r *readerImpl) GetVersion(key []byte, n int) ([]byte, error) {
+	var stats LookupStats
+
+	return r.getVersion(key, n, &stats)
+}
+
+// getVersion implements GetVersion, collects the statistics of the lookup
+func (r *readerImpl) getVersion(key []byte, n int, stats *LookupStats) ([]byte, error) {
+	var (
+		valueSection sectionReaderFactory
+		found        bool
+	)
+
+	// Only the requested value is worth reading in advance
+	err := r.lookup(key, n == 0, func(section sectionReaderFactory) bool {
+		if n == 0 {
+			valueSection, found = section, true
+		}
 
-	return valueSection != nil, err
+		n--
+
+		return n >= 0
+	}, stats)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !found {
+		return nil, ErrEntryNotFound
+	}
+
+	if valueSection.data == nil && r.mem == nil {
+		stats.Reads++
+		stats.BytesRead += int(valueSection.size)
+		atomic.AddInt64(&r.counters.bytesRead, int64(valueSection.size))
+	}
+
+	return r.readValue(valueSection)
+}
+
+// Versions returns all values associated with the given key, see Reader.Versions
+func (r *readerImpl) Versions(key []byte) ([][]byte, error) {
+	var sections []sectionReaderFactory
+
+	err := r.forEachEntry(key, false, func(section sectionReaderFactory) bool {
+		sections = append(sections, section)
+		return true
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([][]byte, len(sections))
+
+	for i, section := range sections {
+		if values[i], err = r.readValue(section); err != nil {
+			return nil, err
+		}
+	}
+
+	return values, nil
+}
+
+// CountKey returns the number of values associated with the given key, see Reader.CountKey
+func (r *readerImpl) CountKey(key []byte) (int, error) {
+	count := 0
+
+	err := r.forEachEntry(key, false, func(section sectionReaderFactory) bool {
+		count++
+		return true
+	})
+
+	return count, err
+}
+
+// readValue reads the value of the given section
+func (r *readerImpl) readValue(valueSection sectionReaderFactory) ([]byte, error) {
+	if valueSection.data != nil {
+		return valueSection.data, nil
+	}
+
+	if r.mem != nil {
+		end := valueSection.position + valueSection.size
+
+		if uint64(end) > uint64(len(r.mem)) {
+			return nil, corrupted(nil, int64(valueSection.position), -1, "value is out of the database")
+		}
+
+		return r.mem[valueSection.position:end:end], nil
+	}
+
+	value := r.newValue(valueSection.size)
+
+	if _, err := valueSection.reader.ReadAt(value, int64(valueSection.position)); err != nil {
+		return nil, err
+	}
+
+	return value, nil
+}
+
+// readValueInto reads the value of the given section into dst, growing it if needed
+func (r *readerImpl) readValueInto(valueSection sectionReaderFactory, dst []byte) ([]byte, error) {
+	size := int(valueSection.size)
+
+	if cap(dst) < size {
+		dst = make([]byte, size)
+	}
+
+	dst = dst[:size]
+
+	if r.mem != nil {
+		end := valueSection.position + valueSection.size
+
+		if uint64(end) > uint64(len(r.mem)) {
+			return dst[:0], corrupted(nil, int64(valueSection.position), -1, "value is out of the database")
+		}
+
+		copy(dst, r.mem[valueSection.position:end])
+
+		return dst, nil
+	}
+
+	if _, err := valueSection.reader.ReadAt(dst, int64(valueSection.position)); err != nil && !(err == io.EOF && size == 0) {
+		return dst[:0], err
+	}
+
+	return dst, nil
 }
 
 // Iterator returns new Iterator object that points on first record
 func (r *readerImpl) Iterator() (Iterator, error) {
-	iterator, err := r.newIterator(tablesRefsSize, nil, nil)
+	if r.opts.tracer != nil {
+		return r.tracedIterator()
+	}
+
+	return r.iterator(false)
+}
+
+// KeyIterator returns new Iterator object that points on first record, see Reader.KeyIterator
+func (r *readerImpl) KeyIterator() (Iterator, error) {
+	return r.iterator(true)
+}
+
+// iterator implements Iterator and KeyIterator
+func (r *readerImpl) iterator(keysOnly bool) (Iterator, error) {
+	iterator, err := r.newIterator(r.header.dataPosition(), nil, nil)
 
 	if err != nil {
 		return nil, err
 	}
 
-	if _, err := iterator.Next(); err != nil {
+	iterator.keysOnly = keysOnly
+
+	ok, err := iterator.Next()
+
+	if err != nil {
 		return nil, err
 	}
 
+	// Every record is expired
+	if !ok {
+		return nil, ErrEmptyCDB
+	}
+
+	iterator.mark()
+
 	return iterator, nil
 }
 
@@ -116,18 +633,32 @@ func (r *readerImpl) Iterator() (Iterator, error) {
 func (r *readerImpl) IteratorAt(key []byte) (Iterator, error) {
 	valueSection, err := r.findEntry(key)
 
-	if err != nil || valueSection == nil {
+	if err != nil {
 		return nil, err
 	}
+	if valueSection == nil {
+		return nil, ErrEntryNotFound
+	}
 
-	return r.newIterator(
-		valueSection.position+valueSection.size,
+	iterator, err := r.newIterator(
+		r.header.alignPosition(valueSection.position+valueSection.size),
 		&sectionReaderFactory{
 			reader: bytes.NewReader(key),
 			size:   uint32(len(key)),
 		},
 		valueSection,
 	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	iterator.meta = valueSection.meta
+	iterator.current = valueSection.record
+	iterator.records = 1
+	iterator.mark()
+
+	return iterator, nil
 }
 
 // Size returns the size of the dataset
@@ -135,111 +666,285 @@ func (r *readerImpl) Size() int {
 	return r.size
 }
 
-// findEntry finds an entry for the given key
+// findEntry finds the first entry for the given key
+func (r *readerImpl) findEntry(key []byte) (*sectionReaderFactory, error) {
+	var valueSection *sectionReaderFactory
+
+	err := r.forEachEntry(key, false, func(section sectionReaderFactory) bool {
+		valueSection = &section
+		return false
+	})
+
+	return valueSection, err
+}
+
+// forEachEntry calls fn for every entry of the given key in the probe order, until fn returns false
 //
 // A record is located as follows:
 // * Compute the hash value of the key in the record.
-// * The hash value modulo 256 (tableNum) is the number of a hash table.
-// * The hash value divided by 256, modulo the length of that table, is a slot number.
+// * The hash value modulo 256 (or the table number of the v2 header) is the number of a hash table.
+// * The hash value divided by 256 (or the table number), modulo the length of that table, is a slot number.
 // * Probe that slot, the next higher slot, and so on, until you find the record or run into an empty slot.
-func (r *readerImpl) findEntry(key []byte) (*sectionReaderFactory, error) {
-	h := r.calcHash(key)
-	ref := &r.refs[h%tableNum]
+// With prefetch, small values are read together with their records, see checkEntry.
+func (r *readerImpl) forEachEntry(key []byte, prefetch bool, fn func(section sectionReaderFactory) bool) error {
+	var stats LookupStats
+
+	return r.lookup(key, prefetch, fn, &stats)
+}
+
+// lookup implements forEachEntry, collects the statistics of the lookup and reports them to metrics
+func (r *readerImpl) lookup(key []byte, prefetch bool, fn func(section sectionReaderFactory) bool, stats *LookupStats) error {
+	if r.opts.metrics == nil && r.opts.logger == nil {
+		err := r.probe(key, prefetch, fn, stats)
+		r.counters.observe(stats)
+
+		return err
+	}
+
+	start := time.Now()
+	err := r.probe(key, prefetch, fn, stats)
+	stats.Duration = time.Since(start)
+	r.counters.observe(stats)
+
+	if r.opts.metrics != nil {
+		r.opts.metrics.ObserveLookup(*stats)
+	}
+
+	r.logCorruption(err)
+
+	return err
+}
+
+// probe implements forEachEntry, collects the statistics of the lookup
+func (r *readerImpl) probe(key []byte, prefetch bool, fn func(section sectionReaderFactory) bool, stats *LookupStats) error {
+	h, hi := r.calcHash(key)
+
+	if r.bloom != nil && !r.bloom.mayContain(h) {
+		return nil
+	}
+
+	table := int(h % uint32(len(r.refs)))
+	ref := &r.refs[table]
 
 	if ref.length == 0 {
-		return nil, nil
+		return nil
 	}
 
 	var (
-		entry        slot
-		j            uint32
-		valueSection *sectionReaderFactory
-		err          error
+		entry slot
+		j     uint32
 	)
 
-	k := (h >> 8) % ref.length
+	// A table lies after the data section and every slot of it is probed at most once
+	size := r.header.slotSize()
+
+	if ref.position < r.endPos || uint64(ref.position)+uint64(ref.length)*uint64(size) > maxUint {
+		return corrupted(nil, int64(ref.position), table, "hash table is out of the database")
+	}
+
+	k := r.header.startSlot(h, ref.length)
 
 	for j = 0; j < ref.length; j++ {
-		r.readPair(ref.position+k*slotSize, &entry.hash, &entry.position)
+		pos := ref.position + k*size
+
+		if err := r.readSlot(pos, &entry); err != nil {
+			return corrupted(err, int64(pos), table, "slot is out of the database")
+		}
+
+		stats.Probes++
+		stats.Reads++
+		stats.BytesRead += int(size)
 
 		if entry.position == 0 {
-			return nil, nil
+			return nil
+		}
+
+		if entry.position < r.header.dataPosition() || entry.position >= r.endPos {
+			return corrupted(nil, int64(pos), table, fmt.Sprintf("slot points at %d out of the data section", entry.position))
 		}
 
-		if entry.hash == h {
-			valueSection, err = r.checkEntry(entry, key)
+		if entry.hash == h && entry.hi == hi {
+			valueSection, ok, err := r.checkEntry(entry, key, prefetch, stats)
 
 			if err != nil {
-				return nil, err
+				return err
 			}
 
-			if valueSection != nil {
-				break
+			if ok {
+				stats.Found = true
+
+				if !fn(valueSection) {
+					return nil
+				}
 			}
 		}
 
 		k = (k + 1) % ref.length
 	}
 
-	return valueSection, nil
+	return nil
 }
 
-// calcHash returns hash value of given key
-func (r *readerImpl) calcHash(key []byte) uint32 {
-	hashFunc := r.hasher()
+// calcHash returns hash value of given key and, with the 64-bit hash, its upper half
+func (r *readerImpl) calcHash(key []byte) (uint32, uint32) {
+	if r.owned != nil {
+		return r.hashWith(r.owned.hashFunc, key)
+	}
+
+	hashFunc := r.hashPool.Get().(hash.Hash32)
+	h, hi := r.hashWith(hashFunc, key)
+	r.hashPool.Put(hashFunc)
+
+	return h, hi
+}
+
+// hashWith returns hash value of given key computed by the given hasher instance
+func (r *readerImpl) hashWith(hashFunc hash.Hash32, key []byte) (uint32, uint32) {
+	hashFunc.Reset()
+	hashFunc.Write(r.bucketName)
 	hashFunc.Write(key)
 
-	return hashFunc.Sum32()
+	return r.header.keyHash(hashFunc)
 }
 
-// checkEntry returns io.SectionReader if given slot belongs to given key, otherwise nil
-func (r *readerImpl) checkEntry(entry slot, key []byte) (*sectionReaderFactory, error) {
-	var (
-		keySize, valSize uint32
-		givenKeySize     = uint32(len(key))
-	)
+// checkEntry returns the value section and true if given slot belongs to given key.
+// The record header and the key are read at once. With prefetch, small values are read
+// by the same read too, so that a lookup of a file-backed database takes two reads.
+func (r *readerImpl) checkEntry(entry slot, key []byte, prefetch bool, stats *LookupStats) (sectionReaderFactory, bool, error) {
+	headerSize := int(r.header.recordHeaderSize())
+	size := headerSize + len(key)
 
-	if err := r.readPair(entry.position, &keySize, &valSize); err != nil {
-		return nil, err
+	// The values of memory-mapped readers are not copied
+	if prefetch && r.mem == nil && size < recordPrefetchSize {
+		size = recordPrefetchSize
 	}
 
-	if keySize != givenKeySize {
-		return nil, nil
+	scratch := r.getBuffer(size)
+	defer r.putBuffer(scratch)
+
+	buf := *scratch
+	n, err := r.reader.ReadAt(buf, int64(entry.position))
+	stats.Reads++
+	stats.BytesRead += n
+
+	// A speculative read may run past the end of the database
+	if n < headerSize || (err != nil && err != io.EOF) {
+		return sectionReaderFactory{}, false, corrupted(err, int64(entry.position), -1, "record header is out of the database")
 	}
 
-	data := make([]byte, keySize)
+	layout, err := r.parseRecord(buf, entry.position)
 
-	if _, err := r.reader.ReadAt(data, int64(entry.position+8)); err != nil {
-		return nil, err
+	if err != nil {
+		return sectionReaderFactory{}, false, err
+	}
+
+	if layout.keySize != uint32(len(key)) || r.skip(layout) {
+		return sectionReaderFactory{}, false, nil
+	}
+
+	suffixEnd := headerSize + int(layout.keySize-layout.shared)
+
+	if suffixEnd > n {
+		return sectionReaderFactory{}, false, corrupted(nil, int64(layout.keyPosition), -1, "key is out of the database")
 	}
 
-	if bytes.Compare(data, key) != 0 {
-		return nil, nil
+	if !bytes.Equal(buf[headerSize:suffixEnd], key[layout.shared:]) {
+		return sectionReaderFactory{}, false, nil
 	}
 
-	return &sectionReaderFactory{
+	if layout.compressed() {
+		stats.Reads++
+		stats.BytesRead += int(layout.shared)
+
+		if equal, err := r.prefixEquals(layout, key); err != nil || !equal {
+			return sectionReaderFactory{}, false, err
+		}
+	}
+
+	section := sectionReaderFactory{
 		reader:   r.reader,
-		position: entry.position + 8 + keySize,
-		size:     valSize,
-	}, nil
+		position: layout.valPosition,
+		size:     layout.valSize,
+		record:   layout.position,
+		meta:     layout.recordMeta,
+	}
+
+	if prefetch && r.mem == nil && uint64(suffixEnd)+uint64(layout.valSize) <= uint64(n) {
+		section.data = r.newValue(layout.valSize)
+		copy(section.data, buf[suffixEnd:])
+	}
+
+	return section, true, nil
 }
 
-// readPair reads from r.reader uint_32 pair if possible. Returns an error on failure
-func (r *readerImpl) readPair(pos uint32, a, b *uint32) error {
-	pair := make([]byte, 8, 8)
+// skip tells if the given record must be treated as not existing
+func (r *readerImpl) skip(layout recordLayout) bool {
+	if layout.flags&RecordSuperseded != 0 || (layout.bucket != r.bucket && !r.allBuckets) {
+		return true
+	}
 
-	_, err := r.reader.ReadAt(pair, int64(pos))
-	if err != nil {
+	return r.opts.skipExpired && layout.expired(r.opts.now().UnixNano())
+}
+
+// readSlot reads the hash table slot at the given position
+func (r *readerImpl) readSlot(pos uint32, s *slot) error {
+	buf := r.getBuffer(int(r.header.slotSize()))
+	defer r.putBuffer(buf)
+
+	raw := *buf
+
+	if _, err := r.reader.ReadAt(raw, int64(pos)); err != nil {
 		return err
 	}
 
-	*a, *b = binary.LittleEndian.Uint32(pair), binary.LittleEndian.Uint32(pair[4:])
+	s.hash, s.position = binary.LittleEndian.Uint32(raw), binary.LittleEndian.Uint32(raw[4:])
+
+	if len(raw) > slotSize {
+		s.hi = binary.LittleEndian.Uint32(raw[slotSize:])
+	}
 
 	return nil
 }
 
+// getBuffer returns a scratch buffer of the given size from the pool
+func (r *readerImpl) getBuffer(size int) *[]byte {
+	var buf *[]byte
+
+	if r.owned == nil {
+		buf = r.bufPool.Get().(*[]byte)
+	} else if n := len(r.owned.buffers); n > 0 {
+		buf, r.owned.buffers = r.owned.buffers[n-1], r.owned.buffers[:n-1]
+	} else {
+		scratch := make([]byte, 0, r.opts.scratchSize)
+		buf = &scratch
+	}
+
+	if cap(*buf) < size {
+		*buf = make([]byte, size)
+	}
+
+	*buf = (*buf)[:size]
+
+	return buf
+}
+
+// putBuffer returns the given scratch buffer to the pool
+func (r *readerImpl) putBuffer(buf *[]byte) {
+	// A buffer grown by a huge key is left to the GC
+	if r.opts.maxPooledScratch > 0 && cap(*buf) > r.opts.maxPooledScratch {
+		return
+	}
+
+	if r.owned != nil {
+		r.owned.buffers = append(r.owned.buffers, buf)
+		return
+	}
+
+	r.bufPool.Put(buf)
+}
+
 // newIterator returns new instance of Iterator object
-func (r *readerImpl) newIterator(position uint32, keySectionFactory, valueSectionFactory *sectionReaderFactory) (Iterator, error) {
+func (r *readerImpl) newIterator(position uint32, keySectionFactory, valueSectionFactory *sectionReaderFactory) (*iterator, error) {
 
 	if r.IsEmpty() {
 		return nil, ErrEmptyCDB
@@ -258,7 +963,10 @@ func (r *readerImpl) newIterator(position uint32, keySectionFactory, valueSectio
 
 	resIterator := &iterator{
 		position:  position,
+		start:     position,
+		end:       r.endPos,
 		cdbReader: r,
+		counting:  position == r.header.dataPosition(),
 		record: &record{
 			keySectionFactory:   keySectionFactory,
 			valueSectionFactory: valueSectionFactory,
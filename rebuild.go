@@ -0,0 +1,82 @@
+package cdb
+
+// Changeset holds upserts and deletions of keys, which Rebuild applies to a base database.
+// The zero value is an empty changeset. Do not share object between multiple goroutines.
+type Changeset struct {
+	changes map[string]change
+	// keys are the changed keys in the order of their first change
+	keys []string
+}
+
+// change is the change of a key, a deletion if deleted is true
+type change struct {
+	value   []byte
+	deleted bool
+}
+
+// Put sets the value of the key, the latest change of a key wins. The key and the value are copied.
+func (c *Changeset) Put(key, value []byte) {
+	c.set(key, change{value: append([]byte{}, value...)})
+}
+
+// Delete deletes the key, the latest change of a key wins
+func (c *Changeset) Delete(key []byte) {
+	c.set(key, change{deleted: true})
+}
+
+// Len returns the number of changed keys
+func (c *Changeset) Len() int {
+	return len(c.keys)
+}
+
+// set sets the change of the key
+func (c *Changeset) set(key []byte, ch change) {
+	if c.changes == nil {
+		c.changes = make(map[string]change)
+	}
+
+	if _, ok := c.changes[string(key)]; !ok {
+		c.keys = append(c.keys, string(key))
+	}
+
+	c.changes[string(key)] = ch
+}
+
+// Rebuild puts the records of base with the changes applied into dst, so the daily diff of a snapshot
+// is applied without tombstones and shadowed records of Stack. Base records are streamed in the file
+// order: all records of a changed key are replaced with a single record of its new value at the place
+// of the first one, records of deleted keys are dropped. New keys follow in the order of their first
+// change. Like Copy, Rebuild doesn't keep record flags and expiration times and doesn't close dst.
+func Rebuild(base Reader, changes Changeset, dst Writer) error {
+	// written marks the changed keys of base, which are already put
+	written := make(map[string]bool)
+
+	err := ForEach(base, func(key, value []byte) error {
+		ch, ok := changes.changes[string(key)]
+		if !ok {
+			return dst.Put(key, value)
+		}
+
+		if ch.deleted || written[string(key)] {
+			return nil
+		}
+
+		written[string(key)] = true
+
+		return dst.Put(key, ch.value)
+	})
+
+	if err != nil {
+		return err
+	}
+
+	for _, key := range changes.keys {
+		if ch := changes.changes[key]; !ch.deleted && !written[key] {
+			if err := dst.Put([]byte(key), ch.value); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,34 @@
+package cdb
+
+func (suite *CDBTestSuite) TestRebuild() {
+	writer := suite.getCDBWriter()
+	for _, key := range []string{"a", "b", "c", "b"} {
+		suite.Require().Nil(writer.Put([]byte(key), []byte("base-"+key)))
+	}
+	suite.Require().Nil(writer.Close())
+
+	var changes Changeset
+	changes.Put([]byte("e"), []byte("new-e"))
+	changes.Put([]byte("b"), []byte("new-b"))
+	changes.Delete([]byte("c"))
+	changes.Put([]byte("d"), []byte("new-d"))
+	changes.Delete([]byte("d"))
+	changes.Delete([]byte("missing"))
+	changes.Put([]byte("f"), []byte("new-f"))
+	suite.Equal(6, changes.Len())
+
+	files := suite.createShardFiles(1)
+	defer suite.removeShardFiles(files)
+
+	dst, err := suite.cdbHandle.GetWriter(files[0])
+	suite.Require().Nil(err)
+	suite.Require().Nil(Rebuild(suite.getCDBReader(), changes, dst))
+	suite.Require().Nil(dst.Close())
+
+	reader, err := suite.cdbHandle.GetReader(files[0])
+	suite.Require().Nil(err)
+
+	iterator, err := reader.Iterator()
+	suite.Require().Nil(err)
+	suite.Equal([]string{"a=base-a", "b=new-b", "e=new-e", "f=new-f"}, suite.mergedRecords(iterator))
+}
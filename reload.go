@@ -0,0 +1,450 @@
+package cdb
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// ErrInvalidInterval tells that the requested polling interval of a ReloadingReader is not positive
+var ErrInvalidInterval = errors.New("cdb watch interval must be positive")
+
+// ReloadingReader implements Reader interface over a database file, which is periodically rebuilt.
+// It polls the file and, once the file is replaced or modified, opens and warms up the new version
+// and atomically swaps it in. The old version is closed after the calls in flight return.
+// Iterators, streams and bucket readers are bound to the version they were obtained from,
+// they must not be used after the next reload. Iterator.Reset restarts a scan of the same version,
+// a scan of the reloaded version needs a new iterator.
+type ReloadingReader struct {
+	cdb  *CDB
+	path string
+
+	// mu guards current, info and err
+	mu      sync.RWMutex
+	current *generation
+	info    os.FileInfo
+	err     error
+
+	// reloadMu serializes reloads
+	reloadMu  sync.Mutex
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// generation is a version of the database, refs counts the calls in flight
+type generation struct {
+	reader Reader
+	refs   sync.WaitGroup
+}
+
+// Watch opens the database file at the given path and returns a ReloadingReader,
+// which checks the file for changes with the given interval. The interval must be positive.
+func (cdb *CDB) Watch(path string, interval time.Duration) (*ReloadingReader, error) {
+	if interval <= 0 {
+		return nil, ErrInvalidInterval
+	}
+
+	r := &ReloadingReader{
+		cdb:  cdb,
+		path: path,
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+
+	go r.watch(interval)
+
+	return r, nil
+}
+
+// watch polls the file until the reader is closed
+func (r *ReloadingReader) watch(interval time.Duration) {
+	defer close(r.done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.stop:
+			return
+		case <-ticker.C:
+			r.reloadIfChanged()
+		}
+	}
+}
+
+// reloadIfChanged reloads the database if the file is replaced or modified since the last load
+func (r *ReloadingReader) reloadIfChanged() {
+	info, err := os.Stat(r.path)
+
+	if err == nil {
+		r.mu.RLock()
+		old := r.info
+		r.mu.RUnlock()
+
+		if os.SameFile(old, info) && info.ModTime().Equal(old.ModTime()) && info.Size() == old.Size() {
+			return
+		}
+
+		err = r.Reload()
+	}
+
+	if err != nil {
+		r.mu.Lock()
+		r.err = err
+		r.mu.Unlock()
+	}
+}
+
+// Reload opens, warms up and swaps in the current version of the file right away.
+// On error the previous version stays in use.
+func (r *ReloadingReader) Reload() error {
+	r.reloadMu.Lock()
+	defer r.reloadMu.Unlock()
+
+	info, err := os.Stat(r.path)
+	if err != nil {
+		return err
+	}
+
+	reader, err := r.cdb.Open(r.path)
+	if err != nil {
+		return err
+	}
+
+	if err := reader.Warmup(false); err != nil {
+		reader.Close()
+		return err
+	}
+
+	r.mu.Lock()
+	old := r.current
+	r.current, r.info, r.err = &generation{reader: reader}, info, nil
+	r.mu.Unlock()
+
+	if old != nil {
+		old.refs.Wait()
+		return old.reader.Close()
+	}
+
+	return nil
+}
+
+// Err returns the error of the last failed background reload, nil once a reload succeeds
+func (r *ReloadingReader) Err() error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.err
+}
+
+// acquire returns the current version, which stays open until release
+func (r *ReloadingReader) acquire() *generation {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	r.current.refs.Add(1)
+
+	return r.current
+}
+
+// release lets the version be closed once it is swapped out
+func (g *generation) release() {
+	g.refs.Done()
+}
+
+// Get returns the first value associated with the given key
+func (r *ReloadingReader) Get(key []byte) ([]byte, error) {
+	g := r.acquire()
+	defer g.release()
+
+	return g.reader.Get(key)
+}
+
+// MultiGet returns the first values associated with the given keys, see Reader.MultiGet
+func (r *ReloadingReader) MultiGet(keys [][]byte) ([][]byte, []error) {
+	g := r.acquire()
+	defer g.release()
+
+	return g.reader.MultiGet(keys)
+}
+
+// GetSize returns the size of the first value associated with the given key
+func (r *ReloadingReader) GetSize(key []byte) (int, error) {
+	g := r.acquire()
+	defer g.release()
+
+	return g.reader.GetSize(key)
+}
+
+// GetRange returns the part of the first value associated with the given key
+func (r *ReloadingReader) GetRange(key []byte, offset, length int) ([]byte, error) {
+	g := r.acquire()
+	defer g.release()
+
+	return g.reader.GetRange(key, offset, length)
+}
+
+// GetStream returns a reader of the first value associated with the given key.
+// The stream must not be used after the next reload.
+func (r *ReloadingReader) GetStream(key []byte) (io.ReadCloser, error) {
+	g := r.acquire()
+	defer g.release()
+
+	return g.reader.GetStream(key)
+}
+
+// GetInto reads the first value associated with the given key into dst
+func (r *ReloadingReader) GetInto(key, dst []byte) ([]byte, int, error) {
+	g := r.acquire()
+	defer g.release()
+
+	return g.reader.GetInto(key, dst)
+}
+
+// GetVersion returns the n-th value associated with the given key
+func (r *ReloadingReader) GetVersion(key []byte, n int) ([]byte, error) {
+	g := r.acquire()
+	defer g.release()
+
+	return g.reader.GetVersion(key, n)
+}
+
+// Versions returns all values associated with the given key
+func (r *ReloadingReader) Versions(key []byte) ([][]byte, error) {
+	g := r.acquire()
+	defer g.release()
+
+	return g.reader.Versions(key)
+}
+
+// CountKey returns the number of values associated with the given key in the current version
+func (r *ReloadingReader) CountKey(key []byte) (int, error) {
+	g := r.acquire()
+	defer g.release()
+
+	return g.reader.CountKey(key)
+}
+
+// GetString returns the first value associated with the given string key
+func (r *ReloadingReader) GetString(key string) ([]byte, error) {
+	return r.Get(stringBytes(key))
+}
+
+// GetAt returns the key and the value of the record at the given offset, see Reader.GetAt.
+// Offsets are meaningful within a version of the file only.
+func (r *ReloadingReader) GetAt(offset uint32) ([]byte, []byte, error) {
+	g := r.acquire()
+	defer g.release()
+
+	return g.reader.GetAt(offset)
+}
+
+// Has returns true if the given key exists, otherwise returns false.
+func (r *ReloadingReader) Has(key []byte) (bool, error) {
+	g := r.acquire()
+	defer g.release()
+
+	return g.reader.Has(key)
+}
+
+// MultiHas tells which of the given keys exist
+func (r *ReloadingReader) MultiHas(keys [][]byte) ([]bool, error) {
+	g := r.acquire()
+	defer g.release()
+
+	return g.reader.MultiHas(keys)
+}
+
+// HasString returns true if the given string key exists, otherwise returns false.
+func (r *ReloadingReader) HasString(key string) (bool, error) {
+	return r.Has(stringBytes(key))
+}
+
+// GetContext returns the first value associated with the given key, see Reader.GetContext
+func (r *ReloadingReader) GetContext(ctx context.Context, key []byte) ([]byte, error) {
+	g := r.acquire()
+	defer g.release()
+
+	return g.reader.GetContext(ctx, key)
+}
+
+// HasContext returns true if the given key exists, see Reader.HasContext
+func (r *ReloadingReader) HasContext(ctx context.Context, key []byte) (bool, error) {
+	g := r.acquire()
+	defer g.release()
+
+	return g.reader.HasContext(ctx, key)
+}
+
+// Iterator returns a new Iterator object over the current version.
+// The iterator must not be used after the next reload.
+func (r *ReloadingReader) Iterator() (Iterator, error) {
+	g := r.acquire()
+	defer g.release()
+
+	return g.reader.Iterator()
+}
+
+// KeyIterator returns a new Iterator object over the current version, see Reader.KeyIterator
+func (r *ReloadingReader) KeyIterator() (Iterator, error) {
+	g := r.acquire()
+	defer g.release()
+
+	return g.reader.KeyIterator()
+}
+
+// IteratorContext returns a new Iterator object over the current version, see Reader.IteratorContext
+func (r *ReloadingReader) IteratorContext(ctx context.Context) (Iterator, error) {
+	g := r.acquire()
+	defer g.release()
+
+	return g.reader.IteratorContext(ctx)
+}
+
+// IteratorAt returns a new Iterator object that points on the first record associated with the given key
+func (r *ReloadingReader) IteratorAt(key []byte) (Iterator, error) {
+	g := r.acquire()
+	defer g.release()
+
+	return g.reader.IteratorAt(key)
+}
+
+// Range returns a new Iterator object over the records of the current version between the given keys
+func (r *ReloadingReader) Range(start, end []byte) (Iterator, error) {
+	g := r.acquire()
+	defer g.release()
+
+	return g.reader.Range(start, end)
+}
+
+// Iterate returns a new Iterator object over the records of the current version between the given keys,
+// see Reader.Iterate
+func (r *ReloadingReader) Iterate(start, end []byte, bounds Bounds) (Iterator, error) {
+	g := r.acquire()
+	defer g.release()
+
+	return g.reader.Iterate(start, end, bounds)
+}
+
+// IteratePrefix returns a new Iterator object over the records of the current version with keys starting
+// with the given prefix
+func (r *ReloadingReader) IteratePrefix(prefix []byte) (Iterator, error) {
+	g := r.acquire()
+	defer g.release()
+
+	return g.reader.IteratePrefix(prefix)
+}
+
+// WalkSlots calls fn for every slot of the current version, see Reader.WalkSlots
+func (r *ReloadingReader) WalkSlots(fn func(slot SlotInfo) error) error {
+	g := r.acquire()
+	defer g.release()
+
+	return g.reader.WalkSlots(fn)
+}
+
+// Sample returns at most n random records of the current version, see Reader.Sample
+func (r *ReloadingReader) Sample(n int) ([]Record, error) {
+	g := r.acquire()
+	defer g.release()
+
+	return g.reader.Sample(n)
+}
+
+// Segments returns iterators over segments of the current version, see Reader.Segments
+func (r *ReloadingReader) Segments(n int) ([]Iterator, error) {
+	g := r.acquire()
+	defer g.release()
+
+	return g.reader.Segments(n)
+}
+
+// ResumeIterator returns a new Iterator object over the current version, which points on the record
+// of the given cursor, see Reader.ResumeIterator
+func (r *ReloadingReader) ResumeIterator(cursor Cursor) (Iterator, error) {
+	g := r.acquire()
+	defer g.release()
+
+	return g.reader.ResumeIterator(cursor)
+}
+
+// OrderedIterator returns a new Iterator object over the records of the current version in the key order
+func (r *ReloadingReader) OrderedIterator() (Iterator, error) {
+	g := r.acquire()
+	defer g.release()
+
+	return g.reader.OrderedIterator()
+}
+
+// ReverseIterator returns a new Iterator object over the records of the current version in the reverse key order
+func (r *ReloadingReader) ReverseIterator() (Iterator, error) {
+	g := r.acquire()
+	defer g.release()
+
+	return g.reader.ReverseIterator()
+}
+
+// Size returns the size of the dataset of the current version
+func (r *ReloadingReader) Size() int {
+	g := r.acquire()
+	defer g.release()
+
+	return g.reader.Size()
+}
+
+// Bucket returns a Reader of the bucket of the current version.
+// The bucket reader must not be used after the next reload.
+func (r *ReloadingReader) Bucket(name string) Reader {
+	g := r.acquire()
+	defer g.release()
+
+	return g.reader.Bucket(name)
+}
+
+// Clone returns the reader itself: versions are swapped behind it, so it is shared by goroutines
+func (r *ReloadingReader) Clone() Reader {
+	return r
+}
+
+// Warmup preloads the current version, see Reader.Warmup
+func (r *ReloadingReader) Warmup(data bool) error {
+	g := r.acquire()
+	defer g.release()
+
+	return g.reader.Warmup(data)
+}
+
+// Stats returns the statistics of the current version
+func (r *ReloadingReader) Stats() (Stats, error) {
+	g := r.acquire()
+	defer g.release()
+
+	return g.reader.Stats()
+}
+
+// Close stops watching the file and closes the current version after the calls in flight return.
+func (r *ReloadingReader) Close() error {
+	var err error
+
+	r.closeOnce.Do(func() {
+		close(r.stop)
+		<-r.done
+
+		r.reloadMu.Lock()
+		defer r.reloadMu.Unlock()
+
+		r.current.refs.Wait()
+		err = r.current.reader.Close()
+	})
+
+	return err
+}
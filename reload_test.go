@@ -0,0 +1,104 @@
+package cdb
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+func (suite *CDBTestSuite) writeFileCDB(path string, key, value string) {
+	tmp := path + ".tmp"
+
+	writer, err := suite.cdbHandle.Create(tmp)
+	suite.Require().Nil(err)
+	suite.Require().Nil(writer.Put([]byte(key), []byte(value)))
+	suite.Require().Nil(writer.Close())
+
+	suite.Require().Nil(os.Rename(tmp, path))
+}
+
+func (suite *CDBTestSuite) TestReloadingReader() {
+	dir, err := ioutil.TempDir("", "test_cdb")
+	suite.Require().Nil(err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "test.cdb")
+	suite.writeFileCDB(path, "key", "v1")
+
+	reader, err := suite.cdbHandle.Watch(path, 5*time.Millisecond)
+	suite.Require().Nil(err)
+	defer reader.Close()
+
+	value, err := reader.Get([]byte("key"))
+	suite.Nil(err)
+	suite.Equal("v1", string(value))
+
+	suite.writeFileCDB(path, "key", "v2")
+
+	suite.Eventually(func() bool {
+		value, err := reader.Get([]byte("key"))
+		return err == nil && string(value) == "v2"
+	}, time.Second, 5*time.Millisecond)
+
+	// A broken file keeps the previous version in use
+	suite.Require().Nil(ioutil.WriteFile(path+".tmp", []byte{1, 2, 3}, 0644))
+	suite.Require().Nil(os.Rename(path+".tmp", path))
+	suite.NotNil(reader.Reload())
+
+	value, err = reader.Get([]byte("key"))
+	suite.Nil(err)
+	suite.Equal("v2", string(value))
+
+	suite.Eventually(func() bool {
+		return reader.Err() != nil
+	}, time.Second, 5*time.Millisecond)
+
+	suite.writeFileCDB(path, "key", "v3")
+	suite.Nil(reader.Reload())
+	suite.Nil(reader.Err())
+
+	value, err = reader.Get([]byte("key"))
+	suite.Nil(err)
+	suite.Equal("v3", string(value))
+
+	_, err = suite.cdbHandle.Watch(filepath.Join(dir, "missing.cdb"), time.Second)
+	suite.True(os.IsNotExist(err))
+
+	_, err = suite.cdbHandle.Watch(path, 0)
+	suite.Equal(ErrInvalidInterval, err)
+
+	_, err = suite.cdbHandle.Watch(path, -time.Second)
+	suite.Equal(ErrInvalidInterval, err)
+}
+
+func (suite *CDBTestSuite) TestReloadingReaderIterator() {
+	dir, err := ioutil.TempDir("", "test_cdb")
+	suite.Require().Nil(err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "test.cdb")
+	suite.writeFileCDB(path, "key", "v1")
+
+	reader, err := suite.cdbHandle.Watch(path, time.Hour)
+	suite.Require().Nil(err)
+	defer reader.Close()
+
+	stale, err := reader.Iterator()
+	suite.Require().Nil(err)
+
+	// An unfinished iterator doesn't hold the reload, the version it walks is closed
+	suite.writeFileCDB(path, "key", "v2")
+	suite.Require().Nil(reader.Reload())
+
+	_, err = stale.Value()
+	suite.NotNil(err)
+
+	iterator, err := reader.Iterator()
+	suite.Require().Nil(err)
+	suite.mustNext(iterator)
+
+	value, err := iterator.Value()
+	suite.Nil(err)
+	suite.Equal("v2", string(value))
+}
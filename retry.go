@@ -0,0 +1,113 @@
+package cdb
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"os"
+	"syscall"
+	"time"
+)
+
+// Maximal delay between read attempts
+const maxRetryBackoff = 10 * time.Second
+
+// ErrInvalidRetry tells that the requested retry policy is invalid
+var ErrInvalidRetry = errors.New("cdb retry attempts and backoff must not be negative")
+
+// retryPolicy tells how readers retry failed reads
+type retryPolicy struct {
+	// attempts is the number of retries, 0 disables retries
+	attempts int
+	backoff  time.Duration
+}
+
+// retryReaderAt implements io.ReaderAt, retries failed reads of the underlying reader
+type retryReaderAt struct {
+	reader io.ReaderAt
+	policy retryPolicy
+}
+
+// SetRetry tells readers to retry failed reads up to the given number of times, so that transient
+// errors of network or NFS backends don't fail lookups. The delay before the i-th retry is
+// backoff * 2^i with a random jitter, at most 10 seconds. Only timeouts, temporary network errors,
+// EIO and EAGAIN are retried, a done context of a lookup stops retries. 0 attempts disables retries,
+// it's the default. Like SetHash, it affects only new instances of Reader.
+func (cdb *CDB) SetRetry(attempts int, backoff time.Duration) error {
+	if attempts < 0 || backoff < 0 {
+		return ErrInvalidRetry
+	}
+
+	cdb.opts.retry = retryPolicy{attempts, backoff}
+
+	return nil
+}
+
+// ReadAt reads len(p) bytes at the given offset, retrying transient errors
+func (r *retryReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	return r.readAtContext(context.Background(), p, off)
+}
+
+// readAtContext reads len(p) bytes at the given offset, retrying transient errors until the context is done
+func (r *retryReaderAt) readAtContext(ctx context.Context, p []byte, off int64) (int, error) {
+	n, err := r.reader.ReadAt(p, off)
+
+	for i := 0; i < r.policy.attempts && isTransient(err); i++ {
+		timer := time.NewTimer(r.policy.delay(i))
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return n, ctx.Err()
+		case <-timer.C:
+		}
+
+		n, err = r.reader.ReadAt(p, off)
+	}
+
+	return n, err
+}
+
+// unwrap returns the underlying reader
+func (r *retryReaderAt) unwrap() io.ReaderAt {
+	return r.reader
+}
+
+// delay returns the delay before the i-th retry, a random one in range [d/2, d) of the exponential backoff d
+func (p retryPolicy) delay(i int) time.Duration {
+	d := maxRetryBackoff
+
+	if i < 32 && p.backoff < maxRetryBackoff>>uint(i) {
+		d = p.backoff << uint(i)
+	}
+
+	if d < 2 {
+		return d
+	}
+
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)))
+}
+
+// isTransient tells if a read failed with the given error could succeed on retry: timeouts and temporary
+// network errors, EIO and EAGAIN. Other errors, like reads of a closed file, are returned as is.
+func isTransient(err error) bool {
+	// context.DeadlineExceeded is a net.Error too
+	if err == context.Canceled || err == context.DeadlineExceeded {
+		return false
+	}
+
+	if err, ok := err.(net.Error); ok {
+		return err.Timeout() || err.Temporary()
+	}
+
+	switch e := err.(type) {
+	case *os.PathError:
+		err = e.Err
+	case *os.SyscallError:
+		err = e.Err
+	}
+
+	return err == syscall.EIO || err == syscall.EAGAIN
+}
@@ -0,0 +1,100 @@
+package cdb
+
+import (
+	"context"
+	"io"
+	"os"
+	"syscall"
+	"time"
+)
+
+// flakyReaderAt fails every other read with err, EIO by default
+type flakyReaderAt struct {
+	reader   io.ReaderAt
+	err      error
+	failures int
+}
+
+func (f *flakyReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	f.failures++
+
+	if f.failures%2 == 1 {
+		if f.err != nil {
+			return 0, f.err
+		}
+
+		return 0, &os.PathError{Op: "read", Path: "flaky", Err: syscall.EIO}
+	}
+
+	return f.reader.ReadAt(p, off)
+}
+
+func (suite *CDBTestSuite) TestRetry() {
+	suite.fillTestCDB()
+
+	flaky := &flakyReaderAt{reader: suite.cdbFile}
+
+	_, err := suite.cdbHandle.GetReader(flaky)
+	suite.NotNil(err)
+
+	suite.Require().Nil(suite.cdbHandle.SetRetry(1, time.Microsecond))
+
+	reader, err := suite.cdbHandle.GetReader(flaky)
+	suite.Require().Nil(err)
+
+	for _, rec := range suite.testRecords {
+		value, err := reader.Get(rec.key)
+		suite.Nil(err)
+		suite.Equal(rec.val, value)
+	}
+
+	suite.Equal(ErrInvalidRetry, suite.cdbHandle.SetRetry(-1, 0))
+}
+
+func (suite *CDBTestSuite) TestRetryOnlyTransient() {
+	suite.fillTestCDB()
+
+	flaky := &flakyReaderAt{reader: suite.cdbFile, err: os.ErrClosed}
+	retry := &retryReaderAt{flaky, retryPolicy{attempts: 3, backoff: time.Microsecond}}
+
+	_, err := retry.ReadAt(make([]byte, 8), 0)
+	suite.Equal(os.ErrClosed, err)
+	suite.Equal(1, flaky.failures, "reads of a closed file aren't retried")
+
+	suite.False(isTransient(nil))
+	suite.False(isTransient(io.EOF))
+	suite.False(isTransient(context.DeadlineExceeded))
+	suite.False(isTransient(&os.PathError{Op: "read", Path: "closed", Err: syscall.EBADF}))
+	suite.True(isTransient(syscall.EAGAIN))
+	suite.True(isTransient(&os.SyscallError{Syscall: "pread", Err: syscall.EIO}))
+}
+
+func (suite *CDBTestSuite) TestRetryContext() {
+	suite.fillTestCDB()
+
+	flaky := &flakyReaderAt{reader: suite.cdbFile}
+	retry := &retryReaderAt{flaky, retryPolicy{attempts: 1, backoff: time.Hour}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := (&contextReaderAt{ctx, retry}).ReadAt(make([]byte, 8), 0)
+	suite.Equal(context.DeadlineExceeded, err)
+	suite.True(time.Since(start) < maxRetryBackoff, "the backoff is interrupted by the context")
+	suite.Equal(1, flaky.failures)
+}
+
+func (suite *CDBTestSuite) TestRetryDelay() {
+	policy := retryPolicy{attempts: 100, backoff: time.Millisecond}
+
+	for i := 0; i < 100; i++ {
+		d := policy.delay(i)
+		suite.True(d <= maxRetryBackoff)
+
+		if i < 10 {
+			suite.True(d >= time.Millisecond<<uint(i)/2)
+			suite.True(d < time.Millisecond<<uint(i))
+		}
+	}
+}
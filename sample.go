@@ -0,0 +1,176 @@
+package cdb
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math/rand"
+)
+
+// Number of slots probed per sampled record, before Sample gives up
+const sampleAttempts = 16
+
+// Sample returns at most n distinct records chosen uniformly at random, see Reader.Sample.
+// Records are found by probing random hash table slots, every record has exactly one slot,
+// so neither the record sizes nor the file order bias the choice.
+func (r *readerImpl) Sample(n int) ([]Record, error) {
+	var slots int64
+	for _, ref := range r.refs {
+		slots += int64(ref.length)
+	}
+
+	if n <= 0 || slots == 0 || r.IsEmpty() {
+		return nil, nil
+	}
+
+	var (
+		records []Record
+		entry   slot
+		seen    = make(map[uint32]bool, n)
+	)
+
+	for attempt := 0; len(records) < n && attempt < n*sampleAttempts; attempt++ {
+		k := rand.Int63n(slots)
+
+		table := 0
+		for k >= int64(r.refs[table].length) {
+			k -= int64(r.refs[table].length)
+			table++
+		}
+
+		if err := r.readSlot(r.refs[table].position+uint32(k)*r.header.slotSize(), &entry); err != nil {
+			return nil, err
+		}
+
+		if entry.position == 0 || seen[entry.position] {
+			continue
+		}
+
+		seen[entry.position] = true
+
+		layout, err := r.readRecord(entry.position)
+		if err != nil {
+			return nil, err
+		}
+
+		if r.skip(layout) {
+			continue
+		}
+
+		record, err := r.recordOf(layout)
+		if err != nil {
+			return nil, err
+		}
+
+		records = append(records, record)
+	}
+
+	return records, nil
+}
+
+// recordOf returns the record of the given layout, a compressed key is restored in memory
+func (r *readerImpl) recordOf(layout recordLayout) (Record, error) {
+	key := &sectionReaderFactory{reader: r.reader, position: layout.keyPosition, size: layout.keySize}
+
+	if layout.compressed() {
+		restored, err := r.readKey(layout)
+		if err != nil {
+			return nil, err
+		}
+
+		key = &sectionReaderFactory{reader: bytes.NewReader(restored), size: layout.keySize}
+	}
+
+	return &record{
+		keySectionFactory:   key,
+		valueSectionFactory: &sectionReaderFactory{reader: r.reader, position: layout.valPosition, size: layout.valSize},
+	}, nil
+}
+
+// Sample returns at most n distinct records of all parts chosen uniformly at random, see Reader.Sample
+func (r *shardedReader) Sample(n int) ([]Record, error) {
+	return sampleParts(r.parts, n, nil)
+}
+
+// Sample returns at most n distinct visible records of all layers chosen at random, see Reader.Sample.
+// Records of layers are sampled, shadowed ones are dropped, so fewer records may be returned.
+func (r *stackedReader) Sample(n int) ([]Record, error) {
+	return sampleParts(r.layers, n, func(i int, key []byte) (bool, error) {
+		_, found, err := r.find(key)
+		if err == ErrEntryNotFound {
+			return false, nil
+		}
+
+		return found == i, err
+	})
+}
+
+// sampleParts draws the number of records to sample from every part in proportion to its size,
+// samples the parts and shuffles the records. visible, if given, tells if a record of a part is kept.
+func sampleParts(parts []Reader, n int, visible func(part int, key []byte) (bool, error)) ([]Record, error) {
+	sizes := make([]int, len(parts))
+	total := 0
+
+	for j, part := range parts {
+		sizes[j] = part.Size()
+		total += sizes[j]
+	}
+
+	if n <= 0 || total == 0 {
+		return nil, nil
+	}
+
+	counts := make([]int, len(parts))
+
+	for k := 0; k < n; k++ {
+		x := rand.Intn(total)
+
+		j := 0
+		for x >= sizes[j] {
+			x -= sizes[j]
+			j++
+		}
+
+		counts[j]++
+	}
+
+	var records []Record
+
+	for j, part := range parts {
+		if counts[j] == 0 {
+			continue
+		}
+
+		sampled, err := part.Sample(counts[j])
+		if err != nil {
+			return nil, err
+		}
+
+		for _, record := range sampled {
+			if visible != nil {
+				keyReader, _ := record.Key()
+
+				key, err := ioutil.ReadAll(keyReader)
+				if err != nil {
+					return nil, err
+				}
+
+				ok, err := visible(j, key)
+				if err != nil {
+					return nil, err
+				}
+
+				if !ok {
+					continue
+				}
+			}
+
+			records = append(records, record)
+		}
+	}
+
+	rand.Shuffle(len(records), func(i, j int) {
+		records[i], records[j] = records[j], records[i]
+	})
+
+	return records, nil
+}
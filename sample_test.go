@@ -0,0 +1,89 @@
+package cdb
+
+import (
+	"io/ioutil"
+	"strconv"
+)
+
+// readRecord returns the key and the value of the given record
+func (suite *CDBTestSuite) readRecord(record Record) (string, string) {
+	keyReader, _ := record.Key()
+	key, err := ioutil.ReadAll(keyReader)
+	suite.Require().Nil(err)
+
+	valueReader, _ := record.Value()
+	value, err := ioutil.ReadAll(valueReader)
+	suite.Require().Nil(err)
+
+	return string(key), string(value)
+}
+
+func (suite *CDBTestSuite) TestSample() {
+	suite.cdbHandle.SetKeyPrefixCompression(true)
+
+	writer := suite.getCDBWriter()
+	for i := 0; i < 1000; i++ {
+		suite.Require().Nil(writer.Put([]byte("key/"+strconv.Itoa(i)), []byte(strconv.Itoa(i))))
+	}
+	suite.Require().Nil(writer.Close())
+
+	reader := suite.getCDBReader()
+
+	records, err := reader.Sample(100)
+	suite.Require().Nil(err)
+	suite.Len(records, 100)
+
+	seen := map[string]bool{}
+	for _, record := range records {
+		key, value := suite.readRecord(record)
+		suite.Equal("key/"+value, key)
+		suite.False(seen[key], "Key %s is sampled twice", key)
+		seen[key] = true
+	}
+
+	records, err = reader.Sample(2000)
+	suite.Nil(err)
+	suite.True(len(records) <= 1000)
+
+	records, err = reader.Sample(0)
+	suite.Nil(err)
+	suite.Empty(records)
+}
+
+func (suite *CDBTestSuite) TestStackedSample() {
+	suite.cdbHandle.SetRecordFlags(true)
+
+	files := suite.createShardFiles(2)
+	defer suite.removeShardFiles(files)
+
+	readers := make([]Reader, len(files))
+	for i, f := range files {
+		writer, err := suite.cdbHandle.GetWriter(f)
+		suite.Require().Nil(err)
+
+		for k := 0; k < 100; k++ {
+			meta := recordMeta{}
+			// The newer layer deletes even keys
+			if i == 1 && k%2 == 0 {
+				meta.flags = RecordTombstone
+			}
+			suite.Require().Nil(writer.(*writerImpl).put([]byte(strconv.Itoa(k)), []byte(strconv.Itoa(i)), meta))
+		}
+		suite.Require().Nil(writer.Close())
+
+		readers[i], err = suite.cdbHandle.GetReader(f)
+		suite.Require().Nil(err)
+	}
+
+	records, err := Stack(readers...).Sample(50)
+	suite.Require().Nil(err)
+	suite.NotEmpty(records)
+
+	for _, record := range records {
+		key, value := suite.readRecord(record)
+		k, err := strconv.Atoi(key)
+		suite.Require().Nil(err)
+		suite.Equal(1, k%2, "Deleted key %s is sampled", key)
+		suite.Equal("1", value, "Shadowed record of key %s is sampled", key)
+	}
+}
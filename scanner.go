@@ -0,0 +1,208 @@
+package cdb
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+)
+
+// Scanner reads records of a database sequentially from an io.Reader, e.g. a pipe or a decompressed
+// stream, without ReadAt. It walks the data section only, hash tables and trailers after it
+// aren't read, so lookups aren't possible and the stream may be closed after the last record.
+// Like Reader.Iterator, Scanner walks the root bucket in the Put order and skips superseded
+// versions and, in the skip expired mode, expired records. Do not share object between multiple goroutines.
+type Scanner struct {
+	reader *bufio.Reader
+	header header
+	opts   options
+	// position is the position of the next byte of the stream, end is the end of the data section
+	position, end uint32
+	// anchor is the position of the last record, which key is stored in full, anchorKey is its key
+	anchor    uint32
+	anchorKey []byte
+	// buf holds the current record header
+	buf   []byte
+	key   []byte
+	value []byte
+	flags RecordFlags
+	err   error
+}
+
+// GetScanner reads the header of the database from the given reader and returns a new Scanner
+// positioned before the first record.
+func (cdb *CDB) GetScanner(reader io.Reader) (*Scanner, error) {
+	s := &Scanner{
+		reader: bufio.NewReader(reader),
+		opts:   cdb.opts,
+	}
+
+	if err := s.readHeader(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// readHeader reads the header and the hash table refs, the scanner is positioned on the data section then
+func (s *Scanner) readHeader() error {
+	head, err := s.readHead(nil, 8)
+	if err != nil {
+		return err
+	}
+
+	if binary.LittleEndian.Uint32(head) == 0 && binary.LittleEndian.Uint32(head[4:]) == v2Magic {
+		if head, err = s.readHead(head, 12); err != nil {
+			return err
+		}
+
+		size := binary.LittleEndian.Uint32(head[8:])
+		if size < v2MinHeaderSize || size > maxHeaderSize {
+			return ErrInvalidHeader
+		}
+
+		if head, err = s.readHead(head, size); err != nil {
+			return err
+		}
+	}
+
+	if s.header, err = readHeader(bytes.NewReader(head)); err != nil {
+		return err
+	}
+
+	if head, err = s.readHead(head, s.header.dataPosition()); err != nil {
+		return err
+	}
+
+	refs := head[s.header.refsPosition():]
+
+	for i := uint32(0); i < s.header.tableNum; i++ {
+		if position := binary.LittleEndian.Uint32(refs[i*8:]); position != 0 {
+			s.end = position
+			break
+		}
+	}
+
+	return nil
+}
+
+// readHead reads the start of the stream up to the given size into the given buffer
+func (s *Scanner) readHead(head []byte, size uint32) ([]byte, error) {
+	n := len(head)
+	head = append(head, make([]byte, int(size)-n)...)
+
+	if _, err := io.ReadFull(s.reader, head[n:]); err != nil {
+		return nil, ErrInvalidHeader
+	}
+
+	s.position = size
+
+	return head, nil
+}
+
+// Scan moves the scanner to the next record. Returns false at the end of the data section
+// or on an error, see Err.
+func (s *Scanner) Scan() bool {
+	for s.err == nil && s.position < s.end {
+		layout, err := s.readRecord()
+		if err != nil {
+			s.err = err
+			return false
+		}
+
+		if layout.flags&RecordSuperseded == 0 && layout.bucket == 0 &&
+			!(s.opts.skipExpired && layout.expired(s.opts.now().UnixNano())) {
+			s.flags = layout.flags
+			return true
+		}
+	}
+
+	return false
+}
+
+// readRecord reads the record at the current position of the stream and the padding after it
+func (s *Scanner) readRecord() (recordLayout, error) {
+	pos := s.position
+	s.buf = grow(s.buf, s.header.recordHeaderSize())
+
+	if err := s.read(s.buf); err != nil {
+		return recordLayout{}, corrupted(err, int64(pos), -1, "record header is out of the database")
+	}
+
+	layout, err := s.header.parseRecord(s.buf, pos, s.end)
+	if err != nil {
+		return recordLayout{}, err
+	}
+
+	s.key = grow(s.key, layout.keySize)
+
+	if layout.compressed() {
+		if layout.anchor != s.anchor || int(layout.shared) > len(s.anchorKey) {
+			return recordLayout{}, corrupted(nil, int64(pos), -1, "anchor record is not the last record with a full key")
+		}
+
+		copy(s.key, s.anchorKey[:layout.shared])
+	}
+
+	if err := s.read(s.key[layout.shared:]); err != nil {
+		return recordLayout{}, corrupted(err, int64(pos), -1, "key is out of the database")
+	}
+
+	if !layout.compressed() && s.header.flags&flagPrefixCompression != 0 {
+		s.anchor, s.anchorKey = pos, append(s.anchorKey[:0], s.key...)
+	}
+
+	s.value = grow(s.value, layout.valSize)
+
+	if err := s.read(s.value); err != nil {
+		return recordLayout{}, corrupted(err, int64(pos), -1, "value is out of the database")
+	}
+
+	if padding := s.header.alignPosition(s.position) - s.position; padding > 0 {
+		if _, err := io.CopyN(ioutil.Discard, s.reader, int64(padding)); err != nil {
+			return recordLayout{}, corrupted(err, int64(s.position), -1, "record padding is out of the database")
+		}
+
+		s.position += padding
+	}
+
+	return layout, nil
+}
+
+// grow returns the given buffer resized to the given size, it is reallocated only if it is too small
+func grow(buf []byte, size uint32) []byte {
+	if uint32(cap(buf)) < size {
+		return make([]byte, size)
+	}
+
+	return buf[:size]
+}
+
+// read reads exactly len(buf) bytes of the stream
+func (s *Scanner) read(buf []byte) error {
+	n, err := io.ReadFull(s.reader, buf)
+	s.position += uint32(n)
+
+	return err
+}
+
+// Err returns the error, which stopped the scanner, nil at the end of the data section
+func (s *Scanner) Err() error {
+	return s.err
+}
+
+// Key returns the key of the current record. The key is valid until the next call of Scan.
+func (s *Scanner) Key() []byte {
+	return s.key
+}
+
+// Value returns the value of the current record. The value is valid until the next call of Scan.
+func (s *Scanner) Value() []byte {
+	return s.value
+}
+
+// Flags returns the raw flags of the current record, 0 if the database has no record flags.
+func (s *Scanner) Flags() RecordFlags {
+	return s.flags
+}
@@ -0,0 +1,96 @@
+package cdb
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+)
+
+// scanRecords returns the records "key=value" walked by the scanner
+func (suite *CDBTestSuite) scanRecords(scanner *Scanner) []string {
+	var records []string
+
+	for scanner.Scan() {
+		records = append(records, string(scanner.Key())+"="+string(scanner.Value()))
+	}
+
+	return records
+}
+
+func (suite *CDBTestSuite) TestScanner() {
+	suite.fillTestCDB()
+
+	data, err := ioutil.ReadFile(suite.cdbFile.Name())
+	suite.Require().Nil(err)
+
+	compressed := &bytes.Buffer{}
+	gz := gzip.NewWriter(compressed)
+	_, err = gz.Write(data)
+	suite.Require().Nil(err)
+	suite.Require().Nil(gz.Close())
+
+	stream, err := gzip.NewReader(compressed)
+	suite.Require().Nil(err)
+
+	scanner, err := suite.cdbHandle.GetScanner(stream)
+	suite.Require().Nil(err)
+
+	for _, rec := range suite.testRecords {
+		suite.Require().True(scanner.Scan())
+		suite.Equal(rec.key, scanner.Key())
+		suite.Equal(rec.val, scanner.Value())
+	}
+
+	suite.False(scanner.Scan())
+	suite.Nil(scanner.Err())
+}
+
+func (suite *CDBTestSuite) TestScannerV2() {
+	suite.cdbHandle.SetKeyPrefixCompression(true)
+	suite.cdbHandle.SetVersions(1)
+	suite.cdbHandle.SetBuckets(true)
+	suite.Require().Nil(suite.cdbHandle.SetAlignment(8))
+
+	writer := suite.getCDBWriter()
+	suite.Require().Nil(writer.Put([]byte("common/prefix/a"), []byte("1")))
+	suite.Require().Nil(writer.Put([]byte("common/prefix/b"), []byte("22")))
+	suite.Require().Nil(writer.Bucket("other").Put([]byte("common/prefix/c"), []byte("333")))
+	suite.Require().Nil(writer.Put([]byte("common/prefix/a"), []byte("4444")))
+	suite.Require().Nil(writer.Put([]byte("x"), []byte("55555")))
+	suite.Require().Nil(writer.Close())
+
+	iterator, err := suite.getCDBReader().Iterator()
+	suite.Require().Nil(err)
+	expected := suite.mergedRecords(iterator)
+	suite.Equal([]string{"common/prefix/b=22", "common/prefix/a=4444", "x=55555"}, expected)
+
+	data, err := ioutil.ReadFile(suite.cdbFile.Name())
+	suite.Require().Nil(err)
+
+	scanner, err := suite.cdbHandle.GetScanner(bytes.NewReader(data))
+	suite.Require().Nil(err)
+	suite.Equal(expected, suite.scanRecords(scanner))
+	suite.Nil(scanner.Err())
+}
+
+func (suite *CDBTestSuite) TestScannerTruncated() {
+	suite.fillTestCDB()
+
+	data, err := ioutil.ReadFile(suite.cdbFile.Name())
+	suite.Require().Nil(err)
+
+	_, err = suite.cdbHandle.GetScanner(bytes.NewReader(data[:100]))
+	suite.Equal(ErrInvalidHeader, err)
+
+	// Cut the stream in the middle of the second record
+	reader := suite.getCDBReader()
+	iterator, err := reader.Iterator()
+	suite.Require().Nil(err)
+	suite.mustNext(iterator)
+
+	scanner, err := suite.cdbHandle.GetScanner(io.LimitReader(bytes.NewReader(data), int64(iterator.Offset()+3)))
+	suite.Require().Nil(err)
+	suite.Equal([]string{string(suite.testRecords[0].key) + "=" + string(suite.testRecords[0].val)}, suite.scanRecords(scanner))
+	suite.IsType(&CorruptionError{}, scanner.Err())
+}
@@ -0,0 +1,48 @@
+package cdb
+
+import (
+	"io"
+	"sync"
+)
+
+// seekerReaderAt implements io.ReaderAt over an io.ReadSeeker. Reads are serialized.
+type seekerReaderAt struct {
+	mu     sync.Mutex
+	source io.ReadSeeker
+	size   int64
+}
+
+// GetReaderFromSeeker returns a new Reader object over the given io.ReadSeeker, for sources,
+// which can't read at an offset. Reads of the database are serialized by a lock, so concurrent
+// lookups don't run in parallel. The source must not be used by anything else while the reader is in use.
+func (cdb *CDB) GetReaderFromSeeker(source io.ReadSeeker) (Reader, error) {
+	size, err := source.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, err
+	}
+
+	return cdb.GetReader(&seekerReaderAt{source: source, size: size})
+}
+
+// ReadAt seeks the source to the given offset and reads len(p) bytes
+func (s *seekerReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.source.Seek(off, io.SeekStart); err != nil {
+		return 0, err
+	}
+
+	n, err := io.ReadFull(s.source, p)
+
+	if err == io.ErrUnexpectedEOF {
+		err = io.EOF
+	}
+
+	return n, err
+}
+
+// Size returns the size of the source
+func (s *seekerReaderAt) Size() int64 {
+	return s.size
+}
@@ -0,0 +1,198 @@
+package cdb
+
+import (
+	"errors"
+	"sort"
+)
+
+// Number of slots sampled per segment to find segment bounds
+const segmentSamples = 64
+
+// ErrInvalidSegmentNum tells that the number of segments is not positive
+var ErrInvalidSegmentNum = errors.New("cdb segment number must be positive")
+
+// Segments splits the data section into at most n disjoint segments of about the same size
+// and returns iterators over their records, see Reader.Segments. Segment bounds are record
+// positions sampled from the hash tables, so the records are not walked to find them.
+func (r *readerImpl) Segments(n int) ([]Iterator, error) {
+	if n < 1 {
+		return nil, ErrInvalidSegmentNum
+	}
+
+	if r.IsEmpty() {
+		return nil, ErrEmptyCDB
+	}
+
+	bounds, err := r.segmentBounds(n)
+	if err != nil {
+		return nil, err
+	}
+
+	iterators := make([]Iterator, 0, n)
+
+	for k := 0; k+1 < len(bounds); k++ {
+		iterator, err := r.newIterator(bounds[k], nil, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		iterator.end = bounds[k+1]
+
+		ok, err := iterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		// Every record of the segment is expired
+		if ok {
+			iterator.mark()
+			iterators = append(iterators, iterator)
+		}
+	}
+
+	if len(iterators) == 0 {
+		return nil, ErrEmptyCDB
+	}
+
+	return iterators, nil
+}
+
+// segmentBounds returns increasing record positions, which split the data section into
+// at most n segments, the first one is the start and the last one is the end of the data section
+func (r *readerImpl) segmentBounds(n int) ([]uint32, error) {
+	start, end := r.header.dataPosition(), r.endPos
+
+	var slots uint32
+	for _, ref := range r.refs {
+		slots += ref.length
+	}
+
+	step := slots / uint32(n*segmentSamples)
+	if step == 0 {
+		step = 1
+	}
+
+	var (
+		samples []uint32
+		entry   slot
+	)
+
+	size := r.header.slotSize()
+
+	for _, ref := range r.refs {
+		for k := uint32(0); k < ref.length; k += step {
+			if err := r.readSlot(ref.position+k*size, &entry); err != nil {
+				return nil, err
+			}
+
+			if entry.position > start && entry.position < end {
+				samples = append(samples, entry.position)
+			}
+		}
+	}
+
+	sort.Slice(samples, func(i, j int) bool { return samples[i] < samples[j] })
+
+	bounds := []uint32{start}
+
+	for k := 1; k < n; k++ {
+		target := start + uint32(uint64(end-start)*uint64(k)/uint64(n))
+		j := sort.Search(len(samples), func(j int) bool { return samples[j] >= target })
+
+		if j < len(samples) && samples[j] > bounds[len(bounds)-1] {
+			bounds = append(bounds, samples[j])
+		}
+	}
+
+	return append(bounds, end), nil
+}
+
+// Segments returns iterators over segments of all parts, see Reader.Segments
+func (r *shardedReader) Segments(n int) ([]Iterator, error) {
+	return segmentParts(r.parts, n, func(_ int, iterator Iterator) (Iterator, error) {
+		return iterator, nil
+	})
+}
+
+// segmentParts splits the given parts into at most n segments and returns iterators over them.
+// With fewer segments than parts, a segment walks several whole parts, otherwise every part
+// is split into its share of segments. wrap is applied to the iterator of every part or part
+// segment, it may return nil to skip it.
+func segmentParts(parts []Reader, n int, wrap func(part int, iterator Iterator) (Iterator, error)) ([]Iterator, error) {
+	if n < 1 {
+		return nil, ErrInvalidSegmentNum
+	}
+
+	var segments []*concatIterator
+
+	if n < len(parts) {
+		segments = make([]*concatIterator, n)
+	}
+
+	for j, part := range parts {
+		var (
+			iterators []Iterator
+			err       error
+		)
+
+		if n < len(parts) {
+			var iterator Iterator
+
+			if iterator, err = part.Iterator(); err == nil {
+				iterators = []Iterator{iterator}
+			}
+		} else {
+			share := n / len(parts)
+			if j < n%len(parts) {
+				share++
+			}
+
+			iterators, err = part.Segments(share)
+		}
+
+		if err == ErrEmptyCDB {
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		for _, iterator := range iterators {
+			if iterator, err = wrap(j, iterator); err != nil {
+				return nil, err
+			}
+
+			if iterator == nil {
+				continue
+			}
+
+			if n >= len(parts) {
+				segments = append(segments, &concatIterator{iterators: []Iterator{iterator}, parts: []int{j}})
+				continue
+			}
+
+			// Whole parts are grouped round-robin
+			if segments[j%n] == nil {
+				segments[j%n] = &concatIterator{}
+			}
+
+			segments[j%n].iterators = append(segments[j%n].iterators, iterator)
+			segments[j%n].parts = append(segments[j%n].parts, j)
+		}
+	}
+
+	iterators := make([]Iterator, 0, len(segments))
+
+	for _, segment := range segments {
+		if segment != nil {
+			iterators = append(iterators, segment)
+		}
+	}
+
+	if len(iterators) == 0 {
+		return nil, ErrEmptyCDB
+	}
+
+	return iterators, nil
+}
@@ -0,0 +1,104 @@
+package cdb
+
+import (
+	"bytes"
+	"strconv"
+)
+
+// walkSegments returns the number of times every key is walked by the given segments
+func (suite *CDBTestSuite) walkSegments(segments []Iterator) map[string]int {
+	walked := map[string]int{}
+
+	for _, segment := range segments {
+		for ok := true; ok; ok = suite.mustNext(segment) {
+			key, err := segment.Key()
+			suite.Require().Nil(err)
+			walked[string(key)]++
+		}
+	}
+
+	return walked
+}
+
+func (suite *CDBTestSuite) TestSegments() {
+	const n = 1000
+
+	writer := suite.getCDBWriter()
+	for i := 0; i < n; i++ {
+		suite.Require().Nil(writer.Put([]byte(strconv.Itoa(i)), bytes.Repeat([]byte("v"), i%100)))
+	}
+	suite.Require().Nil(writer.Close())
+
+	reader := suite.getCDBReader()
+
+	_, err := reader.Segments(0)
+	suite.Equal(ErrInvalidSegmentNum, err)
+
+	for _, k := range []int{1, 4, 16} {
+		segments, err := reader.Segments(k)
+		suite.Require().Nil(err)
+		suite.True(len(segments) <= k)
+		suite.True(len(segments) > k/2, "%d segments of %d", len(segments), k)
+
+		walked := suite.walkSegments(segments)
+		suite.Len(walked, n)
+
+		for key, times := range walked {
+			suite.Equal(1, times, "Key %s is walked %d times", key, times)
+		}
+	}
+
+	// A resumed segment stops at the end of the segment
+	segments, err := reader.Segments(4)
+	suite.Require().Nil(err)
+
+	cursor, err := segments[0].Cursor()
+	suite.Require().Nil(err)
+
+	resumed, err := reader.ResumeIterator(cursor)
+	suite.Require().Nil(err)
+	suite.Equal(suite.restOf(segments[0]), suite.restOf(resumed))
+}
+
+func (suite *CDBTestSuite) TestShardedSegments() {
+	files := suite.createShardFiles(3)
+	defer suite.removeShardFiles(files)
+
+	writers := make([]Writer, len(files))
+	for i, f := range files {
+		writer, err := suite.cdbHandle.GetWriter(f)
+		suite.Require().Nil(err)
+		writers[i] = writer
+	}
+
+	writer, err := suite.cdbHandle.NewShardedWriter(writers)
+	suite.Require().Nil(err)
+
+	for i := 0; i < 300; i++ {
+		suite.Require().Nil(writer.Put([]byte(strconv.Itoa(i)), []byte("value")))
+	}
+
+	suite.Require().Nil(writer.Close())
+
+	readers := make([]Reader, len(files))
+	for i, f := range files {
+		readers[i], err = suite.cdbHandle.GetReader(f)
+		suite.Require().Nil(err)
+	}
+
+	reader, err := suite.cdbHandle.NewShardedReader(readers)
+	suite.Require().Nil(err)
+
+	for _, k := range []int{2, 3, 8} {
+		segments, err := reader.Segments(k)
+		suite.Require().Nil(err)
+		suite.True(len(segments) <= k)
+
+		walked := suite.walkSegments(segments)
+		suite.Len(walked, 300)
+
+		for key, times := range walked {
+			suite.Equal(1, times, "Key %s is walked %d times", key, times)
+		}
+	}
+}
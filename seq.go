@@ -0,0 +1,41 @@
+//go:build go1.23
+// +build go1.23
+
+package cdb
+
+import "iter"
+
+// All returns a sequence of the pairs <key, value> of the reader in the file order:
+//
+//	var err error
+//	for key, value := range cdb.All(reader, &err) {
+//		...
+//	}
+//	if err != nil {
+//		...
+//	}
+//
+// An error stops the sequence and is stored into *errp, an empty database yields nothing.
+func All(reader Reader, errp *error) iter.Seq2[[]byte, []byte] {
+	return func(yield func(key, value []byte) bool) {
+		*errp = walk(reader, true, yield)
+	}
+}
+
+// Keys returns a sequence of the keys of the reader in the file order, see All.
+func Keys(reader Reader, errp *error) iter.Seq[[]byte] {
+	return func(yield func(key []byte) bool) {
+		*errp = walk(reader, false, func(key, _ []byte) bool {
+			return yield(key)
+		})
+	}
+}
+
+// Values returns a sequence of the values of the reader in the file order, see All.
+func Values(reader Reader, errp *error) iter.Seq[[]byte] {
+	return func(yield func(value []byte) bool) {
+		*errp = walk(reader, true, func(_, value []byte) bool {
+			return yield(value)
+		})
+	}
+}
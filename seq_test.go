@@ -0,0 +1,55 @@
+//go:build go1.23
+// +build go1.23
+
+package cdb
+
+func (suite *CDBTestSuite) TestAll() {
+	suite.fillTestCDB()
+	reader := suite.getCDBReader()
+
+	var err error
+	i := 0
+
+	for key, value := range All(reader, &err) {
+		suite.Equal(suite.testRecords[i].key, key)
+		suite.Equal(suite.testRecords[i].val, value)
+		i++
+	}
+
+	suite.Nil(err)
+	suite.Equal(len(suite.testRecords), i)
+
+	i = 0
+	for key := range Keys(reader, &err) {
+		suite.Equal(suite.testRecords[i].key, key)
+		i++
+
+		if i == 3 {
+			break
+		}
+	}
+
+	suite.Nil(err)
+	suite.Equal(3, i)
+
+	i = 0
+	for value := range Values(reader, &err) {
+		suite.Equal(suite.testRecords[i].val, value)
+		i++
+	}
+
+	suite.Nil(err)
+	suite.Equal(len(suite.testRecords), i)
+}
+
+func (suite *CDBTestSuite) TestAllEmpty() {
+	suite.writeEmptyCDB()
+	reader := suite.getCDBReader()
+
+	var err error
+	for range All(reader, &err) {
+		suite.Fail("empty database yields a record")
+	}
+
+	suite.Nil(err)
+}
@@ -0,0 +1,693 @@
+package cdb
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"time"
+)
+
+// ErrShardedOffset tells that records of a sharded database can't be addressed by offsets,
+// offsets are meaningful for part readers only
+var ErrShardedOffset = errors.New("cdb sharded reader can't get records by offsets, use part readers")
+
+// ErrNoShards tells that it was an attempt to create a sharded writer or reader without parts
+var ErrNoShards = errors.New("cdb sharded database must have at least one part")
+
+// ErrMergedSeek tells that an iterator merging several databases in the key order can't seek,
+// it would have to position every merged iterator. Seek a Range iterator of a part reader instead.
+var ErrMergedSeek = errors.New("cdb merged iterator can't seek, use part readers")
+
+// shardedWriter implements Writer interface, distributes records over part writers by key hash
+type shardedWriter struct {
+	parts  []Writer
+	hasher Hasher
+}
+
+// shardedReader implements Reader interface, routes lookups to part readers by key hash
+type shardedReader struct {
+	parts  []Reader
+	hasher Hasher
+}
+
+// NewShardedWriter returns a Writer, which distributes records over the given part writers
+// by key hash, so that datasets beyond the 4 gigabytes limit of a single database can be written.
+// All records with the same key go to the same part. Parts are read back with NewShardedReader
+// of a handle with the same Hasher, given in the same order.
+func (cdb *CDB) NewShardedWriter(parts []Writer) (Writer, error) {
+	if len(parts) == 0 {
+		return nil, ErrNoShards
+	}
+
+	return &shardedWriter{
+		parts:  parts,
+		hasher: cdb.Hasher,
+	}, nil
+}
+
+// NewShardedReader returns a Reader, which routes lookups to the given part readers by key hash
+// and walks all of them on iteration. Parts must be given in the order of NewShardedWriter.
+func (cdb *CDB) NewShardedReader(parts []Reader) (Reader, error) {
+	if len(parts) == 0 {
+		return nil, ErrNoShards
+	}
+
+	return &shardedReader{
+		parts:  parts,
+		hasher: cdb.Hasher,
+	}, nil
+}
+
+// shardOf returns the number of the part, which keeps the given key
+func shardOf(hasher Hasher, key []byte, n int) int {
+	hashFunc := hasher()
+	hashFunc.Write(key)
+
+	// The key hash is mixed, so that parts don't share the distribution over hash tables
+	return int(mix32(^hashFunc.Sum32()) % uint32(n))
+}
+
+// Put saves a new associated pair <key, value> into the part of the key.
+func (w *shardedWriter) Put(key, value []byte) error {
+	return w.parts[shardOf(w.hasher, key, len(w.parts))].Put(key, value)
+}
+
+// PutWithExpiry saves a new associated pair <key, value>, which expires at the given time, into the part of the key.
+func (w *shardedWriter) PutWithExpiry(key, value []byte, expiresAt time.Time) error {
+	return w.parts[shardOf(w.hasher, key, len(w.parts))].PutWithExpiry(key, value, expiresAt)
+}
+
+// Bucket returns a Writer, which distributes records over the buckets of part writers.
+func (w *shardedWriter) Bucket(name string) Writer {
+	parts := make([]Writer, len(w.parts))
+
+	for i, part := range w.parts {
+		parts[i] = part.Bucket(name)
+	}
+
+	return &shardedWriter{
+		parts:  parts,
+		hasher: w.hasher,
+	}
+}
+
+// Close commits all parts. Returns the first error.
+func (w *shardedWriter) Close() error {
+	var firstErr error
+
+	for _, part := range w.parts {
+		if err := part.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return firstErr
+}
+
+// Flush flushes all parts. Returns the first error.
+func (w *shardedWriter) Flush() error {
+	var firstErr error
+
+	for _, part := range w.parts {
+		if err := part.Flush(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return firstErr
+}
+
+// Abort aborts all parts. Returns the first error.
+func (w *shardedWriter) Abort() error {
+	var firstErr error
+
+	for _, part := range w.parts {
+		if err := part.Abort(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return firstErr
+}
+
+// part returns the reader of the part, which keeps the given key
+func (r *shardedReader) part(key []byte) Reader {
+	return r.parts[shardOf(r.hasher, key, len(r.parts))]
+}
+
+// Get returns the first value associated with the given key
+func (r *shardedReader) Get(key []byte) ([]byte, error) {
+	return r.part(key).Get(key)
+}
+
+// MultiGet returns the first values associated with the given keys, every part gets its keys in one batch
+func (r *shardedReader) MultiGet(keys [][]byte) ([][]byte, []error) {
+	values := make([][]byte, len(keys))
+	errs := make([]error, len(keys))
+	shards := make([][]int, len(r.parts))
+
+	for i, key := range keys {
+		part := shardOf(r.hasher, key, len(r.parts))
+		shards[part] = append(shards[part], i)
+	}
+
+	for part, numbers := range shards {
+		if len(numbers) == 0 {
+			continue
+		}
+
+		partKeys := make([][]byte, len(numbers))
+		for j, i := range numbers {
+			partKeys[j] = keys[i]
+		}
+
+		partValues, partErrs := r.parts[part].MultiGet(partKeys)
+
+		for j, i := range numbers {
+			values[i], errs[i] = partValues[j], partErrs[j]
+		}
+	}
+
+	return values, errs
+}
+
+// GetAt returns ErrShardedOffset, offsets belong to parts
+func (r *shardedReader) GetAt(offset uint32) ([]byte, []byte, error) {
+	return nil, nil, ErrShardedOffset
+}
+
+// GetSize returns the length of the first value associated with the given key
+func (r *shardedReader) GetSize(key []byte) (int, error) {
+	return r.part(key).GetSize(key)
+}
+
+// GetRange returns the part of the first value associated with the given key
+func (r *shardedReader) GetRange(key []byte, offset, length int) ([]byte, error) {
+	return r.part(key).GetRange(key, offset, length)
+}
+
+// GetStream returns a stream of the first value associated with the given key
+func (r *shardedReader) GetStream(key []byte) (io.ReadCloser, error) {
+	return r.part(key).GetStream(key)
+}
+
+// GetInto copies the first value associated with the given key into dst
+func (r *shardedReader) GetInto(key, dst []byte) ([]byte, int, error) {
+	return r.part(key).GetInto(key, dst)
+}
+
+// GetVersion returns the n-th value associated with the given key
+func (r *shardedReader) GetVersion(key []byte, n int) ([]byte, error) {
+	return r.part(key).GetVersion(key, n)
+}
+
+// Versions returns all values associated with the given key
+func (r *shardedReader) Versions(key []byte) ([][]byte, error) {
+	return r.part(key).Versions(key)
+}
+
+// CountKey returns the number of values associated with the given key
+func (r *shardedReader) CountKey(key []byte) (int, error) {
+	return r.part(key).CountKey(key)
+}
+
+// Has returns true if the given key exists, otherwise returns false.
+func (r *shardedReader) Has(key []byte) (bool, error) {
+	return r.part(key).Has(key)
+}
+
+// MultiHas tells which of the given keys exist
+func (r *shardedReader) MultiHas(keys [][]byte) ([]bool, error) {
+	exists := make([]bool, len(keys))
+
+	for i, key := range keys {
+		var err error
+
+		if exists[i], err = r.Has(key); err != nil {
+			return nil, err
+		}
+	}
+
+	return exists, nil
+}
+
+// Iterator returns a new Iterator object, which walks all parts one by one.
+func (r *shardedReader) Iterator() (Iterator, error) {
+	return r.concat(Reader.Iterator)
+}
+
+// KeyIterator returns a new Iterator object, which walks keys of all parts one by one.
+func (r *shardedReader) KeyIterator() (Iterator, error) {
+	return r.concat(Reader.KeyIterator)
+}
+
+// concat returns a new Iterator object, which walks iterators of all parts one by one
+func (r *shardedReader) concat(open func(Reader) (Iterator, error)) (Iterator, error) {
+	concat := &concatIterator{}
+
+	for j, part := range r.parts {
+		iterator, err := open(part)
+
+		if err == ErrEmptyCDB {
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		concat.iterators = append(concat.iterators, iterator)
+		concat.parts = append(concat.parts, j)
+	}
+
+	if len(concat.iterators) == 0 {
+		return nil, ErrEmptyCDB
+	}
+
+	return concat, nil
+}
+
+// ResumeIterator returns a new Iterator object, which points on the record of the given cursor
+// and walks the rest of its part and the following parts, see Reader.ResumeIterator
+func (r *shardedReader) ResumeIterator(cursor Cursor) (Iterator, error) {
+	return resumeParts(r.parts, cursor, func(_ int, iterator Iterator) (Iterator, error) {
+		return iterator, nil
+	})
+}
+
+// IteratorAt returns a new Iterator object that points on the first record associated with the given key.
+// The iterator walks the rest of the part of the key only.
+func (r *shardedReader) IteratorAt(key []byte) (Iterator, error) {
+	part := shardOf(r.hasher, key, len(r.parts))
+
+	iterator, err := r.parts[part].IteratorAt(key)
+	if err != nil {
+		return nil, err
+	}
+
+	// The part number makes cursors of the iterator resumable by the sharded reader
+	return &concatIterator{iterators: []Iterator{iterator}, parts: []int{part}}, nil
+}
+
+// Range returns a new Iterator object, which merges ranges of all parts in the key order.
+func (r *shardedReader) Range(start, end []byte) (Iterator, error) {
+	return r.Iterate(start, end, IncludeStart)
+}
+
+// Iterate returns a new Iterator object, which merges ranges of all parts in the key order, see Reader.Iterate
+func (r *shardedReader) Iterate(start, end []byte, bounds Bounds) (Iterator, error) {
+	return r.merge(func(part Reader) (Iterator, error) {
+		return part.Iterate(start, end, bounds)
+	}, ErrEntryNotFound, false)
+}
+
+// IteratePrefix returns a new Iterator object, which walks records of all parts with keys starting
+// with the given prefix, see Reader.IteratePrefix
+func (r *shardedReader) IteratePrefix(prefix []byte) (Iterator, error) {
+	return iteratePrefix(r, prefix)
+}
+
+// OrderedIterator returns a new Iterator object, which merges all parts in the key order.
+func (r *shardedReader) OrderedIterator() (Iterator, error) {
+	return r.mergeAll(Reader.OrderedIterator, false)
+}
+
+// ReverseIterator returns a new Iterator object, which merges all parts in the reverse key order.
+func (r *shardedReader) ReverseIterator() (Iterator, error) {
+	return r.mergeAll(Reader.ReverseIterator, true)
+}
+
+// mergeAll returns a new Iterator object, which merges iterators over all records of parts.
+// Returns ErrEmptyCDB if all parts are empty.
+func (r *shardedReader) mergeAll(open func(Reader) (Iterator, error), reverse bool) (Iterator, error) {
+	return r.merge(open, ErrEmptyCDB, reverse)
+}
+
+// merge returns a new Iterator object, which merges ordered iterators of all parts.
+// Parts, which open fails with the empty error for, are left out. Returns the empty error if no part has records.
+func (r *shardedReader) merge(open func(Reader) (Iterator, error), empty error, reverse bool) (Iterator, error) {
+	iterators := make([]Iterator, 0, len(r.parts))
+
+	for _, part := range r.parts {
+		iterator, err := open(part)
+
+		if err == empty {
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		iterators = append(iterators, iterator)
+	}
+
+	if len(iterators) == 0 {
+		return nil, empty
+	}
+
+	iterator, err := newMergeIterator(iterators, reverse)
+	if err != nil {
+		return nil, err
+	}
+
+	return iterator, nil
+}
+
+// Size returns the size of the dataset
+func (r *shardedReader) Size() int {
+	size := 0
+
+	for _, part := range r.parts {
+		size += part.Size()
+	}
+
+	return size
+}
+
+// Bucket returns a Reader, which routes lookups to the buckets of part readers.
+func (r *shardedReader) Bucket(name string) Reader {
+	parts := make([]Reader, len(r.parts))
+
+	for i, part := range r.parts {
+		parts[i] = part.Bucket(name)
+	}
+
+	return &shardedReader{
+		parts:  parts,
+		hasher: r.hasher,
+	}
+}
+
+// Clone returns a Reader over clones of all parts, see Reader.Clone
+func (r *shardedReader) Clone() Reader {
+	parts := make([]Reader, len(r.parts))
+
+	for i, part := range r.parts {
+		parts[i] = part.Clone()
+	}
+
+	return &shardedReader{
+		parts:  parts,
+		hasher: r.hasher,
+	}
+}
+
+// Warmup preloads all parts. Returns the first error.
+func (r *shardedReader) Warmup(data bool) error {
+	for _, part := range r.parts {
+		if err := part.Warmup(data); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Close closes all parts. Returns the first error.
+func (r *shardedReader) Close() error {
+	var firstErr error
+
+	for _, part := range r.parts {
+		if err := part.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return firstErr
+}
+
+// concatIterator implements Iterator interface, walks the given iterators one by one.
+// Every iterator points on a record.
+type concatIterator struct {
+	iterators []Iterator
+	// parts are the numbers of the parts of the iterators
+	parts []int
+	// all and allParts are the iterators and their parts before any of them is dropped
+	all      []Iterator
+	allParts []int
+	// walked is the number of records of the dropped iterators, see Iterator.Position
+	walked int
+}
+
+// Next moves the iterator to the next record. Returns true on success otherwise returns false.
+func (i *concatIterator) Next() (bool, error) {
+	ok, err := i.iterators[0].Next()
+
+	if err != nil || ok {
+		return ok, err
+	}
+
+	if len(i.iterators) == 1 {
+		return false, nil
+	}
+
+	i.mark()
+	i.walked += i.iterators[0].Position().Records
+	i.iterators, i.parts = i.iterators[1:], i.parts[1:]
+
+	return true, nil
+}
+
+// mark remembers the iterators to be restored by Reset, before the first of them is dropped
+func (i *concatIterator) mark() {
+	if i.all == nil {
+		i.all, i.allParts = i.iterators, i.parts
+	}
+}
+
+// Reset resets all iterators and moves back to the first one, see Iterator.Reset
+func (i *concatIterator) Reset() error {
+	if i.all != nil {
+		i.iterators, i.parts = i.all, i.allParts
+	}
+
+	i.walked = 0
+
+	for _, iterator := range i.iterators {
+		if err := iterator.Reset(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Seek moves the current or one of the following iterators to the first record associated
+// with the given key and drops the iterators before it. Records of the iterators, which are
+// already walked, can't be reached.
+func (i *concatIterator) Seek(key []byte) error {
+	for j, iterator := range i.iterators {
+		err := iterator.Seek(key)
+
+		if err == ErrEntryNotFound {
+			continue
+		}
+
+		if err != nil {
+			return err
+		}
+
+		i.mark()
+
+		for _, dropped := range i.iterators[:j] {
+			i.walked += dropped.Position().Records
+		}
+
+		i.iterators, i.parts = i.iterators[j:], i.parts[j:]
+
+		return nil
+	}
+
+	return ErrEntryNotFound
+}
+
+// Err returns the error, which stopped the current iterator
+func (i *concatIterator) Err() error {
+	return i.iterators[0].Err()
+}
+
+// Record returns copy of current record
+func (i *concatIterator) Record() Record {
+	return i.iterators[0].Record()
+}
+
+// HasNext tells if the iterator can be moved to the next record.
+func (i *concatIterator) HasNext() bool {
+	return len(i.iterators) > 1 || i.iterators[0].HasNext()
+}
+
+// Flags returns the raw flags of the current record.
+func (i *concatIterator) Flags() RecordFlags {
+	return i.iterators[0].Flags()
+}
+
+// Offset returns the offset of the current record inside its part.
+func (i *concatIterator) Offset() uint32 {
+	return i.iterators[0].Offset()
+}
+
+// Sequence returns the sequence number of the current record inside its part.
+func (i *concatIterator) Sequence() (uint32, error) {
+	return i.iterators[0].Sequence()
+}
+
+// Key returns key's []byte slice.
+func (i *concatIterator) Key() ([]byte, error) {
+	return i.iterators[0].Key()
+}
+
+// Value returns values's []byte slice.
+func (i *concatIterator) Value() ([]byte, error) {
+	return i.iterators[0].Value()
+}
+
+// mergeIterator implements Iterator interface, merges the given ordered iterators in the key order
+// or in the reverse key order. Records with equal keys are yielded in the order of iterators.
+type mergeIterator struct {
+	iterators []Iterator
+	keys      [][]byte
+	current   int
+	// reverse tells that the iterators are ordered by keys descending
+	reverse bool
+	// err is the error, which stopped the iterator
+	err error
+	// all are the given iterators, exhausted ones are dropped from iterators
+	all []Iterator
+}
+
+// newMergeIterator returns a new mergeIterator, which points on the least record.
+// Every given iterator must point on a record.
+func newMergeIterator(iterators []Iterator, reverse bool) (*mergeIterator, error) {
+	m := &mergeIterator{
+		all:     iterators,
+		reverse: reverse,
+	}
+
+	if err := m.start(); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// start points the iterator on the least record of all iterators
+func (i *mergeIterator) start() error {
+	i.iterators = append([]Iterator(nil), i.all...)
+	i.keys = make([][]byte, len(i.all))
+
+	for j, iterator := range i.iterators {
+		key, err := iterator.Key()
+		if err != nil {
+			return err
+		}
+
+		i.keys[j] = key
+	}
+
+	i.pick()
+
+	return nil
+}
+
+// Reset resets all iterators and points on the least record again, see Iterator.Reset
+func (i *mergeIterator) Reset() error {
+	for _, iterator := range i.all {
+		if err := iterator.Reset(); err != nil {
+			return err
+		}
+	}
+
+	i.err = nil
+
+	return i.start()
+}
+
+// pick chooses the iterator with the least key, or with the greatest key in the reverse order
+func (i *mergeIterator) pick() {
+	i.current = 0
+
+	for j := 1; j < len(i.keys); j++ {
+		c := bytes.Compare(i.keys[j], i.keys[i.current])
+
+		if (c < 0 && !i.reverse) || (c > 0 && i.reverse) {
+			i.current = j
+		}
+	}
+}
+
+// Next moves the iterator to the next record in the key order. Returns true on success otherwise returns false.
+func (i *mergeIterator) Next() (bool, error) {
+	if i.err != nil {
+		return false, i.err
+	}
+
+	ok, err := i.iterators[i.current].Next()
+	if err != nil {
+		i.err = err
+		return false, err
+	}
+
+	if ok {
+		if i.keys[i.current], err = i.iterators[i.current].Key(); err != nil {
+			i.err = err
+			return false, err
+		}
+	} else {
+		if len(i.iterators) == 1 {
+			return false, nil
+		}
+
+		i.iterators = append(i.iterators[:i.current], i.iterators[i.current+1:]...)
+		i.keys = append(i.keys[:i.current], i.keys[i.current+1:]...)
+	}
+
+	i.pick()
+
+	return true, nil
+}
+
+// Err returns the error, which stopped the iterator
+func (i *mergeIterator) Err() error {
+	return i.err
+}
+
+// Seek returns ErrMergedSeek, see Iterator.Seek
+func (i *mergeIterator) Seek(key []byte) error {
+	return ErrMergedSeek
+}
+
+// Record returns copy of current record
+func (i *mergeIterator) Record() Record {
+	return i.iterators[i.current].Record()
+}
+
+// HasNext tells if the iterator can be moved to the next record.
+func (i *mergeIterator) HasNext() bool {
+	return len(i.iterators) > 1 || i.iterators[0].HasNext()
+}
+
+// Flags returns the raw flags of the current record.
+func (i *mergeIterator) Flags() RecordFlags {
+	return i.iterators[i.current].Flags()
+}
+
+// Offset returns the offset of the current record inside its part.
+func (i *mergeIterator) Offset() uint32 {
+	return i.iterators[i.current].Offset()
+}
+
+// Sequence returns the sequence number of the current record inside its part.
+func (i *mergeIterator) Sequence() (uint32, error) {
+	return i.iterators[i.current].Sequence()
+}
+
+// Key returns key's []byte slice.
+func (i *mergeIterator) Key() ([]byte, error) {
+	return i.keys[i.current], nil
+}
+
+// Value returns values's []byte slice.
+func (i *mergeIterator) Value() ([]byte, error) {
+	return i.iterators[i.current].Value()
+}
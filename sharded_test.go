@@ -0,0 +1,108 @@
+package cdb
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+)
+
+func (suite *CDBTestSuite) createShardFiles(n int) []*os.File {
+	files := make([]*os.File, n)
+
+	for i := range files {
+		f, err := ioutil.TempFile("", "test_shard_*.cdb")
+		suite.Require().Nilf(err, "Can't open temporary file: %#v", err)
+		files[i] = f
+	}
+
+	return files
+}
+
+func (suite *CDBTestSuite) removeShardFiles(files []*os.File) {
+	for _, f := range files {
+		f.Close()
+		os.Remove(f.Name())
+	}
+}
+
+func (suite *CDBTestSuite) TestSharded() {
+	files := suite.createShardFiles(3)
+	defer suite.removeShardFiles(files)
+
+	writers := make([]Writer, len(files))
+	indexes := make([]*bytes.Buffer, len(files))
+	for i, f := range files {
+		indexes[i] = &bytes.Buffer{}
+		writer, err := suite.cdbHandle.GetWriterWithIndex(f, indexes[i])
+		suite.Require().Nil(err)
+		writers[i] = writer
+	}
+
+	writer, err := suite.cdbHandle.NewShardedWriter(writers)
+	suite.Require().Nil(err)
+
+	for _, rec := range suite.testRecords {
+		suite.Require().Nil(writer.Put(rec.key, rec.val))
+	}
+
+	suite.Require().Nil(writer.Close())
+
+	readers := make([]Reader, len(files))
+	for i, f := range files {
+		reader, err := suite.cdbHandle.GetReaderWithIndex(f, bytes.NewReader(indexes[i].Bytes()))
+		suite.Require().Nil(err)
+		suite.Less(reader.Size(), len(suite.testRecords), "records must be distributed over parts")
+		readers[i] = reader
+	}
+
+	reader, err := suite.cdbHandle.NewShardedReader(readers)
+	suite.Require().Nil(err)
+	suite.Equal(len(suite.testRecords), reader.Size())
+
+	for _, rec := range suite.testRecords {
+		value, err := reader.Get(rec.key)
+		suite.Nil(err)
+		suite.Equal(rec.val, value)
+	}
+
+	keys := make([][]byte, len(suite.testRecords))
+	for i, rec := range suite.testRecords {
+		keys[i] = rec.key
+	}
+
+	values, errs := reader.MultiGet(keys)
+	for i, rec := range suite.testRecords {
+		suite.Nil(errs[i])
+		suite.Equal(rec.val, values[i])
+	}
+
+	suite.Equal(len(suite.testRecords), suite.countIteratedRecords(reader))
+
+	// test records are generated in the key order
+	iterator, err := reader.Range(nil, nil)
+	suite.Require().Nil(err)
+
+	for i, rec := range suite.testRecords {
+		suite.EqualKeyValue(iterator, rec)
+
+		ok, err := iterator.Next()
+		suite.Nil(err)
+		suite.Equal(i != len(suite.testRecords)-1, ok)
+	}
+
+	suite.Require().Nil(iterator.Reset())
+	suite.EqualKeyValue(iterator, suite.testRecords[0])
+
+	_, err = reader.Range([]byte{0xff}, nil)
+	suite.Equal(ErrEntryNotFound, err, "no part has records in the range")
+
+	iterator, err = reader.Iterator()
+	suite.Require().Nil(err)
+	walked := suite.restOf(iterator)
+	suite.Len(walked, len(suite.testRecords))
+	suite.Require().Nil(iterator.Reset())
+	suite.Equal(walked, suite.restOf(iterator))
+
+	_, err = suite.cdbHandle.NewShardedReader(nil)
+	suite.Equal(ErrNoShards, err)
+}
@@ -0,0 +1,130 @@
+package cdb
+
+import (
+	"encoding/binary"
+	"hash"
+	"math/bits"
+)
+
+// sipHash implements hash.Hash32 by SipHash-2-4 keyed with k0, k1.
+// The 64 bits result is folded to 32 bits.
+type sipHash struct {
+	k0, k1         uint64
+	v0, v1, v2, v3 uint64
+	// tail keeps the bytes, which don't form a whole 8 bytes block yet
+	tail  [8]byte
+	ntail int
+	// length is the number of written bytes
+	length uint64
+}
+
+// newSipHasher returns a Hasher of SipHash-2-4 with the given key
+func newSipHasher(k0, k1 uint64) Hasher {
+	return func() hash.Hash32 {
+		h := &sipHash{k0: k0, k1: k1}
+		h.Reset()
+
+		return h
+	}
+}
+
+// Reset resets the hash to its initial state
+func (h *sipHash) Reset() {
+	h.v0 = h.k0 ^ 0x736f6d6570736575
+	h.v1 = h.k1 ^ 0x646f72616e646f6d
+	h.v2 = h.k0 ^ 0x6c7967656e657261
+	h.v3 = h.k1 ^ 0x7465646279746573
+	h.ntail = 0
+	h.length = 0
+}
+
+// Write adds the given data to the hash
+func (h *sipHash) Write(data []byte) (int, error) {
+	n := len(data)
+	h.length += uint64(n)
+
+	if h.ntail > 0 {
+		c := copy(h.tail[h.ntail:], data)
+		h.ntail += c
+		data = data[c:]
+
+		if h.ntail < 8 {
+			return n, nil
+		}
+
+		h.block(binary.LittleEndian.Uint64(h.tail[:]))
+		h.ntail = 0
+	}
+
+	for len(data) >= 8 {
+		h.block(binary.LittleEndian.Uint64(data))
+		data = data[8:]
+	}
+
+	h.ntail = copy(h.tail[:], data)
+
+	return n, nil
+}
+
+// block compresses the given 8 bytes block
+func (h *sipHash) block(m uint64) {
+	h.v3 ^= m
+	h.round()
+	h.round()
+	h.v0 ^= m
+}
+
+// round is a SipRound
+func (h *sipHash) round() {
+	h.v0 += h.v1
+	h.v1 = bits.RotateLeft64(h.v1, 13)
+	h.v1 ^= h.v0
+	h.v0 = bits.RotateLeft64(h.v0, 32)
+	h.v2 += h.v3
+	h.v3 = bits.RotateLeft64(h.v3, 16)
+	h.v3 ^= h.v2
+	h.v0 += h.v3
+	h.v3 = bits.RotateLeft64(h.v3, 21)
+	h.v3 ^= h.v0
+	h.v2 += h.v1
+	h.v1 = bits.RotateLeft64(h.v1, 17)
+	h.v1 ^= h.v2
+	h.v2 = bits.RotateLeft64(h.v2, 32)
+}
+
+// Sum64 returns the SipHash-2-4 of the written data, the state is not changed
+func (h *sipHash) Sum64() uint64 {
+	final := *h
+
+	var last [8]byte
+	copy(last[:], h.tail[:h.ntail])
+	last[7] = byte(h.length)
+
+	final.block(binary.LittleEndian.Uint64(last[:]))
+	final.v2 ^= 0xff
+
+	for i := 0; i < 4; i++ {
+		final.round()
+	}
+
+	return final.v0 ^ final.v1 ^ final.v2 ^ final.v3
+}
+
+// Sum32 returns the SipHash-2-4 of the written data folded to 32 bits
+func (h *sipHash) Sum32() uint32 {
+	s := h.Sum64()
+	return uint32(s) ^ uint32(s>>32)
+}
+
+func (h *sipHash) Sum(b []byte) []byte {
+	s := h.Sum32()
+	return append(b, byte(s>>24), byte(s>>16), byte(s>>8), byte(s))
+}
+
+func (h *sipHash) BlockSize() int {
+	return 8
+}
+
+func (h *sipHash) Size() int {
+	return size
+}
@@ -0,0 +1,105 @@
+package cdb
+
+import "encoding/binary"
+
+// SlotInfo describes a slot of a hash table, see Reader.WalkSlots
+type SlotInfo struct {
+	// Part is the number of the part of a sharded database or of the layer of a stacked one
+	Part int
+	// Table is the number of the hash table, Index is the number of the slot in the table
+	Table int
+	Index uint32
+	// Hash is the hash of the key of the record, HashHi is its upper half with the 64-bit hash
+	Hash, HashHi uint32
+	// Position is the position of the record, 0 for an empty slot
+	Position uint32
+	// Home is the number of the slot, which the probe sequence of the hash starts from
+	Home uint32
+	// Probes is the number of probes, which a lookup of the record takes: 1 if the record is in
+	// its home slot, more if it is displaced by a collision cluster
+	Probes int
+}
+
+// Occupied tells if the slot points on a record
+func (s *SlotInfo) Occupied() bool {
+	return s.Position != 0
+}
+
+// WalkSlots calls fn for every slot of every hash table in the file order, see Reader.WalkSlots
+func (r *readerImpl) WalkSlots(fn func(slot SlotInfo) error) error {
+	size := r.header.slotSize()
+
+	for i, ref := range r.refs {
+		if ref.length == 0 {
+			continue
+		}
+
+		buf := make([]byte, uint64(ref.length)*uint64(size))
+
+		if _, err := r.reader.ReadAt(buf, int64(ref.position)); err != nil {
+			return corrupted(err, int64(ref.position), i, "hash table is out of the database")
+		}
+
+		for k := uint32(0); k < ref.length; k++ {
+			entry := buf[k*size:]
+
+			info := SlotInfo{
+				Table:    i,
+				Index:    k,
+				Hash:     binary.LittleEndian.Uint32(entry),
+				Position: binary.LittleEndian.Uint32(entry[4:]),
+			}
+
+			if size > slotSize {
+				info.HashHi = binary.LittleEndian.Uint32(entry[slotSize:])
+			}
+
+			if info.Occupied() {
+				info.Home = r.header.startSlot(info.Hash, ref.length)
+				info.Probes = int((k+ref.length-info.Home)%ref.length) + 1
+			}
+
+			if err := fn(info); err != nil {
+				if err == Stop {
+					return nil
+				}
+
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// WalkSlots calls fn for every slot of every part, see Reader.WalkSlots
+func (r *shardedReader) WalkSlots(fn func(slot SlotInfo) error) error {
+	return walkPartSlots(r.parts, fn)
+}
+
+// WalkSlots calls fn for every slot of every layer from the newest one, see Reader.WalkSlots
+func (r *stackedReader) WalkSlots(fn func(slot SlotInfo) error) error {
+	return walkPartSlots(r.layers, fn)
+}
+
+// walkPartSlots calls fn for every slot of the given parts, the slots are marked with part numbers
+func walkPartSlots(parts []Reader, fn func(slot SlotInfo) error) error {
+	stopped := false
+
+	for j, part := range parts {
+		err := part.WalkSlots(func(slot SlotInfo) error {
+			slot.Part = j
+
+			err := fn(slot)
+			stopped = err == Stop
+
+			return err
+		})
+
+		if err != nil || stopped {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,52 @@
+package cdb
+
+func (suite *CDBTestSuite) TestWalkSlots() {
+	suite.fillTestCDB()
+	reader := suite.getCDBReader()
+
+	stats, err := reader.Stats()
+	suite.Require().Nil(err)
+
+	var (
+		slots, occupied int
+		probeLengths    []int
+	)
+
+	err = reader.WalkSlots(func(slot SlotInfo) error {
+		slots++
+
+		if !slot.Occupied() {
+			suite.Equal(0, slot.Probes)
+			return nil
+		}
+
+		occupied++
+
+		for len(probeLengths) < slot.Probes {
+			probeLengths = append(probeLengths, 0)
+		}
+		probeLengths[slot.Probes-1]++
+
+		key, _, err := reader.GetAt(slot.Position)
+		suite.Require().Nil(err)
+
+		hash, _ := reader.(*readerImpl).calcHash(key)
+		suite.Equal(hash, slot.Hash)
+		suite.Equal(hash%uint32(len(stats.Tables)), uint32(slot.Table))
+
+		return nil
+	})
+	suite.Nil(err)
+
+	suite.Equal(stats.Records, occupied)
+	suite.Equal(stats.ProbeLengths, probeLengths)
+	suite.Equal(int(float64(occupied)/stats.FillFactor+0.5), slots)
+
+	slots = 0
+	err = reader.WalkSlots(func(slot SlotInfo) error {
+		slots++
+		return Stop
+	})
+	suite.Nil(err)
+	suite.Equal(1, slots)
+}
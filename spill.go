@@ -0,0 +1,132 @@
+package cdb
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+)
+
+// Size of a spilled slot: the hash, the position and the upper half of the hash
+const spilledSlotSize = 12
+
+// SetSpill tells writers to keep at most maxSlots hash table slots in memory. Once there are
+// more of them, they are spilled to a temporary file in dir, grouped by tables, and merged
+// table by table at Close, so the memory of a writer doesn't grow with the number of records.
+// The empty dir stands for the default directory for temporary files, a non-positive maxSlots
+// disables spilling. Sorted indexes, versions and duplicate policies still keep every key in memory.
+// Like SetHash, it affects only new instances of Writer.
+func (cdb *CDB) SetSpill(dir string, maxSlots int) {
+	cdb.opts.spillDir = dir
+	cdb.opts.spillSlots = maxSlots
+}
+
+// slotSpill is the temporary file of spilled slots. Every spill appends a run, which holds
+// the slots of table 0, then of table 1 and so on, in the put order within a table.
+type slotSpill struct {
+	file *os.File
+	size int64
+	runs []spillRun
+}
+
+// spillRun refers to the slots of every table in a run
+type spillRun struct {
+	offsets []int64
+	counts  []uint32
+}
+
+// spillSlots appends the slots, which are kept in memory, to the spill file as a new run
+func (w *writerImpl) spillSlots() error {
+	if w.spill == nil {
+		file, err := ioutil.TempFile(w.spillDir, "cdb-slots-")
+		if err != nil {
+			return err
+		}
+
+		w.spill = &slotSpill{file: file}
+	}
+
+	run := spillRun{
+		offsets: make([]int64, len(w.tables)),
+		counts:  make([]uint32, len(w.tables)),
+	}
+
+	buffer := bufio.NewWriter(w.spill.file)
+	buf := make([]byte, spilledSlotSize)
+
+	for i, table := range w.tables {
+		run.offsets[i] = w.spill.size
+		run.counts[i] = uint32(len(table))
+
+		for _, slot := range table {
+			binary.LittleEndian.PutUint32(buf, slot.hash)
+			binary.LittleEndian.PutUint32(buf[4:], slot.position)
+			binary.LittleEndian.PutUint32(buf[8:], slot.hi)
+
+			if _, err := buffer.Write(buf); err != nil {
+				return err
+			}
+		}
+
+		w.spill.size += int64(len(table)) * spilledSlotSize
+		w.tables[i] = table[:0]
+	}
+
+	if err := buffer.Flush(); err != nil {
+		return err
+	}
+
+	w.spill.runs = append(w.spill.runs, run)
+	w.pending = 0
+
+	return nil
+}
+
+// table returns the slots of the i-th hash table in the put order, spilled slots of dropped
+// records are left out. Unless slots are spilled, it is the table kept in memory.
+func (w *writerImpl) table(i int) (hashTable, error) {
+	if w.spill == nil {
+		return w.tables[i], nil
+	}
+
+	var table hashTable
+
+	for _, run := range w.spill.runs {
+		buf := make([]byte, int(run.counts[i])*spilledSlotSize)
+
+		if _, err := w.spill.file.ReadAt(buf, run.offsets[i]); err != nil {
+			return nil, err
+		}
+
+		for ; len(buf) > 0; buf = buf[spilledSlotSize:] {
+			s := slot{
+				hash:     binary.LittleEndian.Uint32(buf),
+				position: binary.LittleEndian.Uint32(buf[4:]),
+				hi:       binary.LittleEndian.Uint32(buf[8:]),
+			}
+
+			if !w.droppedPositions[s.position] {
+				table = append(table, s)
+			}
+		}
+	}
+
+	return append(table, w.tables[i]...), nil
+}
+
+// removeSpill closes and removes the spill file, if there is one
+func (w *writerImpl) removeSpill() error {
+	if w.spill == nil {
+		return nil
+	}
+
+	file := w.spill.file
+	w.spill = nil
+
+	err := file.Close()
+	if removeErr := os.Remove(file.Name()); err == nil {
+		err = removeErr
+	}
+
+	return err
+}
@@ -0,0 +1,82 @@
+package cdb
+
+import (
+	"io/ioutil"
+	"os"
+	"strconv"
+)
+
+// buildSpillTestCDB writes records with duplicate keys, returns the database
+func (suite *CDBTestSuite) buildSpillTestCDB() []byte {
+	suite.resetTestCDB()
+
+	writer := suite.getCDBWriter()
+
+	for i := 0; i < 100; i++ {
+		suite.Require().Nil(writer.Put([]byte(strconv.Itoa(i%70)), []byte(strconv.Itoa(i))))
+	}
+
+	suite.Require().Nil(writer.Close())
+
+	data, err := ioutil.ReadFile(suite.cdbFile.Name())
+	suite.Require().Nil(err)
+
+	return data
+}
+
+func (suite *CDBTestSuite) TestSpill() {
+	dir, err := ioutil.TempDir("", "cdb-spill")
+	suite.Require().Nil(err)
+	defer os.RemoveAll(dir)
+
+	for _, configure := range []func(){
+		func() {},
+		func() { suite.cdbHandle.SetVersions(2) },
+		func() { suite.cdbHandle.SetDuplicates(DuplicatesKeepLast) },
+		func() { suite.Require().Nil(suite.cdbHandle.SetBloomFilter(10)) },
+	} {
+		suite.cdbHandle = New()
+		configure()
+		expected := suite.buildSpillTestCDB()
+
+		suite.cdbHandle.SetSpill(dir, 7)
+		suite.Equal(expected, suite.buildSpillTestCDB(), "spilled slots don't change the database")
+
+		files, err := ioutil.ReadDir(dir)
+		suite.Require().Nil(err)
+		suite.Empty(files, "the spill file is removed at Close")
+
+		value, err := suite.getCDBReader().Get([]byte("5"))
+		suite.Nil(err)
+		suite.NotEmpty(value)
+	}
+
+	suite.cdbHandle = New()
+	suite.cdbHandle.SetSpill(dir, 1)
+
+	writer := suite.getCDBWriter()
+	suite.Require().Nil(writer.Put([]byte("key"), []byte("value")))
+	suite.Nil(writer.Abort())
+
+	files, err := ioutil.ReadDir(dir)
+	suite.Require().Nil(err)
+	suite.Empty(files, "the spill file is removed at Abort")
+
+	// The spill file is removed by a failed Close, so the failure sticks
+	suite.resetTestCDB()
+	file := &failingFile{syncedFile: syncedFile{File: suite.cdbFile}}
+
+	writer, err = suite.cdbHandle.GetWriter(file)
+	suite.Require().Nil(err)
+
+	for i := 0; i < 10; i++ {
+		suite.Require().Nil(writer.Put([]byte(strconv.Itoa(i)), []byte("value")))
+	}
+
+	// Close fails on a write of the hash tables, after the slots are read from the spill file
+	suite.Require().Nil(writer.Flush())
+	file.broken = true
+	err = writer.Close()
+	suite.NotNil(err)
+	suite.Equal(err, writer.Close())
+}
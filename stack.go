@@ -0,0 +1,592 @@
+package cdb
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+// ErrStackedOffset tells that records of a stacked database can't be addressed by offsets,
+// offsets are meaningful for layer readers only
+var ErrStackedOffset = errors.New("cdb stacked reader can't get records by offsets, use layer readers")
+
+// stackedReader implements Reader interface, overlays layer readers
+type stackedReader struct {
+	// layers are ordered from the newest to the oldest
+	layers []Reader
+}
+
+// Stack returns a Reader, which overlays the given readers, ordered from the oldest to the newest,
+// e.g. a base snapshot followed by daily deltas. A lookup consults layers from the newest one and
+// stops at the first layer, which has the key. A record with RecordTombstone flag hides the key
+// of older layers and is not found itself. Iteration walks every layer and skips records,
+// which keys are shadowed by newer layers.
+func Stack(readers ...Reader) Reader {
+	layers := make([]Reader, len(readers))
+
+	for i, reader := range readers {
+		layers[len(readers)-1-i] = reader
+	}
+
+	return &stackedReader{layers}
+}
+
+// find returns an iterator, which points on the first record of the given key in the newest layer
+// having the key, and the number of the layer. Returns ErrEntryNotFound if the key is deleted.
+func (r *stackedReader) find(key []byte) (Iterator, int, error) {
+	for i, layer := range r.layers {
+		iterator, err := layer.IteratorAt(key)
+
+		if err == ErrEntryNotFound || err == ErrEmptyCDB {
+			continue
+		}
+
+		if err != nil {
+			return nil, 0, err
+		}
+
+		if iterator.Flags()&RecordTombstone != 0 {
+			return nil, 0, ErrEntryNotFound
+		}
+
+		return iterator, i, nil
+	}
+
+	return nil, 0, ErrEntryNotFound
+}
+
+// layer returns the newest layer, which has the given key
+func (r *stackedReader) layer(key []byte) (Reader, error) {
+	_, i, err := r.find(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.layers[i], nil
+}
+
+// Get returns the first value associated with the given key in the newest layer having the key
+func (r *stackedReader) Get(key []byte) ([]byte, error) {
+	iterator, _, err := r.find(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return iterator.Value()
+}
+
+// MultiGet returns the first values associated with the given keys, see Reader.MultiGet
+func (r *stackedReader) MultiGet(keys [][]byte) ([][]byte, []error) {
+	values := make([][]byte, len(keys))
+	errs := make([]error, len(keys))
+
+	for i, key := range keys {
+		values[i], errs[i] = r.Get(key)
+	}
+
+	return values, errs
+}
+
+// GetAt returns ErrStackedOffset, offsets belong to layers
+func (r *stackedReader) GetAt(offset uint32) ([]byte, []byte, error) {
+	return nil, nil, ErrStackedOffset
+}
+
+// GetSize returns the size of the first value associated with the given key
+func (r *stackedReader) GetSize(key []byte) (int, error) {
+	layer, err := r.layer(key)
+	if err != nil {
+		return 0, err
+	}
+
+	return layer.GetSize(key)
+}
+
+// GetRange returns the part of the first value associated with the given key
+func (r *stackedReader) GetRange(key []byte, offset, length int) ([]byte, error) {
+	layer, err := r.layer(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return layer.GetRange(key, offset, length)
+}
+
+// GetStream returns a reader of the first value associated with the given key
+func (r *stackedReader) GetStream(key []byte) (io.ReadCloser, error) {
+	layer, err := r.layer(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return layer.GetStream(key)
+}
+
+// GetInto reads the first value associated with the given key into dst
+func (r *stackedReader) GetInto(key, dst []byte) ([]byte, int, error) {
+	layer, err := r.layer(key)
+	if err != nil {
+		return dst, 0, err
+	}
+
+	return layer.GetInto(key, dst)
+}
+
+// GetVersion returns the n-th value associated with the given key in the newest layer having the key
+func (r *stackedReader) GetVersion(key []byte, n int) ([]byte, error) {
+	layer, err := r.layer(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return layer.GetVersion(key, n)
+}
+
+// Versions returns all values associated with the given key in the newest layer having the key
+func (r *stackedReader) Versions(key []byte) ([][]byte, error) {
+	layer, err := r.layer(key)
+	if err == ErrEntryNotFound {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return layer.Versions(key)
+}
+
+// CountKey returns the number of values associated with the given key in the newest layer having the key
+func (r *stackedReader) CountKey(key []byte) (int, error) {
+	layer, err := r.layer(key)
+	if err == ErrEntryNotFound {
+		return 0, nil
+	}
+
+	if err != nil {
+		return 0, err
+	}
+
+	return layer.CountKey(key)
+}
+
+// GetString returns the first value associated with the given string key
+func (r *stackedReader) GetString(key string) ([]byte, error) {
+	return r.Get(stringBytes(key))
+}
+
+// Has returns true if the given key exists and is not deleted, otherwise returns false.
+func (r *stackedReader) Has(key []byte) (bool, error) {
+	_, _, err := r.find(key)
+
+	if err == ErrEntryNotFound {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+// MultiHas tells which of the given keys exist
+func (r *stackedReader) MultiHas(keys [][]byte) ([]bool, error) {
+	exists := make([]bool, len(keys))
+
+	for i, key := range keys {
+		var err error
+
+		if exists[i], err = r.Has(key); err != nil {
+			return nil, err
+		}
+	}
+
+	return exists, nil
+}
+
+// HasString returns true if the given string key exists, otherwise returns false.
+func (r *stackedReader) HasString(key string) (bool, error) {
+	return r.Has(stringBytes(key))
+}
+
+// GetContext returns the first value associated with the given key, see Reader.GetContext
+func (r *stackedReader) GetContext(ctx context.Context, key []byte) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	layer, err := r.layer(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return layer.GetContext(ctx, key)
+}
+
+// HasContext returns true if the given key exists and is not deleted, see Reader.HasContext
+func (r *stackedReader) HasContext(ctx context.Context, key []byte) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	return r.Has(key)
+}
+
+// Iterator returns a new Iterator object, which walks all layers from the newest one
+// and skips shadowed records.
+func (r *stackedReader) Iterator() (Iterator, error) {
+	return r.overlay(Reader.Iterator)
+}
+
+// KeyIterator returns a new Iterator object, which walks keys of all layers, see Reader.KeyIterator
+func (r *stackedReader) KeyIterator() (Iterator, error) {
+	return r.overlay(Reader.KeyIterator)
+}
+
+// IteratorContext returns a new Iterator object, which walks all layers, see Reader.IteratorContext
+func (r *stackedReader) IteratorContext(ctx context.Context) (Iterator, error) {
+	return r.overlay(func(layer Reader) (Iterator, error) {
+		return layer.IteratorContext(ctx)
+	})
+}
+
+// overlay returns a new Iterator object, which walks visible records of all layers one by one
+func (r *stackedReader) overlay(open func(Reader) (Iterator, error)) (Iterator, error) {
+	concat := &concatIterator{}
+
+	for i, layer := range r.layers {
+		iterator, err := open(layer)
+
+		if err == ErrEmptyCDB {
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		visible, err := r.visible(i, iterator)
+		if err != nil {
+			return nil, err
+		}
+
+		if visible != nil {
+			concat.iterators = append(concat.iterators, visible)
+			concat.parts = append(concat.parts, i)
+		}
+	}
+
+	if len(concat.iterators) == 0 {
+		return nil, ErrEmptyCDB
+	}
+
+	return concat, nil
+}
+
+// visible returns a new Iterator object, which walks the visible records of the given iterator
+// of the i-th layer. Returns nil if there are no visible records.
+func (r *stackedReader) visible(i int, iterator Iterator) (Iterator, error) {
+	visible, err := newStackIterator(iterator, r.layers[i], r.layers[:i])
+	if visible == nil {
+		return nil, err
+	}
+
+	return visible, nil
+}
+
+// Segments returns iterators over visible records of segments of all layers, see Reader.Segments
+func (r *stackedReader) Segments(n int) ([]Iterator, error) {
+	return segmentParts(r.layers, n, r.visible)
+}
+
+// ResumeIterator returns a new Iterator object, which points on the record of the given cursor
+// and walks the rest of its layer and the older layers, see Reader.ResumeIterator
+func (r *stackedReader) ResumeIterator(cursor Cursor) (Iterator, error) {
+	return resumeParts(r.layers, cursor, r.visible)
+}
+
+// IteratorAt returns a new Iterator object that points on the first record associated with the given key
+// in the newest layer having the key. The iterator walks the rest of that layer only.
+func (r *stackedReader) IteratorAt(key []byte) (Iterator, error) {
+	iterator, i, err := r.find(key)
+	if err != nil {
+		return nil, err
+	}
+
+	visible := &stackIterator{Iterator: iterator, layer: r.layers[i], newer: r.layers[:i]}
+
+	// The layer number makes cursors of the iterator resumable by the stacked reader
+	return &concatIterator{iterators: []Iterator{visible}, parts: []int{i}}, nil
+}
+
+// Range returns a new Iterator object, which merges visible records of ranges of all layers in the key order.
+func (r *stackedReader) Range(start, end []byte) (Iterator, error) {
+	return r.Iterate(start, end, IncludeStart)
+}
+
+// Iterate returns a new Iterator object, which merges visible records of ranges of all layers in the key order,
+// see Reader.Iterate
+func (r *stackedReader) Iterate(start, end []byte, bounds Bounds) (Iterator, error) {
+	return r.merge(func(layer Reader) (Iterator, error) {
+		return layer.Iterate(start, end, bounds)
+	}, ErrEntryNotFound, false)
+}
+
+// IteratePrefix returns a new Iterator object, which walks visible records of all layers with keys starting
+// with the given prefix, see Reader.IteratePrefix
+func (r *stackedReader) IteratePrefix(prefix []byte) (Iterator, error) {
+	return iteratePrefix(r, prefix)
+}
+
+// OrderedIterator returns a new Iterator object, which merges visible records of all layers in the key order.
+func (r *stackedReader) OrderedIterator() (Iterator, error) {
+	return r.mergeAll(Reader.OrderedIterator, false)
+}
+
+// ReverseIterator returns a new Iterator object, which merges visible records of all layers
+// in the reverse key order.
+func (r *stackedReader) ReverseIterator() (Iterator, error) {
+	return r.mergeAll(Reader.ReverseIterator, true)
+}
+
+// mergeAll returns a new Iterator object, which merges visible records of iterators over all records
+// of layers. Returns ErrEmptyCDB if no record is visible.
+func (r *stackedReader) mergeAll(open func(Reader) (Iterator, error), reverse bool) (Iterator, error) {
+	return r.merge(open, ErrEmptyCDB, reverse)
+}
+
+// merge returns a new Iterator object, which merges visible records of ordered iterators of all layers.
+// Layers, which open fails with the empty error for, are left out. Returns the empty error if no record is visible.
+func (r *stackedReader) merge(open func(Reader) (Iterator, error), empty error, reverse bool) (Iterator, error) {
+	iterators := make([]Iterator, 0, len(r.layers))
+
+	for i, layer := range r.layers {
+		iterator, err := open(layer)
+
+		if err == empty {
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		visible, err := newStackIterator(iterator, layer, r.layers[:i])
+		if err != nil {
+			return nil, err
+		}
+
+		if visible != nil {
+			iterators = append(iterators, visible)
+		}
+	}
+
+	if len(iterators) == 0 {
+		return nil, empty
+	}
+
+	iterator, err := newMergeIterator(iterators, reverse)
+	if err != nil {
+		return nil, err
+	}
+
+	return iterator, nil
+}
+
+// Size returns the total size of all layers, which is an upper bound of the size of the dataset:
+// shadowed records and tombstones are counted too.
+func (r *stackedReader) Size() int {
+	size := 0
+
+	for _, layer := range r.layers {
+		size += layer.Size()
+	}
+
+	return size
+}
+
+// Bucket returns a Reader, which overlays the buckets of layer readers.
+func (r *stackedReader) Bucket(name string) Reader {
+	layers := make([]Reader, len(r.layers))
+
+	for i, layer := range r.layers {
+		layers[i] = layer.Bucket(name)
+	}
+
+	return &stackedReader{layers}
+}
+
+// Clone returns a Reader over clones of all layers, see Reader.Clone
+func (r *stackedReader) Clone() Reader {
+	layers := make([]Reader, len(r.layers))
+
+	for i, layer := range r.layers {
+		layers[i] = layer.Clone()
+	}
+
+	return &stackedReader{layers}
+}
+
+// Warmup preloads all layers. Returns the first error.
+func (r *stackedReader) Warmup(data bool) error {
+	for _, layer := range r.layers {
+		if err := layer.Warmup(data); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Stats returns the statistics of all layers combined
+func (r *stackedReader) Stats() (Stats, error) {
+	return mergeStats(r.layers)
+}
+
+// Close closes all layers. Returns the first error.
+func (r *stackedReader) Close() error {
+	var firstErr error
+
+	for _, layer := range r.layers {
+		if err := layer.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return firstErr
+}
+
+// stackIterator implements Iterator interface, walks the records of a layer,
+// which are neither tombstones nor shadowed by the given newer layers.
+// HasNext may report true, when only invisible records are left.
+type stackIterator struct {
+	Iterator
+	layer Reader
+	newer []Reader
+	// err is the error, which stopped the iterator
+	err error
+	// records is the number of moves of the iterator, see Iterator.Position
+	records int
+}
+
+// newStackIterator returns a new stackIterator, which points on the first visible record
+// of the given iterator. Returns nil if there are no visible records.
+func newStackIterator(iterator Iterator, layer Reader, newer []Reader) (*stackIterator, error) {
+	i := &stackIterator{Iterator: iterator, layer: layer, newer: newer}
+
+	ok, err := i.settle()
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	return i, nil
+}
+
+// Next moves the iterator to the next visible record. Returns true on success otherwise returns false.
+func (i *stackIterator) Next() (bool, error) {
+	if i.err != nil {
+		return false, i.err
+	}
+
+	ok, err := i.Iterator.Next()
+	if err != nil || !ok {
+		return false, err
+	}
+
+	if ok, err = i.settle(); err != nil {
+		i.err = err
+	}
+
+	if ok {
+		i.records++
+	}
+
+	return ok, err
+}
+
+// Err returns the error, which stopped the iterator
+func (i *stackIterator) Err() error {
+	if i.err != nil {
+		return i.err
+	}
+
+	return i.Iterator.Err()
+}
+
+// Seek moves the iterator to the first record associated with the given key,
+// if the key is visible in the layer of the iterator, see Iterator.Seek
+func (i *stackIterator) Seek(key []byte) error {
+	for _, layer := range i.newer {
+		exists, err := layer.Has(key)
+		if err != nil {
+			return err
+		}
+
+		if exists {
+			return ErrEntryNotFound
+		}
+	}
+
+	found, err := i.layer.IteratorAt(key)
+	if err != nil {
+		return err
+	}
+
+	if found.Flags()&RecordTombstone != 0 {
+		return ErrEntryNotFound
+	}
+
+	if err := i.Iterator.Seek(key); err != nil {
+		return err
+	}
+
+	i.records++
+
+	return nil
+}
+
+// Reset moves the iterator back to the record it pointed on when it was created, see Iterator.Reset
+func (i *stackIterator) Reset() error {
+	i.err, i.records = nil, 0
+
+	if err := i.Iterator.Reset(); err != nil {
+		return err
+	}
+
+	_, err := i.settle()
+
+	return err
+}
+
+// settle moves the iterator to the first visible record starting from the current one
+func (i *stackIterator) settle() (bool, error) {
+	for {
+		visible, err := i.visible()
+		if err != nil || visible {
+			return visible, err
+		}
+
+		ok, err := i.Iterator.Next()
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+}
+
+// visible tells if the current record is neither a tombstone nor shadowed by a newer layer
+func (i *stackIterator) visible() (bool, error) {
+	if i.Flags()&RecordTombstone != 0 {
+		return false, nil
+	}
+
+	key, err := i.Key()
+	if err != nil {
+		return false, err
+	}
+
+	for _, layer := range i.newer {
+		// Tombstones of newer layers shadow the key as well
+		if exists, err := layer.Has(key); err != nil || exists {
+			return false, err
+		}
+	}
+
+	return true, nil
+}
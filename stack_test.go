@@ -0,0 +1,119 @@
+package cdb
+
+import (
+	"bytes"
+)
+
+func (suite *CDBTestSuite) TestStack() {
+	suite.cdbHandle.SetRecordFlags(true)
+
+	files := suite.createShardFiles(2)
+	defer suite.removeShardFiles(files)
+
+	layers := []map[string]string{
+		{"a": "base-a", "b": "base-b", "c": "base-c", "d": "base-d"},
+		{"b": "delta-b", "c": "", "e": "delta-e"},
+	}
+
+	readers := make([]Reader, len(files))
+	for i, f := range files {
+		index := &bytes.Buffer{}
+		writer, err := suite.cdbHandle.GetWriterWithIndex(f, index)
+		suite.Require().Nil(err)
+
+		for key, value := range layers[i] {
+			meta := recordMeta{}
+			if value == "" {
+				meta.flags = RecordTombstone
+			}
+			suite.Require().Nil(writer.(*writerImpl).put([]byte(key), []byte(value), meta))
+		}
+		suite.Require().Nil(writer.Close())
+
+		readers[i], err = suite.cdbHandle.GetReaderWithIndex(f, bytes.NewReader(index.Bytes()))
+		suite.Require().Nil(err)
+	}
+
+	reader := Stack(readers...)
+	expected := map[string]string{"a": "base-a", "b": "delta-b", "d": "base-d", "e": "delta-e"}
+
+	for key, value := range expected {
+		got, err := reader.Get([]byte(key))
+		suite.Nil(err)
+		suite.Equal(value, string(got))
+
+		exists, err := reader.Has([]byte(key))
+		suite.Nil(err)
+		suite.True(exists)
+	}
+
+	_, err := reader.Get([]byte("c"))
+	suite.Equal(ErrEntryNotFound, err)
+
+	exists, err := reader.Has([]byte("c"))
+	suite.Nil(err)
+	suite.False(exists)
+
+	iterated := map[string]string{}
+	iterator, err := reader.Iterator()
+	suite.Require().Nil(err)
+
+	for ok := true; ok; ok, err = iterator.Next() {
+		suite.Require().Nil(err)
+
+		key, err := iterator.Key()
+		suite.Require().Nil(err)
+		value, err := iterator.Value()
+		suite.Require().Nil(err)
+
+		suite.NotContains(iterated, string(key), "shadowed record is iterated")
+		iterated[string(key)] = string(value)
+	}
+	suite.Nil(err)
+	suite.Equal(expected, iterated)
+
+	var keys []string
+	iterator, err = reader.Range(nil, nil)
+	suite.Require().Nil(err)
+
+	for ok := true; ok; ok, err = iterator.Next() {
+		key, err := iterator.Key()
+		suite.Require().Nil(err)
+		keys = append(keys, string(key))
+	}
+	suite.Nil(err)
+	suite.Equal([]string{"a", "b", "d", "e"}, keys)
+	suite.Equal(ErrMergedSeek, iterator.Seek([]byte("a")))
+
+	_, err = reader.Range([]byte("x"), nil)
+	suite.Equal(ErrEntryNotFound, err, "no layer has records in the range")
+
+	keys = nil
+	iterator, err = reader.ReverseIterator()
+	suite.Require().Nil(err)
+
+	for ok := true; ok; ok, err = iterator.Next() {
+		key, err := iterator.Key()
+		suite.Require().Nil(err)
+		keys = append(keys, string(key))
+	}
+	suite.Nil(err)
+	suite.Equal([]string{"e", "d", "b", "a"}, keys)
+
+	iterator, err = reader.Iterator()
+	suite.Require().Nil(err)
+
+	// Layers are walked from the newest one, a seek drops the layers before the found record
+	for _, key := range []string{"e", "b", "a", "d"} {
+		suite.Require().Nil(iterator.Seek([]byte(key)))
+		got, err := iterator.Value()
+		suite.Nil(err)
+		suite.Equal(expected[key], string(got))
+	}
+
+	suite.Equal(ErrEntryNotFound, iterator.Seek([]byte("c")))
+	suite.Equal(ErrEntryNotFound, iterator.Seek([]byte("e")))
+
+	_, _, err = reader.GetAt(0)
+	suite.Equal(ErrStackedOffset, err)
+}
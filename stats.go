@@ -0,0 +1,187 @@
+package cdb
+
+import (
+	"encoding/binary"
+	"sync"
+	"sync/atomic"
+)
+
+// Stats describes the hash tables of a database, so that the hash quality for a key set can be evaluated,
+// and counts lookups of the reader
+type Stats struct {
+	// Records is the number of records of the database, all buckets and versions included
+	Records int
+	// Tables describes every hash table
+	Tables []TableStats
+	// FillFactor is the ratio of records to slots
+	FillFactor float64
+	// ProbeLengths is the probe length distribution: ProbeLengths[i] records are found by i+1 probes
+	ProbeLengths []int
+	// Lookups is the number of lookups since the reader is opened, Hits and Misses split them by the result
+	Lookups, Hits, Misses int64
+	// BytesRead is the number of bytes read by lookups: slots, records and values
+	BytesRead int64
+	// MaxProbes is the longest probe sequence of a lookup
+	MaxProbes int64
+}
+
+// readerCounters counts lookups of a reader, see Stats. Fields are updated atomically.
+type readerCounters struct {
+	lookups, hits, bytesRead, maxProbes int64
+}
+
+// observe counts the given lookup
+func (c *readerCounters) observe(stats *LookupStats) {
+	atomic.AddInt64(&c.lookups, 1)
+	atomic.AddInt64(&c.bytesRead, int64(stats.BytesRead))
+
+	if stats.Found {
+		atomic.AddInt64(&c.hits, 1)
+	}
+
+	probes := int64(stats.Probes)
+
+	for {
+		max := atomic.LoadInt64(&c.maxProbes)
+		if probes <= max || atomic.CompareAndSwapInt64(&c.maxProbes, max, probes) {
+			return
+		}
+	}
+}
+
+// snapshot copies the counters into the given stats
+func (c *readerCounters) snapshot(stats *Stats) {
+	stats.Lookups = atomic.LoadInt64(&c.lookups)
+	stats.Hits = atomic.LoadInt64(&c.hits)
+	stats.Misses = stats.Lookups - stats.Hits
+	stats.BytesRead = atomic.LoadInt64(&c.bytesRead)
+	stats.MaxProbes = atomic.LoadInt64(&c.maxProbes)
+}
+
+// TableStats describes a hash table
+type TableStats struct {
+	Slots, Records int
+}
+
+// lazyStats computes Stats of a reader once
+type lazyStats struct {
+	once  sync.Once
+	stats Stats
+	err   error
+}
+
+// Stats returns the statistics of the hash tables and the lookup counters. The statistics of the hash tables
+// are computed by the first call, which reads all hash tables. Both are shared by bucket readers and clones.
+func (r *readerImpl) Stats() (Stats, error) {
+	r.lazyStats.once.Do(func() {
+		r.lazyStats.stats, r.lazyStats.err = r.computeStats()
+	})
+
+	if r.lazyStats.err != nil {
+		return Stats{}, r.lazyStats.err
+	}
+
+	stats := r.lazyStats.stats
+	r.counters.snapshot(&stats)
+
+	return stats, nil
+}
+
+// computeStats reads all hash tables and computes Stats
+func (r *readerImpl) computeStats() (Stats, error) {
+	stats := Stats{Tables: make([]TableStats, len(r.refs))}
+	slots := 0
+
+	for i, ref := range r.refs {
+		table := &stats.Tables[i]
+		table.Slots = int(ref.length)
+		slots += table.Slots
+
+		if ref.length == 0 {
+			continue
+		}
+
+		size := r.header.slotSize()
+		buf := make([]byte, uint64(ref.length)*uint64(size))
+
+		if _, err := r.reader.ReadAt(buf, int64(ref.position)); err != nil {
+			return Stats{}, corrupted(err, int64(ref.position), i, "hash table is out of the database")
+		}
+
+		for k := uint32(0); k < ref.length; k++ {
+			entry := buf[k*size:]
+
+			if binary.LittleEndian.Uint32(entry[4:]) == 0 {
+				continue
+			}
+
+			table.Records++
+
+			home := r.header.startSlot(binary.LittleEndian.Uint32(entry), ref.length)
+			probes := int((k+ref.length-home)%ref.length) + 1
+
+			for len(stats.ProbeLengths) < probes {
+				stats.ProbeLengths = append(stats.ProbeLengths, 0)
+			}
+
+			stats.ProbeLengths[probes-1]++
+		}
+
+		stats.Records += table.Records
+	}
+
+	if slots != 0 {
+		stats.FillFactor = float64(stats.Records) / float64(slots)
+	}
+
+	return stats, nil
+}
+
+// Stats returns the statistics of hash tables of all parts, tables go in the order of parts
+func (r *shardedReader) Stats() (Stats, error) {
+	return mergeStats(r.parts)
+}
+
+// mergeStats returns the statistics of the given readers combined, lookup counters are summed
+func mergeStats(readers []Reader) (Stats, error) {
+	var (
+		total Stats
+		slots int
+	)
+
+	for _, reader := range readers {
+		stats, err := reader.Stats()
+		if err != nil {
+			return Stats{}, err
+		}
+
+		total.Records += stats.Records
+		total.Tables = append(total.Tables, stats.Tables...)
+		total.Lookups += stats.Lookups
+		total.Hits += stats.Hits
+		total.Misses += stats.Misses
+		total.BytesRead += stats.BytesRead
+
+		if stats.MaxProbes > total.MaxProbes {
+			total.MaxProbes = stats.MaxProbes
+		}
+
+		for _, table := range stats.Tables {
+			slots += table.Slots
+		}
+
+		for len(total.ProbeLengths) < len(stats.ProbeLengths) {
+			total.ProbeLengths = append(total.ProbeLengths, 0)
+		}
+
+		for i, n := range stats.ProbeLengths {
+			total.ProbeLengths[i] += n
+		}
+	}
+
+	if slots != 0 {
+		total.FillFactor = float64(total.Records) / float64(slots)
+	}
+
+	return total, nil
+}
@@ -0,0 +1,63 @@
+package cdb
+
+func (suite *CDBTestSuite) TestStats() {
+	suite.fillTestCDB()
+
+	reader := suite.getCDBReader()
+
+	stats, err := reader.Stats()
+	suite.Require().Nil(err)
+	suite.Equal(len(suite.testRecords), stats.Records)
+	suite.Len(stats.Tables, tableNum)
+
+	records, slots := 0, 0
+	for _, table := range stats.Tables {
+		records += table.Records
+		slots += table.Slots
+	}
+
+	suite.Equal(stats.Records, records)
+	suite.Equal(2*stats.Records, slots)
+	suite.InDelta(0.5, stats.FillFactor, 1e-9)
+
+	probed := 0
+	for _, n := range stats.ProbeLengths {
+		probed += n
+	}
+
+	suite.Equal(stats.Records, probed)
+	suite.True(stats.ProbeLengths[0] > 0)
+
+	bucketStats, err := reader.Bucket("").Stats()
+	suite.Nil(err)
+	suite.Equal(stats, bucketStats)
+}
+
+func (suite *CDBTestSuite) TestStatsCounters() {
+	suite.fillTestCDB()
+	reader := suite.getCDBReader()
+
+	for _, rec := range suite.testRecords {
+		_, err := reader.Get(rec.key)
+		suite.Require().Nil(err)
+	}
+
+	_, err := reader.Get([]byte("missing"))
+	suite.Equal(ErrEntryNotFound, err)
+
+	stats, err := reader.Stats()
+	suite.Require().Nil(err)
+	suite.Equal(int64(len(suite.testRecords)+1), stats.Lookups)
+	suite.Equal(int64(len(suite.testRecords)), stats.Hits)
+	suite.Equal(int64(1), stats.Misses)
+	suite.True(stats.BytesRead > 0)
+	suite.True(stats.MaxProbes >= 1)
+
+	// Clones share the counters of their parent
+	_, err = reader.Clone().Has(suite.testRecords[0].key)
+	suite.Nil(err)
+
+	stats, err = reader.Stats()
+	suite.Require().Nil(err)
+	suite.Equal(int64(len(suite.testRecords)+2), stats.Lookups)
+}
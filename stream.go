@@ -0,0 +1,48 @@
+package cdb
+
+import "context"
+
+// Stream walks the records of the reader in the file order in a new goroutine and sends them
+// to the returned record channel, which has the given capacity, so a slow consumer pauses the walk.
+// Both channels are closed, when the walk stops. The error channel receives at most one error:
+// the error of a read or the error of the context, once it is done. Records are read lazily,
+// see Iterator.Record, their reads fail after the context is done.
+func Stream(ctx context.Context, reader Reader, capacity int) (<-chan Record, <-chan error) {
+	records := make(chan Record, capacity)
+	errs := make(chan error, 1)
+
+	go func() {
+		defer close(errs)
+		defer close(records)
+
+		if err := stream(ctx, reader, records); err != nil {
+			errs <- err
+		}
+	}()
+
+	return records, errs
+}
+
+// stream sends the records of the reader to the given channel until the context is done
+func stream(ctx context.Context, reader Reader, records chan<- Record) error {
+	iterator, err := reader.IteratorContext(ctx)
+
+	if err == ErrEmptyCDB {
+		return nil
+	}
+
+	for ok := err == nil; ok; ok, err = iterator.Next() {
+		select {
+		case records <- iterator.Record():
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	// Reads fail once the context is done, the error of the context tells why
+	if err != nil && ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	return err
+}
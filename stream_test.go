@@ -0,0 +1,51 @@
+package cdb
+
+import (
+	"context"
+	"io/ioutil"
+)
+
+func (suite *CDBTestSuite) TestStream() {
+	suite.fillTestCDB()
+
+	records, errs := Stream(context.Background(), suite.getCDBReader(), 1)
+
+	i := 0
+	for record := range records {
+		suite.EqualRecords(record, suite.testRecords[i])
+		i++
+	}
+
+	suite.Equal(len(suite.testRecords), i)
+	suite.Nil(<-errs)
+}
+
+func (suite *CDBTestSuite) TestStreamCancel() {
+	suite.fillTestCDB()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	records, errs := Stream(ctx, suite.getCDBReader(), 0)
+
+	record := <-records
+	cancel()
+
+	// The record is read with the cancelled context
+	valueReader, _ := record.Value()
+	_, err := ioutil.ReadAll(valueReader)
+	suite.Equal(context.Canceled, err)
+
+	for range records {
+	}
+
+	suite.Equal(context.Canceled, <-errs)
+}
+
+func (suite *CDBTestSuite) TestStreamEmpty() {
+	suite.writeEmptyCDB()
+
+	records, errs := Stream(context.Background(), suite.getCDBReader(), 0)
+
+	_, ok := <-records
+	suite.False(ok)
+	suite.Nil(<-errs)
+}
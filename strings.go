@@ -0,0 +1,55 @@
+package cdb
+
+import (
+	"reflect"
+	"unsafe"
+)
+
+// stringBytes returns the bytes of the given string without a copy. The result must not be modified,
+// it is passed only to functions, which neither change nor keep their arguments.
+func stringBytes(s string) []byte {
+	var b []byte
+
+	stringHeader := (*reflect.StringHeader)(unsafe.Pointer(&s))
+	sliceHeader := (*reflect.SliceHeader)(unsafe.Pointer(&b))
+	sliceHeader.Data = stringHeader.Data
+	sliceHeader.Len = stringHeader.Len
+	sliceHeader.Cap = stringHeader.Len
+
+	return b
+}
+
+// PutString saves a new associated pair <key, value> with a string key into databases.
+func (w *writerImpl) PutString(key string, value []byte) error {
+	return w.Put(stringBytes(key), value)
+}
+
+// PutString saves a new associated pair <key, value> with a string key into the bucket.
+func (w *bucketWriter) PutString(key string, value []byte) error {
+	return w.Put(stringBytes(key), value)
+}
+
+// PutString saves a new associated pair <key, value> with a string key into the part of the key.
+func (w *shardedWriter) PutString(key string, value []byte) error {
+	return w.Put(stringBytes(key), value)
+}
+
+// GetString returns the first value associated with the given string key
+func (r *readerImpl) GetString(key string) ([]byte, error) {
+	return r.Get(stringBytes(key))
+}
+
+// HasString returns true if the given string key exists, otherwise returns false.
+func (r *readerImpl) HasString(key string) (bool, error) {
+	return r.Has(stringBytes(key))
+}
+
+// GetString returns the first value associated with the given string key
+func (r *shardedReader) GetString(key string) ([]byte, error) {
+	return r.Get(stringBytes(key))
+}
+
+// HasString returns true if the given string key exists, otherwise returns false.
+func (r *shardedReader) HasString(key string) (bool, error) {
+	return r.Has(stringBytes(key))
+}
@@ -0,0 +1,63 @@
+//go:build linux && (amd64 || arm64)
+// +build linux
+// +build amd64 arm64
+
+package cdb
+
+import (
+	"os"
+	"strconv"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	// oTmpFile is O_TMPFILE, which includes O_DIRECTORY
+	oTmpFile = 0x400000 | syscall.O_DIRECTORY
+	// atFDCWD and atSymlinkFollow are arguments of linkat
+	atFDCWD         = -0x64
+	atSymlinkFollow = 0x400
+)
+
+// procSelfFD is the directory of links to open files, linkUnnamed links files by them
+var procSelfFD = "/proc/self/fd/"
+
+// openUnnamed opens an unnamed file in the given directory, which disappears, unless it is linked
+// by linkUnnamed. Returns false if the filesystem doesn't support unnamed files or /proc isn't
+// mounted, so the file couldn't be linked.
+func openUnnamed(dir string) (*os.File, bool) {
+	fd, err := syscall.Open(dir, oTmpFile|syscall.O_RDWR|syscall.O_CLOEXEC, 0600)
+	if err != nil {
+		return nil, false
+	}
+
+	if _, err := os.Stat(procSelfFD + strconv.Itoa(fd)); err != nil {
+		syscall.Close(fd)
+		return nil, false
+	}
+
+	return os.NewFile(uintptr(fd), dir), true
+}
+
+// linkUnnamed gives the unnamed file the given path, which must not exist
+func linkUnnamed(f *os.File, path string) error {
+	from, err := syscall.BytePtrFromString(procSelfFD + strconv.Itoa(int(f.Fd())))
+	if err != nil {
+		return err
+	}
+
+	to, err := syscall.BytePtrFromString(path)
+	if err != nil {
+		return err
+	}
+
+	dirfd := atFDCWD
+
+	_, _, errno := syscall.Syscall6(syscall.SYS_LINKAT, uintptr(dirfd), uintptr(unsafe.Pointer(from)),
+		uintptr(dirfd), uintptr(unsafe.Pointer(to)), atSymlinkFollow, 0)
+	if errno != 0 {
+		return &os.LinkError{Op: "linkat", Old: f.Name(), New: path, Err: errno}
+	}
+
+	return nil
+}
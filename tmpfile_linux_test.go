@@ -0,0 +1,41 @@
+//go:build linux && (amd64 || arm64)
+// +build linux
+// +build amd64 arm64
+
+package cdb
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+func (suite *CDBTestSuite) TestWriteFileWithoutProc() {
+	dir, err := ioutil.TempDir("", "test_cdb")
+	suite.Require().Nil(err)
+	defer os.RemoveAll(dir)
+
+	defer func(path string) { procSelfFD = path }(procSelfFD)
+	procSelfFD = filepath.Join(dir, "missing") + "/"
+
+	_, ok := openUnnamed(dir)
+	suite.False(ok, "unnamed files can't be linked without /proc")
+
+	path := filepath.Join(dir, "test.cdb")
+
+	suite.Require().Nil(WriteFile(path, func(writer Writer) error {
+		return writer.Put([]byte("key"), []byte("value"))
+	}))
+
+	reader, err := Open(path)
+	suite.Require().Nil(err)
+
+	value, err := reader.Get([]byte("key"))
+	suite.Nil(err)
+	suite.Equal([]byte("value"), value)
+	suite.Nil(reader.Close())
+
+	files, err := ioutil.ReadDir(dir)
+	suite.Require().Nil(err)
+	suite.Len(files, 1, "Temporary files must be removed")
+}
@@ -0,0 +1,16 @@
+//go:build !linux || !(amd64 || arm64)
+// +build !linux !amd64,!arm64
+
+package cdb
+
+import "os"
+
+// openUnnamed returns false, there are no unnamed files on the platform
+func openUnnamed(dir string) (*os.File, bool) {
+	return nil, false
+}
+
+// linkUnnamed is never called, there are no unnamed files on the platform
+func linkUnnamed(f *os.File, path string) error {
+	return os.ErrInvalid
+}
@@ -0,0 +1,121 @@
+package cdb
+
+import "context"
+
+// Tracer starts spans around reader operations, so that lookups show up in distributed traces,
+// see CDB.SetTracer. An OpenTelemetry adapter wraps a trace.Tracer:
+//
+//	type otelTracer struct {
+//		tracer trace.Tracer
+//	}
+//
+//	func (t otelTracer) StartSpan(ctx context.Context, name string) (context.Context, cdb.Span) {
+//		ctx, span := t.tracer.Start(ctx, name)
+//		return ctx, otelSpan{span}
+//	}
+//
+//	type otelSpan struct {
+//		trace.Span
+//	}
+//
+//	func (s otelSpan) SetAttribute(key string, value int) {
+//		s.SetAttributes(attribute.Int(key, value))
+//	}
+//
+//	func (s otelSpan) Finish(err error) {
+//		if err != nil {
+//			s.RecordError(err)
+//		}
+//		s.End()
+//	}
+type Tracer interface {
+	// StartSpan starts a span with the given name as a child of the span of the given context
+	StartSpan(ctx context.Context, name string) (context.Context, Span)
+}
+
+// Span is a traced operation
+type Span interface {
+	// SetAttribute sets an integer attribute of the span
+	SetAttribute(key string, value int)
+	// Finish ends the span, the error is the result of the operation
+	Finish(err error)
+}
+
+// Names of spans and attributes
+const (
+	spanGet       = "cdb.Get"
+	spanMultiGet  = "cdb.MultiGet"
+	spanIterator  = "cdb.Iterator"
+	attrKeySize   = "cdb.key_size"
+	attrValueSize = "cdb.value_size"
+	attrProbes    = "cdb.probes"
+	attrReads     = "cdb.reads"
+	attrKeys      = "cdb.keys"
+	attrFound     = "cdb.found"
+	attrRecords   = "cdb.records"
+)
+
+// SetTracer sets the tracer of Get, GetContext, MultiGet and Iterator calls, nil disables tracing,
+// it's the default. Spans of GetContext are children of the span of its context.
+// Like SetHash, it affects only new instances of Reader.
+func (cdb *CDB) SetTracer(tracer Tracer) {
+	cdb.opts.tracer = tracer
+}
+
+// context returns the context of lookups of the reader
+func (r *readerImpl) context() context.Context {
+	if r.ctx == nil {
+		return context.Background()
+	}
+
+	return r.ctx
+}
+
+// tracedGet implements Get with a span
+func (r *readerImpl) tracedGet(key []byte) ([]byte, error) {
+	var stats LookupStats
+
+	_, span := r.opts.tracer.StartSpan(r.context(), spanGet)
+	value, err := r.getVersion(key, 0, &stats)
+
+	span.SetAttribute(attrKeySize, len(key))
+	span.SetAttribute(attrValueSize, len(value))
+	span.SetAttribute(attrProbes, stats.Probes)
+	span.SetAttribute(attrReads, stats.Reads)
+	span.Finish(err)
+
+	return value, err
+}
+
+// tracedMultiGet implements MultiGet with a span
+func (r *readerImpl) tracedMultiGet(keys [][]byte) ([][]byte, []error) {
+	_, span := r.opts.tracer.StartSpan(r.context(), spanMultiGet)
+	values, errs := r.multiGet(keys)
+
+	found, size := 0, 0
+
+	for i, value := range values {
+		if errs[i] == nil {
+			found++
+			size += len(value)
+		}
+	}
+
+	span.SetAttribute(attrKeys, len(keys))
+	span.SetAttribute(attrFound, found)
+	span.SetAttribute(attrValueSize, size)
+	span.Finish(nil)
+
+	return values, errs
+}
+
+// tracedIterator implements Iterator with a span, which covers the creation of the iterator
+func (r *readerImpl) tracedIterator() (Iterator, error) {
+	_, span := r.opts.tracer.StartSpan(r.context(), spanIterator)
+	iterator, err := r.iterator(false)
+
+	span.SetAttribute(attrRecords, r.Size())
+	span.Finish(err)
+
+	return iterator, err
+}
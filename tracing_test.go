@@ -0,0 +1,84 @@
+package cdb
+
+import (
+	"context"
+	"sync"
+)
+
+// recordingTracer implements Tracer, keeps finished spans
+type recordingTracer struct {
+	mu    sync.Mutex
+	spans []*recordingSpan
+}
+
+// recordingSpan implements Span
+type recordingSpan struct {
+	name       string
+	parent     *recordingSpan
+	attributes map[string]int
+	err        error
+	finished   bool
+}
+
+type spanKey struct{}
+
+func (t *recordingTracer) StartSpan(ctx context.Context, name string) (context.Context, Span) {
+	parent, _ := ctx.Value(spanKey{}).(*recordingSpan)
+	span := &recordingSpan{name: name, parent: parent, attributes: make(map[string]int)}
+
+	t.mu.Lock()
+	t.spans = append(t.spans, span)
+	t.mu.Unlock()
+
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+func (s *recordingSpan) SetAttribute(key string, value int) {
+	s.attributes[key] = value
+}
+
+func (s *recordingSpan) Finish(err error) {
+	s.err, s.finished = err, true
+}
+
+func (suite *CDBTestSuite) TestTracer() {
+	suite.fillTestCDB()
+
+	tracer := &recordingTracer{}
+	suite.cdbHandle.SetTracer(tracer)
+	reader := suite.getCDBReader()
+	rec := suite.testRecords[0]
+
+	_, err := reader.Get(rec.key)
+	suite.Require().Nil(err)
+
+	suite.Require().Len(tracer.spans, 1)
+	span := tracer.spans[0]
+	suite.Equal(spanGet, span.name)
+	suite.True(span.finished)
+	suite.Nil(span.err)
+	suite.Equal(len(rec.key), span.attributes[attrKeySize])
+	suite.Equal(len(rec.val), span.attributes[attrValueSize])
+	suite.True(span.attributes[attrProbes] >= 1)
+	suite.True(span.attributes[attrReads] >= 2)
+
+	ctx, parent := tracer.StartSpan(context.Background(), "request")
+	_, err = reader.GetContext(ctx, []byte("missing key"))
+	suite.Equal(ErrEntryNotFound, err)
+
+	span = tracer.spans[len(tracer.spans)-1]
+	suite.Equal(parent, span.parent)
+	suite.Equal(ErrEntryNotFound, span.err)
+
+	_, errs := reader.MultiGet([][]byte{rec.key, []byte("missing key")})
+	suite.Nil(errs[0])
+
+	span = tracer.spans[len(tracer.spans)-1]
+	suite.Equal(spanMultiGet, span.name)
+	suite.Equal(2, span.attributes[attrKeys])
+	suite.Equal(1, span.attributes[attrFound])
+
+	_, err = reader.Iterator()
+	suite.Nil(err)
+	suite.Equal(spanIterator, tracer.spans[len(tracer.spans)-1].name)
+}
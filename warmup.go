@@ -0,0 +1,67 @@
+package cdb
+
+// Size of chunks, which warmup reads
+const warmupChunkSize = 64 << 10
+
+// Warmup preloads the hash tables and, if data is true, the data section into the page cache,
+// see Reader.Warmup
+func (r *readerImpl) Warmup(data bool) error {
+	if r.IsEmpty() {
+		return nil
+	}
+
+	begin, end := r.header.dataPosition(), r.endPos
+
+	for _, ref := range r.refs {
+		if tableEnd := ref.position + ref.length*r.header.slotSize(); tableEnd > end {
+			end = tableEnd
+		}
+	}
+
+	if !data {
+		begin = r.endPos
+	} else if r.mem == nil && fadviseWillNeed(r.reader, int64(begin), int64(r.endPos-begin)) {
+		// The kernel reads the data section in background
+		begin = r.endPos
+	}
+
+	return r.touch(begin, end)
+}
+
+// touch reads the given region of the database, so that it gets into the page cache
+func (r *readerImpl) touch(begin, end uint32) error {
+	if r.mem != nil {
+		var sum byte
+
+		for pos := uint64(begin); pos < uint64(end) && pos < uint64(len(r.mem)); pos += pageSize {
+			sum += r.mem[pos]
+		}
+
+		warmupSink = sum
+
+		return nil
+	}
+
+	buf := make([]byte, warmupChunkSize)
+
+	for pos := begin; pos < end; {
+		n := end - pos
+		if n > warmupChunkSize {
+			n = warmupChunkSize
+		}
+
+		if _, err := r.reader.ReadAt(buf[:n], int64(pos)); err != nil {
+			return err
+		}
+
+		pos += n
+	}
+
+	return nil
+}
+
+// Size of pages, which touch reads from a memory mapping
+const pageSize = 4096
+
+// warmupSink keeps the result of touching a mapping, so that the compiler doesn't drop the loop
+var warmupSink byte
@@ -0,0 +1,52 @@
+//go:build linux && (amd64 || arm64)
+// +build linux
+// +build amd64 arm64
+
+package cdb
+
+import (
+	"io"
+	"os"
+	"syscall"
+)
+
+// Advice of madvise, which enables transparent huge pages for the region
+const madvHugePage = 14
+
+// fadviseWillNeed asks the kernel to read the given region of the file in background.
+// Returns false if the reader is not a file or the call fails.
+func fadviseWillNeed(reader io.ReaderAt, offset, length int64) bool {
+	f, ok := osFile(reader)
+	if !ok {
+		return false
+	}
+
+	return fadvise(f, offset, length, AccessWillNeed)
+}
+
+// fadvise advises the kernel of the access pattern of the given region of the file,
+// 0 length stands for the rest of the file. Advice values of fadvise match AccessPattern ones.
+func fadvise(f *os.File, offset, length int64, pattern AccessPattern) bool {
+	_, _, errno := syscall.Syscall6(syscall.SYS_FADVISE64, f.Fd(), uintptr(offset), uintptr(length), uintptr(pattern), 0, 0)
+
+	return errno == 0
+}
+
+// madvise advises the kernel of the access pattern of the given memory mapping.
+// Advice values of madvise match AccessPattern ones.
+func madvise(data []byte, pattern AccessPattern) bool {
+	if len(data) == 0 {
+		return false
+	}
+
+	return syscall.Madvise(data, int(pattern)) == nil
+}
+
+// madviseHugePage asks the kernel to back the given memory mapping by transparent huge pages
+func madviseHugePage(data []byte) bool {
+	if len(data) == 0 {
+		return false
+	}
+
+	return syscall.Madvise(data, madvHugePage) == nil
+}
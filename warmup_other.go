@@ -0,0 +1,29 @@
+//go:build !linux || !(amd64 || arm64)
+// +build !linux !amd64,!arm64
+
+package cdb
+
+import (
+	"io"
+	"os"
+)
+
+// fadviseWillNeed does nothing, there is no fadvise on the platform
+func fadviseWillNeed(reader io.ReaderAt, offset, length int64) bool {
+	return false
+}
+
+// fadvise does nothing, there is no fadvise on the platform
+func fadvise(f *os.File, offset, length int64, pattern AccessPattern) bool {
+	return false
+}
+
+// madvise does nothing, access patterns are advised on Linux only
+func madvise(data []byte, pattern AccessPattern) bool {
+	return false
+}
+
+// madviseHugePage does nothing, transparent huge pages are Linux-specific
+func madviseHugePage(data []byte) bool {
+	return false
+}
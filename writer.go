@@ -2,13 +2,18 @@ package cdb
 
 import (
 	"bufio"
+	"crypto/rand"
 	"encoding/binary"
+	"hash"
 	"io"
+	"time"
 )
 
 // slot (bucket)
 type slot struct {
 	hash, position uint32
+	// hi is the upper half of the 64-bit key hash, 0 without the 64-bit hash
+	hi uint32
 }
 
 // hashTable is a linearly probed initialize hash table
@@ -16,68 +21,293 @@ type hashTable []slot
 
 // writerImpl implements Writer interface
 type writerImpl struct {
-	tables         [tableNum]hashTable
+	tables         []hashTable
+	header         header
 	writer         io.WriteSeeker
 	buffer         *bufio.Writer
 	hasher         Hasher
+	hashFunc       hash.Hash32
 	begin, current int64
+	// bloomBitsPerKey is the size of the bloom filter, 0 if it is disabled
+	bloomBitsPerKey uint32
+	// anchorKey is the key of the last anchor record, used by the key prefix compression
+	anchorKey []byte
+	anchor    uint32
+	// index is the sidecar stream for the sorted index, entries are collected for it
+	index   io.Writer
+	entries []indexEntry
+	// versions is the number of kept versions of a key, history keeps the versions of every key
+	// and dropped keeps versions and duplicates, which are superseded by newer ones
+	versions int
+	history  map[string][]versionRef
+	dropped  []versionRef
+	// duplicates is the duplicate policy, seen keeps the last record of every key,
+	// it is nil unless the policy needs it
+	duplicates DuplicatePolicy
+	seen       map[string]versionRef
+	// bucketIDs maps names of buckets to their ids, bucketNames and bucketSizes are indexed by ids.
+	// The root bucket has id 0 and the empty name.
+	bucketIDs   map[string]uint32
+	bucketNames [][]byte
+	bucketSizes []int
+	// aborted tells that the writer is aborted, see Writer.Abort
+	aborted bool
+	// closed tells that Close was called, closeErr is its result, which repeated calls of Close return
+	closed   bool
+	closeErr error
+	// pending is the number of slots kept in memory, they are spilled once there are spillLimit of them,
+	// see CDB.SetSpill. droppedPositions are the positions of dropped records, see dropVersions.
+	spillDir         string
+	spillLimit       int
+	pending          int
+	spill            *slotSpill
+	droppedPositions map[uint32]bool
+	// sync tells that the output is synced on Close, see CDB.SetSync
+	sync bool
+	// deterministic tells that the output is cut right after the database, see CDB.SetDeterministic
+	deterministic bool
+	// records, keyBytes and valueBytes count put records, see Writer.Stats. progress is the progress hook
+	// called every progressEvery records, see CDB.SetProgress. started is the creation time of the writer.
+	records         int
+	keyBytes        int64
+	valueBytes      int64
+	expectedRecords int
+	progress        func(BuildProgress)
+	progressEvery   int
+	now             func() time.Time
+	started         time.Time
+	// pipeline hashes keys in background, nil without hashing workers, see CDB.SetHashWorkers
+	pipeline *hashPipeline
+}
+
+// versionRef refers to a version of a key
+type versionRef struct {
+	position uint32
+	flags    RecordFlags
+	bucket   uint32
 }
 
 // newWriter returns pointer to new instance of writerImpl
-func newWriter(writer io.WriteSeeker, hasher Hasher) (*writerImpl, error) {
-	startPosition := int64(tablesRefsSize)
+func newWriter(writer io.WriteSeeker, hasher Hasher, opts options) (*writerImpl, error) {
+	if opts.tableNum == 0 {
+		opts.tableNum = tableNum
+	}
+
+	h := newHeader(opts)
+
+	if opts.seededHash {
+		h.seed = deterministicSeed
+
+		if !opts.deterministic {
+			seed := make([]byte, 16)
+			if _, err := rand.Read(seed); err != nil {
+				return nil, err
+			}
+
+			h.seed = [2]uint64{binary.LittleEndian.Uint64(seed), binary.LittleEndian.Uint64(seed[8:])}
+		}
+
+		hasher = h.seededHasher()
+	}
+
+	startPosition := int64(h.dataPosition())
 	begin, err := writer.Seek(0, io.SeekCurrent)
 
 	if err != nil {
 		return nil, err
 	}
 
-	if _, err = writer.Seek(startPosition, io.SeekStart); err != nil {
+	if _, err = writer.Seek(begin+startPosition, io.SeekStart); err != nil {
 		return nil, err
 	}
 
-	return &writerImpl{
-		writer:  writer,
-		buffer:  bufio.NewWriter(writer),
-		hasher:  hasher,
-		begin:   begin,
-		current: startPosition,
-	}, nil
+	w := &writerImpl{
+		tables:          make([]hashTable, h.tableNum),
+		header:          h,
+		writer:          writer,
+		buffer:          bufio.NewWriterSize(writer, opts.writeBufferSize),
+		hasher:          hasher,
+		hashFunc:        hasher(),
+		begin:           begin,
+		current:         startPosition,
+		bloomBitsPerKey: opts.bloomBitsPerKey,
+		versions:        opts.versions,
+		history:         make(map[string][]versionRef),
+		bucketIDs:       make(map[string]uint32),
+		bucketNames:     [][]byte{nil},
+		bucketSizes:     []int{0},
+		duplicates:      opts.duplicates,
+		spillDir:        opts.spillDir,
+		spillLimit:      opts.spillSlots,
+		sync:            opts.sync,
+		deterministic:   opts.deterministic,
+		expectedRecords: opts.expectedRecords,
+		progress:        opts.progress,
+		progressEvery:   opts.progressEvery,
+		now:             opts.now,
+	}
+
+	if opts.duplicates != DuplicatesKeepAll && opts.versions == 0 {
+		w.seen = make(map[string]versionRef)
+	}
+
+	if w.now == nil {
+		w.now = time.Now
+	}
+
+	w.started = w.now()
+
+	w.reserve(opts)
+
+	if opts.hashWorkers > 0 {
+		w.pipeline = w.newHashPipeline(opts.hashWorkers)
+	}
+
+	return w, nil
 }
 
 // Put saves a new associated pair <key, value> into databases. Returns an error on failure.
 func (w *writerImpl) Put(key, value []byte) error {
-	lenKey, lenValue := len(key), len(value)
+	return w.put(key, value, recordMeta{})
+}
+
+// PutWithExpiry saves a new associated pair <key, value>, which expires at the given time.
+func (w *writerImpl) PutWithExpiry(key, value []byte, expiresAt time.Time) error {
+	if w.header.flags&flagExpiry == 0 {
+		return ErrExpiryDisabled
+	}
+
+	return w.put(key, value, expiryMeta(expiresAt, 0))
+}
+
+// expiryMeta returns meta fields of a record of the given bucket, which expires at the given time
+func expiryMeta(expiresAt time.Time, bucket uint32) recordMeta {
+	meta := recordMeta{bucket: bucket}
+
+	if !expiresAt.IsZero() {
+		meta.expiry = expiresAt.UnixNano()
+		meta.flags |= RecordHasTTL
+	}
+
+	return meta
+}
+
+// put saves a new record with the given meta fields, see recordLayout
+func (w *writerImpl) put(key, value []byte, meta recordMeta) error {
+	return w.writeRecord(key, value, nil, int64(len(value)), meta)
+}
+
+// writeRecord saves a new record, its value is either the given value or size bytes read from r.
+// A negative size of r means that the size is unknown, see writerImpl.writeValue.
+func (w *writerImpl) writeRecord(key, value []byte, r io.Reader, size int64, meta recordMeta) error {
+	if w.aborted {
+		return ErrWriterAborted
+	}
+
+	lenKey := len(key)
+
+	if uint64(lenKey) > maxUint || size > maxUint {
+		return ErrTooBig
+	}
+
+	if w.header.flags&flagFixedValueSize != 0 {
+		if size < 0 {
+			size = int64(w.header.valueSize)
+		}
+
+		if size != int64(w.header.valueSize) {
+			return ErrInvalidValueSize
+		}
+	}
+
+	var (
+		id       string
+		replaced *versionRef
+	)
+
+	if w.seen != nil {
+		id = keyID(key, w.bucketNames[meta.bucket])
+
+		write, ref, err := w.checkDuplicate(id)
+		if err != nil || !write {
+			return err
+		}
+
+		replaced = ref
+	}
+
+	if err := w.pad(); err != nil {
+		return err
+	}
+
+	// The record must end below 4 gb, the key is counted in full even if its prefix is shared
+	end := w.current + int64(w.header.recordHeaderSize()) + int64(lenKey)
+	if size > 0 {
+		end += size
+	}
 
-	if uint64(lenKey) > maxUint || uint64(lenValue) > maxUint {
-		return ErrOutOfMemory
+	if end >= maxUint {
+		return ErrTooBig
 	}
 
-	if err := writePair(w.buffer, uint32(lenKey), uint32(lenValue)); err != nil {
+	position := uint32(w.current)
+	stored, err := w.writeRecordHeader(key, uint32(size), meta)
+
+	if err != nil {
 		return err
 	}
 
-	if err := binary.Write(w.buffer, binary.LittleEndian, key); err != nil {
+	if err := binary.Write(w.buffer, binary.LittleEndian, key[lenKey-stored:]); err != nil {
 		return err
 	}
 
-	if err := binary.Write(w.buffer, binary.LittleEndian, value); err != nil {
+	lenValue := len(value)
+
+	if r == nil {
+		err = binary.Write(w.buffer, binary.LittleEndian, value)
+	} else {
+		lenValue, err = w.writeValue(r, size, position+w.header.recordHeaderSize()+uint32(stored))
+	}
+
+	if err != nil {
 		return err
 	}
 
-	hashFunc := w.hasher()
-	hashFunc.Write(key)
-	h := hashFunc.Sum32()
+	if w.pipeline != nil {
+		err = w.enqueue(w.bucketNames[meta.bucket], key, position)
+	} else {
+		hashFunc := w.hashFunc
+		hashFunc.Reset()
+		hashFunc.Write(w.bucketNames[meta.bucket])
+		hashFunc.Write(key)
+		h, hi := w.header.keyHash(hashFunc)
 
-	table := w.tables[h%tableNum]
-	table = append(table, slot{h, uint32(w.current)})
-	w.tables[h%tableNum] = table
+		err = w.addSlot(slot{h, position, hi})
+	}
 
-	if err := w.addPos(8); err != nil {
+	if err != nil {
 		return err
 	}
 
-	if err := w.addPos(lenKey); err != nil {
+	if w.index != nil {
+		w.entries = append(w.entries, indexEntry{append([]byte(nil), key...), position})
+	}
+
+	if w.versions > 0 {
+		w.addVersion(key, versionRef{position, meta.flags, meta.bucket})
+	}
+
+	if w.seen != nil {
+		w.addKey(id, versionRef{position, meta.flags, meta.bucket}, replaced)
+	}
+
+	w.bucketSizes[meta.bucket]++
+
+	if err := w.addPos(int(w.header.recordHeaderSize())); err != nil {
+		return err
+	}
+
+	if err := w.addPos(stored); err != nil {
 		return err
 	}
 
@@ -85,40 +315,130 @@ func (w *writerImpl) Put(key, value []byte) error {
 		return err
 	}
 
+	w.records++
+	w.keyBytes += int64(lenKey)
+	w.valueBytes += int64(lenValue)
+	w.reportPut()
+
 	return nil
 }
 
-// Close commits database, makes it possible for reading.
+// Flush pushes the buffered records to the underlying writer, see Writer.Flush
+func (w *writerImpl) Flush() error {
+	if w.aborted {
+		return ErrWriterAborted
+	}
+
+	return w.buffer.Flush()
+}
+
+// addSlot adds the slot of a record to its hash table
+func (w *writerImpl) addSlot(s slot) error {
+	n := s.hash % w.header.tableNum
+	w.tables[n] = append(w.tables[n], s)
+	w.pending++
+
+	if w.spillLimit > 0 && w.pending >= w.spillLimit {
+		return w.spillSlots()
+	}
+
+	return nil
+}
+
+// Close commits database, makes it possible for reading. Repeated calls return the result of the first one:
+// a failed Close releases the collected hash tables, so the database can't be committed again.
 func (w *writerImpl) Close() error {
-	w.buffer.Flush()
+	if w.aborted {
+		return ErrWriterAborted
+	}
 
-	for _, table := range &w.tables {
-		n := uint32(len(table) << 1)
-		if n == 0 {
+	if !w.closed {
+		w.closed = true
+		w.closeErr = w.commit()
+	}
+
+	return w.closeErr
+}
+
+// commit writes the hash tables and the header, see Close
+func (w *writerImpl) commit() error {
+	defer w.removeSpill()
+	defer w.stopWorkers()
+
+	if err := w.drain(); err != nil {
+		return err
+	}
+
+	if err := w.buffer.Flush(); err != nil {
+		return err
+	}
+
+	if err := w.dropVersions(); err != nil {
+		return err
+	}
+
+	lengths := make([]int, len(w.tables))
+	closing := w.now()
+	end := w.current
+
+	for i := range w.tables {
+		w.reportTables(i, closing)
+
+		table, err := w.table(i)
+		if err != nil {
+			return err
+		}
+
+		lengths[i] = len(table)
+
+		if len(table) == 0 {
 			continue
 		}
 
+		// Tables are checked before they are written, their positions are set after all of them
+		if end += int64(len(table)) * 2 * int64(w.header.slotSize()); end >= maxUint {
+			return ErrTooBig
+		}
+
+		n := uint32(len(table) << 1)
+
 		slots := make(hashTable, n)
 
+		// The newest version of a key is placed first, so it is found first
+		if w.header.flags&flagVersioned != 0 {
+			for i, j := 0, len(table)-1; i < j; i, j = i+1, j-1 {
+				table[i], table[j] = table[j], table[i]
+			}
+		}
+
 		for _, slot := range table {
-			k := (slot.hash >> 8) % n
+			k := w.header.startSlot(slot.hash, n)
 
 			// Linear probing
 			for slots[k].position != 0 {
 				k = (k + 1) % n
 			}
 
-			slots[k].position = slot.position
-			slots[k].hash = slot.hash
+			slots[k] = slot
 		}
 
 		for _, slot := range slots {
-			if err := writePair(w.writer, slot.hash, slot.position); err != nil {
+			if err := w.writeSlot(slot); err != nil {
 				return err
 			}
 		}
 	}
 
+	w.reportTables(len(w.tables), closing)
+
+	if err := w.writeBloomFilter(lengths); err != nil {
+		return err
+	}
+
+	if err := w.writeBucketDirectory(); err != nil {
+		return err
+	}
+
 	offset, err := w.writer.Seek(0, io.SeekCurrent)
 
 	if err != nil {
@@ -129,10 +449,14 @@ func (w *writerImpl) Close() error {
 		return err
 	}
 
+	if err := w.header.write(w.writer); err != nil {
+		return err
+	}
+
 	var pos uint32
 
-	for _, table := range &w.tables {
-		n := len(table) << 1
+	for _, length := range lengths {
+		n := length << 1
 
 		if n == 0 {
 			pos = 0
@@ -144,7 +468,7 @@ func (w *writerImpl) Close() error {
 			return err
 		}
 
-		if err := w.addPos(slotSize * n); err != nil {
+		if err := w.addPos(int(w.header.slotSize()) * n); err != nil {
 			return err
 		}
 	}
@@ -153,7 +477,166 @@ func (w *writerImpl) Close() error {
 		return err
 	}
 
-	return nil
+	if err := w.truncateOutput(offset); err != nil {
+		return err
+	}
+
+	if w.index != nil {
+		if err := writeIndex(w.index, w.entries); err != nil {
+			return err
+		}
+	}
+
+	if !w.sync {
+		return nil
+	}
+
+	if err := syncOutput(w.writer); err != nil {
+		return err
+	}
+
+	return syncOutput(w.index)
+}
+
+// Abort discards buffered records and the collected hash tables, see Writer.Abort
+func (w *writerImpl) Abort() error {
+	w.aborted = true
+	w.stopWorkers()
+	w.buffer.Reset(w.writer)
+	w.tables, w.entries, w.history, w.dropped, w.seen = nil, nil, nil, nil, nil
+
+	return w.removeSpill()
+}
+
+// addVersion adds a new version of the given key, the oldest version is dropped if there are too many
+func (w *writerImpl) addVersion(key []byte, ref versionRef) {
+	id := keyID(key, w.bucketNames[ref.bucket])
+	refs := append(w.history[id], ref)
+
+	if len(refs) > w.versions {
+		w.dropped = append(w.dropped, refs[0])
+		w.bucketSizes[refs[0].bucket]--
+		refs = refs[1:]
+	}
+
+	w.history[id] = refs
+}
+
+// keyID returns the id of the given key of the bucket with the given name, which is unique in the database
+func keyID(key, bucket []byte) string {
+	return string(bucket) + "\x00" + string(key)
+}
+
+// dropVersions removes dropped versions from hash tables and marks them as superseded in the data section
+func (w *writerImpl) dropVersions() error {
+	if len(w.dropped) == 0 {
+		return nil
+	}
+
+	dropped := make(map[uint32]bool, len(w.dropped))
+
+	for _, ref := range w.dropped {
+		dropped[ref.position] = true
+
+		// flags is the last field of the record header
+		if _, err := w.writer.Seek(w.begin+int64(ref.position+w.header.recordHeaderSize()-flagsFieldSize), io.SeekStart); err != nil {
+			return err
+		}
+
+		if _, err := w.writer.Write([]byte{byte(ref.flags | RecordSuperseded)}); err != nil {
+			return err
+		}
+	}
+
+	// Spilled slots are left out, when they are read back
+	w.droppedPositions = dropped
+
+	for i, table := range w.tables {
+		kept := table[:0]
+
+		for _, slot := range table {
+			if !dropped[slot.position] {
+				kept = append(kept, slot)
+			}
+		}
+
+		w.tables[i] = kept
+	}
+
+	entries := w.entries[:0]
+
+	for _, entry := range w.entries {
+		if !dropped[entry.position] {
+			entries = append(entries, entry)
+		}
+	}
+
+	w.entries = entries
+
+	_, err := w.writer.Seek(w.begin+w.current, io.SeekStart)
+
+	return err
+}
+
+// writeBloomFilter writes the bloom filter trailer after the hash tables, if it is enabled.
+// lengths are the numbers of slots in the tables.
+func (w *writerImpl) writeBloomFilter(lengths []int) error {
+	if w.bloomBitsPerKey == 0 {
+		return nil
+	}
+
+	pos, err := w.writer.Seek(0, io.SeekCurrent)
+
+	if err != nil {
+		return err
+	}
+
+	n := 0
+	for _, length := range lengths {
+		n += length
+	}
+
+	filter := newBloomFilter(n, w.bloomBitsPerKey)
+
+	for i := range w.tables {
+		table, err := w.table(i)
+		if err != nil {
+			return err
+		}
+
+		for _, slot := range table {
+			filter.add(slot.hash)
+		}
+	}
+
+	// Positions are relative to the database start
+	pos -= w.begin
+
+	if pos+int64(filter.size()) >= maxUint {
+		return ErrTooBig
+	}
+
+	w.header.bloom = uint32(pos)
+
+	return filter.write(w.writer)
+}
+
+// pad writes zeros up to the next aligned record start
+func (w *writerImpl) pad() error {
+	if w.header.align <= 1 {
+		return nil
+	}
+
+	n := int64(w.header.alignPosition(uint32(w.current))) - w.current
+	if n == 0 {
+		return nil
+	}
+
+	if _, err := w.buffer.Write(make([]byte, n)); err != nil {
+		return err
+	}
+
+	return w.addPos(int(n))
 }
 
 // addPos try to shift current position on len. Returns err when was attempt to create a database up to 4 gb
@@ -161,7 +644,7 @@ func (w *writerImpl) addPos(offset int) error {
 	newPos := w.current + int64(offset)
 
 	if newPos >= maxUint {
-		return ErrOutOfMemory
+		return ErrTooBig
 	}
 
 	w.current = newPos
@@ -169,6 +652,15 @@ func (w *writerImpl) addPos(offset int) error {
 	return nil
 }
 
+// writeSlot writes the given hash table slot
+func (w *writerImpl) writeSlot(s slot) error {
+	if w.header.flags&flagHash64 == 0 {
+		return writePair(w.writer, s.hash, s.position)
+	}
+
+	return binary.Write(w.writer, binary.LittleEndian, []uint32{s.hash, s.position, s.hi})
+}
+
 // writePair writes binary representation of two uint32 numbers to io.Writer
 func writePair(writer io.Writer, a, b uint32) error {
 	var pairBuf = []uint32{a, b}
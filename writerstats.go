@@ -0,0 +1,68 @@
+package cdb
+
+// WriterStats describes the records put by a writer, see Writer.Stats
+type WriterStats struct {
+	// Records is the number of put records, the dropped versions and duplicates included
+	Records int
+	// KeyBytes and ValueBytes are the total sizes of the put keys and values
+	KeyBytes, ValueBytes int64
+	// Offset is the size of the data section written so far, the header included
+	Offset int64
+}
+
+// Stats returns the statistics of the put records, see Writer.Stats
+func (w *writerImpl) Stats() WriterStats {
+	return WriterStats{
+		Records:    w.records,
+		KeyBytes:   w.keyBytes,
+		ValueBytes: w.valueBytes,
+		Offset:     w.current,
+	}
+}
+
+// EstimateFinalSize returns the size of the database, if it is closed now, see Writer.EstimateFinalSize.
+// Hash tables have two slots per kept record, the bloom filter and the bucket directory follow them.
+func (w *writerImpl) EstimateFinalSize() int64 {
+	n := w.records - len(w.dropped)
+	size := w.current + int64(n)*2*int64(w.header.slotSize())
+
+	if w.bloomBitsPerKey != 0 {
+		size += bloomSize(n, w.bloomBitsPerKey)
+	}
+
+	if len(w.bucketNames) > 1 {
+		size += 4
+
+		for _, name := range w.bucketNames[1:] {
+			size += bucketEntrySize + int64(len(name))
+		}
+	}
+
+	return size
+}
+
+// Stats returns the sums of the statistics of all parts, see Writer.Stats
+func (w *shardedWriter) Stats() WriterStats {
+	var stats WriterStats
+
+	for _, part := range w.parts {
+		s := part.Stats()
+		stats.Records += s.Records
+		stats.KeyBytes += s.KeyBytes
+		stats.ValueBytes += s.ValueBytes
+		stats.Offset += s.Offset
+	}
+
+	return stats
+}
+
+// EstimateFinalSize returns the total size of all parts, if they are closed now, see Writer.EstimateFinalSize
+func (w *shardedWriter) EstimateFinalSize() int64 {
+	var size int64
+
+	for _, part := range w.parts {
+		size += part.EstimateFinalSize()
+	}
+
+	return size
+}
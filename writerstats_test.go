@@ -0,0 +1,49 @@
+package cdb
+
+import "hash/fnv"
+
+func (suite *CDBTestSuite) TestWriterStats() {
+	for _, configure := range []func(){
+		func() {},
+		func() {
+			suite.cdbHandle.SetBuckets(true)
+			suite.cdbHandle.SetVersions(1)
+			suite.Require().Nil(suite.cdbHandle.SetBloomFilter(10))
+			suite.cdbHandle.SetHash64(fnv.New64a)
+		},
+	} {
+		suite.cdbHandle = New()
+		configure()
+		suite.resetTestCDB()
+
+		writer := suite.getCDBWriter()
+		keyBytes, valueBytes := 0, 0
+
+		for _, rec := range suite.testRecords {
+			suite.Require().Nil(writer.Put(rec.key, rec.val))
+			keyBytes += len(rec.key)
+			valueBytes += len(rec.val)
+		}
+
+		suite.Require().Nil(writer.Put(suite.testRecords[0].key, nil))
+		keyBytes += len(suite.testRecords[0].key)
+
+		if suite.cdbHandle.opts.buckets {
+			suite.Require().Nil(writer.Bucket("other").Put([]byte("key"), []byte("value")))
+			keyBytes += 3
+			valueBytes += 5
+		}
+
+		stats := writer.Stats()
+		suite.Equal(int64(keyBytes), stats.KeyBytes)
+		suite.Equal(int64(valueBytes), stats.ValueBytes)
+
+		estimate := writer.EstimateFinalSize()
+		suite.Require().Nil(writer.Close())
+
+		info, err := suite.cdbFile.Stat()
+		suite.Require().Nil(err)
+		suite.Equal(info.Size(), estimate)
+		suite.True(stats.Offset < estimate)
+	}
+}